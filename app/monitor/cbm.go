@@ -107,11 +107,11 @@ func (m *modC128MMU) Command(args []string) error {
 	}
 	switch args[0] {
 	case "cr": // configuration register
-		return valueFunc8(m.out, m.mmu.ReadCR, m.mmu.WriteCR, args[1:])
+		return valueFunc8(m.mon, m.mmu.PeekCR, m.mmu.WriteCR, args[1:])
 	case "info":
 		return m.cmdInfo(args[1:])
 	// case "mode":
-	// 	return valueUint8(m.out, &m.mmu.Mode, args[1:])
+	// 	return valueUint8(m.mon, &m.mmu.Mode, args[1:])
 	case "watch", "w":
 		return m.cmdWatch(args[1:])
 	}
@@ -0,0 +1,116 @@
+package monitor
+
+import (
+	"fmt"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// cmdCDL controls the code/data log of the memory. Loading a log merges it
+// with the one that is running so that the coverage of several runs can be
+// combined.
+func (m *modMemory) cmdCDL(args []string) error {
+	if len(args) == 0 {
+		return m.cmdCDLUsage(args)
+	}
+	switch args[0] {
+	case "clear":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		if m.mem.CDL != nil {
+			m.mem.CDL.Clear()
+		}
+		return nil
+	case "load":
+		return m.cmdCDLLoad(args[1:])
+	case "off":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		m.mem.CDL = nil
+		return nil
+	case "on":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		if m.mem.CDL == nil {
+			m.mem.CDL = rcs.NewCDL(m.mem.MaxAddr + 1)
+		}
+		return nil
+	case "save":
+		return m.cmdCDLSave(args[1:])
+	case "usage":
+		return m.cmdCDLUsage(args[1:])
+	}
+	return fmt.Errorf("invalid argument: %v", args[0])
+}
+
+func (m *modMemory) cmdCDLLoad(args []string) error {
+	if err := checkLen(args, 1, 1); err != nil {
+		return err
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	cdl, err := rcs.LoadCDL(filename)
+	if err != nil {
+		return err
+	}
+	if m.mem.CDL == nil {
+		m.mem.CDL = rcs.NewCDL(m.mem.MaxAddr + 1)
+	}
+	return m.mem.CDL.Merge(cdl)
+}
+
+func (m *modMemory) cmdCDLSave(args []string) error {
+	if err := checkLen(args, 1, 1); err != nil {
+		return err
+	}
+	if m.mem.CDL == nil {
+		return fmt.Errorf("code/data log is off")
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	return m.mem.CDL.Save(filename)
+}
+
+// cmdCDLUsage shows how many bytes from the start address to the end
+// address, inclusive, have been executed or used as data.
+func (m *modMemory) cmdCDLUsage(args []string) error {
+	if err := checkLen(args, 0, 2); err != nil {
+		return err
+	}
+	if m.mem.CDL == nil {
+		m.mon.out.Println("off")
+		return nil
+	}
+	start, end := 0, m.mem.MaxAddr
+	if len(args) > 0 {
+		addr, err := m.mon.parseAddress(m.mem, args[0])
+		if err != nil {
+			return err
+		}
+		start, end = addr, addr
+	}
+	if len(args) > 1 {
+		addr, err := m.mon.parseAddress(m.mem, args[1])
+		if err != nil {
+			return err
+		}
+		end = addr
+	}
+	if end < start {
+		return fmt.Errorf("invalid range: %v", formatRange(start, end))
+	}
+	code, data, unused := m.mem.CDL.Usage(start, end)
+	total := float64(end - start + 1)
+	m.mon.out.Printf("%v%v", m.prefix(), formatRange(start, end))
+	m.mon.out.Printf("code   %6d %5.1f%%", code, float64(code)*100/total)
+	m.mon.out.Printf("data   %6d %5.1f%%", data, float64(data)*100/total)
+	m.mon.out.Printf("unused %6d %5.1f%%", unused, float64(unused)*100/total)
+	return nil
+}
@@ -0,0 +1,124 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestCDL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	filename := filepath.Join(dir, "test.cdl")
+	source := filepath.Join(dir, "test.s")
+	f.mon.Eval(strings.Join([]string{
+		"mem cdl",
+		"mem cdl on",
+		"poke 0 $2f $60 $00",
+		"poke $60 $0f",
+		"s",
+		"s",
+		"mem cdl usage 0 $7f",
+		"source " + source + " $60 $6f",
+		"mem cdl save " + filename,
+		"mem cdl clear",
+		"mem cdl usage 0 $7f",
+		"mem cdl load " + filename,
+		"mem cdl usage $60",
+		"mem cdl off",
+		"mem cdl save " + filename,
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ mem cdl",
+		"off",
+		"+ mem cdl on",
+		"+ poke 0 $2f $60 $00",
+		"+ poke $60 $0f",
+		"+ s",
+		"$0060:  0f        i0f",
+		"+ s",
+		"$0003:  00        i00",
+		"+ mem cdl usage 0 $7f",
+		"$0000-$007f",
+		"code        4   3.1%",
+		"data        0   0.0%",
+		"unused    124  96.9%",
+		"+ source " + source + " $60 $6f",
+		"saved $0060-$006f, 1 instructions, 0 labels",
+		"+ mem cdl save " + filename,
+		"+ mem cdl clear",
+		"+ mem cdl usage 0 $7f",
+		"$0000-$007f",
+		"code        0   0.0%",
+		"data        0   0.0%",
+		"unused    128 100.0%",
+		"+ mem cdl load " + filename,
+		"+ mem cdl usage $60",
+		"$0060-$0060",
+		"code        1 100.0%",
+		"data        0   0.0%",
+		"unused      0   0.0%",
+		"+ mem cdl off",
+		"+ mem cdl save " + filename,
+		"code/data log is off",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+}
+
+func TestCDLWorkDir(t *testing.T) {
+	dir, dataDir, done := workDir(t)
+	defer done()
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval(strings.Join([]string{
+		"mem cdl on",
+		"poke 0 $0f",
+		"s",
+		"mem cdl save test.cdl",
+		"mem cdl clear",
+		"mem cdl load test.cdl",
+		"mem cdl usage 0",
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ mem cdl on",
+		"+ poke 0 $0f",
+		"+ s",
+		"$0000:  0f        i0f",
+		"+ mem cdl save test.cdl",
+		"+ mem cdl clear",
+		"+ mem cdl load test.cdl",
+		"+ mem cdl usage 0",
+		"$0000-$0000",
+		"code        1 100.0%",
+		"data        0   0.0%",
+		"unused      0   0.0%",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.cdl")); err != nil {
+		t.Errorf("not saved to working directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "test.cdl")); err == nil {
+		t.Errorf("saved to data directory")
+	}
+}
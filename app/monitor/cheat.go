@@ -0,0 +1,170 @@
+package monitor
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/config"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// CheatFile returns the name of the file where the cheats for the current
+// system are saved. It is empty if there is no user directory.
+func CheatFile() string {
+	if config.UserDir == "" || config.System == "" {
+		return ""
+	}
+	return filepath.Join(config.UserDir, "cheats", config.System)
+}
+
+func (m *Monitor) cmdCheat(args []string) error {
+	if len(args) == 0 {
+		return m.cmdCheatList()
+	}
+	switch args[0] {
+	case "add":
+		return m.cmdCheatAdd(args[1:])
+	case "clear":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		m.mach.Cheats = nil
+		return m.saveCheats()
+	case "import":
+		return m.cmdCheatImport(args[1:])
+	case "list":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		return m.cmdCheatList()
+	}
+	return m.cmdCheatSwitch(args)
+}
+
+func (m *Monitor) cmdCheatList() error {
+	for i, c := range m.mach.Cheats {
+		m.out.Printf("%3d %v", i+1, c)
+	}
+	return nil
+}
+
+// cmdCheatAdd adds a cheat for the selected CPU in the form:
+//
+//	address value [if compare] [bank n] [description]
+func (m *Monitor) cmdCheatAdd(args []string) error {
+	if err := checkLen(args, 2, maxArgs); err != nil {
+		return err
+	}
+	cpu, ok := m.cpu[m.sc]
+	if !ok {
+		return fmt.Errorf("no cpu selected")
+	}
+	mem := cpu.Memory()
+	addr, err := m.parseAddress(mem, args[0])
+	if err != nil {
+		return err
+	}
+	value, err := m.parseValue8(args[1])
+	if err != nil {
+		return err
+	}
+	c := &rcs.Cheat{
+		CPU:     m.sc,
+		Addr:    addr,
+		Value:   value,
+		Bank:    -1,
+		Compare: -1,
+		Enabled: true,
+	}
+	args = args[2:]
+	for len(args) >= 2 {
+		if args[0] == "if" {
+			v, err := m.parseValue8(args[1])
+			if err != nil {
+				return err
+			}
+			c.Compare = int(v)
+		} else if args[0] == "bank" {
+			v, err := m.parseValue(args[1])
+			if err != nil {
+				return err
+			}
+			if v < 0 || v >= mem.NBank {
+				return fmt.Errorf("invalid bank: %v", args[1])
+			}
+			c.Bank = v
+		} else {
+			break
+		}
+		args = args[2:]
+	}
+	c.Desc = strings.Join(args, " ")
+	m.mach.Cheats = append(m.mach.Cheats, c)
+	return m.saveCheats()
+}
+
+func (m *Monitor) cmdCheatImport(args []string) error {
+	if err := checkLen(args, 1, 1); err != nil {
+		return err
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	var cpus []string
+	for _, comp := range m.mach.Comps {
+		if _, ok := comp.C.(rcs.CPU); ok {
+			cpus = append(cpus, comp.Name)
+		}
+	}
+	cheats, skipped, err := rcs.ImportCheats(in, config.System, cpus)
+	if err != nil {
+		return fmt.Errorf("unable to import %v: %v", args[0], err)
+	}
+	m.mach.Cheats = append(m.mach.Cheats, cheats...)
+	m.out.Printf("imported %v, skipped %v", len(cheats), skipped)
+	return m.saveCheats()
+}
+
+func (m *Monitor) cmdCheatSwitch(args []string) error {
+	if err := checkLen(args, 2, 2); err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(m.mach.Cheats) {
+		return fmt.Errorf("no such cheat: %v", args[0])
+	}
+	switch args[1] {
+	case "on":
+		m.mach.Cheats[n-1].Enabled = true
+	case "off":
+		m.mach.Cheats[n-1].Enabled = false
+	case "remove":
+		m.mach.Cheats = append(m.mach.Cheats[:n-1], m.mach.Cheats[n:]...)
+	default:
+		return fmt.Errorf("invalid argument: %v", args[1])
+	}
+	return m.saveCheats()
+}
+
+func (m *Monitor) saveCheats() error {
+	filename := CheatFile()
+	if filename == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("unable to save cheats: %v", err)
+	}
+	if err := rcs.SaveCheats(filename, m.mach.Cheats); err != nil {
+		return fmt.Errorf("unable to save cheats: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestCheatImportWorkDir(t *testing.T) {
+	_, _, done := workDir(t)
+	defer done()
+	cheats := strings.Join([]string{
+		`<?xml version="1.0"?>`,
+		`<mamecheat version="1">`,
+		`  <cheat desc="Infinite Lives">`,
+		`    <script state="run">`,
+		`      <action>maincpu.pb@4E14=03</action>`,
+		`    </script>`,
+		`  </cheat>`,
+		`</mamecheat>`,
+	}, "\n")
+	if err := ioutil.WriteFile("test.xml", []byte(cheats), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval(strings.Join([]string{
+		"cheat import test.xml",
+		"cheat list",
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ cheat import test.xml",
+		"imported 1, skipped 0",
+		"+ cheat list",
+		"  1 off cpu $4e14 $03 - - Infinite Lives",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+}
@@ -25,18 +25,13 @@ type modCPU struct {
 
 func newModCPU(mon *Monitor, comp rcs.Component) module {
 	c := comp.C.(rcs.CPU)
-	var dasm *rcs.Disassembler
-	cpud, ok := c.(rcs.CPUDisassembler)
-	if ok {
-		dasm = cpud.NewDisassembler()
-	}
 	mod := &modCPU{
 		name:   comp.Name,
 		mon:    mon,
 		out:    mon.out,
 		cpu:    c,
 		mem:    c.Memory(),
-		dasm:   dasm,
+		dasm:   mon.newDisassembler(c),
 		brkpts: mon.mach.Breakpoints[comp.Name],
 	}
 	return mod
@@ -47,6 +42,10 @@ func (m *modCPU) Command(args []string) error {
 		return m.cmdInfo(args[0:])
 	}
 	switch args[0] {
+	case "assemble", "a":
+		return m.cmdAssemble(args[1:])
+	case "backtrace", "bt":
+		return m.cmdBacktrace(args[1:])
 	case "breakpoint", "bp":
 		return m.cmdBreakpoint(args[1:])
 	case "disassemble", "d":
@@ -55,16 +54,134 @@ func (m *modCPU) Command(args []string) error {
 		return m.cmdInfo(args[1:])
 	case "next", "n":
 		return m.cmdNext(args[1:])
+	case "out":
+		return m.cmdOut(args[1:])
+	case "over":
+		return m.cmdOver(args[1:])
 	case "step", "s":
 		return m.cmdStep(args[1:])
 	case "select":
 		return m.cmdSelect(args[1:])
+	case "source":
+		return m.cmdSource(args[1:])
 	case "trace", "t":
 		return m.cmdTrace(args[1:])
+	case "until":
+		return m.cmdUntil(args[1:])
 	}
 	return fmt.Errorf("no such command: %v", args[0])
 }
 
+// cmdAssemble writes the instruction to memory at the address. Without an
+// instruction, the monitor enters assembly mode where each line entered is
+// assembled at the next address until a blank line is entered. A line that
+// cannot be assembled is reported and may be entered again.
+func (m *modCPU) cmdAssemble(args []string) error {
+	if err := checkLen(args, 1, maxArgs); err != nil {
+		return err
+	}
+	asm, ok := m.cpu.(rcs.CPUAssembler)
+	if !ok {
+		return fmt.Errorf("cannot assemble on this processor")
+	}
+	addr, err := m.mon.parseAddress(m.mem, args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		m.mon.asmCPU = m.name
+		m.mon.asmAddr = addr
+		return nil
+	}
+	code, err := asm.Assemble(addr, strings.Join(args[1:], " "))
+	if err != nil {
+		return err
+	}
+	m.mem.WriteN(addr, code...)
+	if m.dasm != nil {
+		pc := m.dasm.PC()
+		m.dasm.SetPC(addr)
+		m.mon.out.Printf("%v%v\n", m.prefix(), m.dasm.Next())
+		m.dasm.SetPC(pc)
+	}
+	m.mon.dot = addr + len(code)
+	if m.mon.asmCPU != "" {
+		m.mon.asmAddr = m.mon.dot
+	}
+	return nil
+}
+
+// feedAssembler assembles a line entered while in assembly mode. A blank
+// line leaves assembly mode.
+func (m *Monitor) feedAssembler(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		m.asmCPU = ""
+		return nil
+	}
+	args := append([]string{m.asmCPU, "assemble", formatAddress(m.asmAddr)}, fields...)
+	return m.exec(args)
+}
+
+// cmdBacktrace lists the call stack, innermost frame first. If tracking
+// has not been enabled with "backtrace track on", the stack is scanned for
+// return addresses instead.
+func (m *modCPU) cmdBacktrace(args []string) error {
+	if err := checkLen(args, 0, 2); err != nil {
+		return err
+	}
+	cs, ok := m.cpu.(rcs.CPUCallStack)
+	if !ok {
+		return fmt.Errorf("cannot backtrace on this processor")
+	}
+	if len(args) > 0 {
+		if args[0] != "track" {
+			return fmt.Errorf("invalid argument: %v", args[0])
+		}
+		return m.cmdBacktraceTrack(cs.CallStack(), args[1:])
+	}
+	frames := cs.CallStack().Frames
+	if !cs.CallStack().Enabled {
+		frames = cs.ScanStack()
+		m.mon.out.Println("(scanned)")
+	}
+	lines := make([]string, 0, len(frames))
+	for i := len(frames) - 1; i >= 0; i-- {
+		f := frames[i]
+		irq := ""
+		if f.Interrupt {
+			irq = "irq"
+		}
+		line := fmt.Sprintf("#%-3d %v %-16v ret %v %-16v sp %v %v",
+			len(frames)-1-i,
+			formatAddress(f.Entry), m.mon.symbolize(f.Entry),
+			formatAddress(f.Return), m.mon.symbolize(f.Return),
+			formatAddress(f.SP), irq)
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	if len(lines) > 0 {
+		m.mon.out.Println(strings.Join(lines, "\n"))
+	}
+	return nil
+}
+
+func (m *modCPU) cmdBacktraceTrack(calls *rcs.CallStack, args []string) error {
+	if err := checkLen(args, 0, 1); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		m.mon.out.Println(calls.Enabled)
+		return nil
+	}
+	v, err := parseBool(args[0])
+	if err != nil {
+		return err
+	}
+	calls.Enabled = v
+	calls.Reset()
+	return nil
+}
+
 func (m *modCPU) cmdBreakpoint(args []string) error {
 	if len(args) == 0 {
 		return m.cmdBreakpointList(args[0:])
@@ -84,7 +201,7 @@ func (m *modCPU) cmdBreakpointClear(args []string) error {
 	if err := checkLen(args, 1, 1); err != nil {
 		return err
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return err
 	}
@@ -122,7 +239,7 @@ func (m *modCPU) cmdBreakpointSwitch(args []string) error {
 	if err := checkLen(args, 1, 2); err != nil {
 		return err
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return fmt.Errorf("invalid address: %v", args[0])
 	}
@@ -153,7 +270,7 @@ func (m *modCPU) cmdDisassemble(args []string) error {
 		return fmt.Errorf("cannot disassemble this processor")
 	}
 	if len(args) > 0 {
-		addr, err := parseAddress(m.mem, args[0])
+		addr, err := m.mon.parseAddress(m.mem, args[0])
 		if err != nil {
 			return err
 		}
@@ -161,7 +278,7 @@ func (m *modCPU) cmdDisassemble(args []string) error {
 	}
 	if len(args) > 1 {
 		// list until at ending address
-		addrEnd, err := parseAddress(m.mem, args[1])
+		addrEnd, err := m.mon.parseAddress(m.mem, args[1])
 		if err != nil {
 			return err
 		}
@@ -185,6 +302,7 @@ func (m *modCPU) cmdDisassemble(args []string) error {
 			m.mon.out.Printf("%v%v\n", m.prefix(), m.dasm.Next())
 		}
 	}
+	m.mon.dot = m.dasm.PC()
 	// m.lastCmd = m.cmdDasmList
 	return nil
 }
@@ -213,6 +331,56 @@ func (m *modCPU) cmdNext(args []string) error {
 	return nil
 }
 
+// cmdOut runs until the current subroutine returns. A return from a
+// nested call or from an interrupt handler does not stop execution since
+// the stack pointer will not be above where it was when this command
+// was issued.
+func (m *modCPU) cmdOut(args []string) error {
+	if err := checkLen(args, 0, 0); err != nil {
+		return err
+	}
+	flow, ok := m.cpu.(rcs.CPUFlow)
+	if !ok {
+		return fmt.Errorf("cannot step out on this processor")
+	}
+	sp := flow.StackAddr()
+	m.mon.mach.Command(rcs.MachUntil, m.name, func() bool {
+		return flow.Flow(m.mon.mach.At) == rcs.FlowReturn && flow.StackAddr() > sp
+	})
+	return nil
+}
+
+// cmdOver executes the next instruction. If the instruction is a call,
+// execution continues until the call returns. The stack pointer is checked
+// so a recursive call to the same subroutine does not stop execution early.
+func (m *modCPU) cmdOver(args []string) error {
+	if err := checkLen(args, 0, 0); err != nil {
+		return err
+	}
+	flow, ok := m.cpu.(rcs.CPUFlow)
+	if !ok || m.dasm == nil {
+		return fmt.Errorf("cannot step over on this processor")
+	}
+	pc := m.cpu.PC() + m.cpu.Offset()
+	if flow.Flow(pc) != rcs.FlowCall {
+		err := m.cmdStep(args)
+		m.mon.defaultCmd = fmt.Sprintf("%v over", m.name)
+		return err
+	}
+	ppc := m.dasm.PC()
+	m.dasm.SetPC(pc)
+	m.dasm.Next()
+	next := m.dasm.PC()
+	m.dasm.SetPC(ppc)
+
+	sp := flow.StackAddr()
+	m.mon.mach.Command(rcs.MachUntil, m.name, func() bool {
+		return m.cpu.PC()+m.cpu.Offset() == next && flow.StackAddr() >= sp
+	})
+	m.mon.defaultCmd = fmt.Sprintf("%v over", m.name)
+	return nil
+}
+
 func (m *modCPU) cmdSelect(args []string) error {
 	if err := checkLen(args, 0, 0); err != nil {
 		return err
@@ -251,8 +419,28 @@ func (m *modCPU) cmdTrace(args []string) error {
 	return nil
 }
 
+func (m *modCPU) cmdUntil(args []string) error {
+	if err := checkLen(args, 1, 1); err != nil {
+		return err
+	}
+	addr, err := m.mon.parseAddress(m.mem, args[0])
+	if err != nil {
+		return err
+	}
+	m.mon.mach.Command(rcs.MachUntil, m.name, func() bool {
+		return m.cpu.PC()+m.cpu.Offset() == addr
+	})
+	return nil
+}
+
 func (m *modCPU) AutoComplete() []readline.PrefixCompleterInterface {
 	return []readline.PrefixCompleterInterface{
+		readline.PcItem("backtrace",
+			readline.PcItem("track",
+				readline.PcItem("on"),
+				readline.PcItem("off"),
+			),
+		),
 		readline.PcItem("breakpoint",
 			readline.PcItem("list"),
 			readline.PcItem("none"),
@@ -261,9 +449,12 @@ func (m *modCPU) AutoComplete() []readline.PrefixCompleterInterface {
 		readline.PcItem("disassemble"),
 		readline.PcItem("info"),
 		readline.PcItem("next"),
+		readline.PcItem("out"),
+		readline.PcItem("over"),
 		readline.PcItem("step"),
 		readline.PcItem("select"),
 		readline.PcItem("trace"),
+		readline.PcItem("until"),
 	}
 }
 
@@ -272,6 +463,14 @@ func (m *modCPU) Silence() error {
 	return nil
 }
 
+// Value returns the address of the next instruction to execute for "pc".
+func (m *modCPU) Value(name string) (int, bool) {
+	if name == "pc" {
+		return m.cpu.PC() + m.cpu.Offset(), true
+	}
+	return 0, false
+}
+
 func (m *modCPU) prefix() string {
 	if m.name == "cpu" {
 		return ""
@@ -303,17 +502,17 @@ func (m *modM6502) Command(args []string) error {
 
 	switch args[0] {
 	case "r.pc":
-		return valueIntF(m.out, m.cpu.PC, m.cpu.SetPC, args[1:])
+		return valueIntF(m.mon, m.cpu.PC, m.cpu.SetPC, args[1:])
 	case "r.a":
-		return valueUint8(m.out, &m.cpu.A, args[1:])
+		return valueUint8(m.mon, &m.cpu.A, args[1:])
 	case "r.x":
-		return valueUint8(m.out, &m.cpu.X, args[1:])
+		return valueUint8(m.mon, &m.cpu.X, args[1:])
 	case "r.y":
-		return valueUint8(m.out, &m.cpu.Y, args[1:])
+		return valueUint8(m.mon, &m.cpu.Y, args[1:])
 	case "r.sp":
-		return valueUint8(m.out, &m.cpu.SP, args[1:])
+		return valueUint8(m.mon, &m.cpu.SP, args[1:])
 	case "r.sr":
-		return valueUint8(m.out, &m.cpu.SR, args[1:])
+		return valueUint8(m.mon, &m.cpu.SR, args[1:])
 	case "f.c":
 		return valueBit(m.out, &m.cpu.SR, m6502.FlagC, args[1:])
 	case "f.z":
@@ -339,6 +538,22 @@ func (m *modM6502) Command(args []string) error {
 	return m.parent.Command(args)
 }
 
+func (m *modM6502) Value(name string) (int, bool) {
+	switch name {
+	case "a":
+		return int(m.cpu.A), true
+	case "x":
+		return int(m.cpu.X), true
+	case "y":
+		return int(m.cpu.Y), true
+	case "sp":
+		return int(m.cpu.SP), true
+	case "sr":
+		return int(m.cpu.SR), true
+	}
+	return m.parent.(valuer).Value(name)
+}
+
 func (m *modM6502) AutoComplete() []readline.PrefixCompleterInterface {
 	cmd := m.parent.AutoComplete()
 	cmd = append(cmd, []readline.PrefixCompleterInterface{
@@ -413,85 +628,85 @@ func (m *modZ80) Command(args []string) error {
 		return valueBit(m.out, &m.cpu.F, z80.FlagS, args[1:])
 
 	case "r.pc":
-		return valueIntF(m.out, m.cpu.PC, m.cpu.SetPC, args[1:])
+		return valueIntF(m.mon, m.cpu.PC, m.cpu.SetPC, args[1:])
 	case "r.a":
-		return valueUint8(m.out, &m.cpu.A, args[1:])
+		return valueUint8(m.mon, &m.cpu.A, args[1:])
 	case "r.f":
-		return valueUint8(m.out, &m.cpu.F, args[1:])
+		return valueUint8(m.mon, &m.cpu.F, args[1:])
 	case "r.b":
-		return valueUint8(m.out, &m.cpu.B, args[1:])
+		return valueUint8(m.mon, &m.cpu.B, args[1:])
 	case "r.c":
-		return valueUint8(m.out, &m.cpu.C, args[1:])
+		return valueUint8(m.mon, &m.cpu.C, args[1:])
 	case "r.d":
-		return valueUint8(m.out, &m.cpu.D, args[1:])
+		return valueUint8(m.mon, &m.cpu.D, args[1:])
 	case "r.e":
-		return valueUint8(m.out, &m.cpu.E, args[1:])
+		return valueUint8(m.mon, &m.cpu.E, args[1:])
 	case "r.h":
-		return valueUint8(m.out, &m.cpu.H, args[1:])
+		return valueUint8(m.mon, &m.cpu.H, args[1:])
 	case "r.l":
-		return valueUint8(m.out, &m.cpu.L, args[1:])
+		return valueUint8(m.mon, &m.cpu.L, args[1:])
 
 	case "r.a1":
-		return valueUint8(m.out, &m.cpu.A1, args[1:])
+		return valueUint8(m.mon, &m.cpu.A1, args[1:])
 	case "r.f1":
-		return valueUint8(m.out, &m.cpu.F1, args[1:])
+		return valueUint8(m.mon, &m.cpu.F1, args[1:])
 	case "r.b1":
-		return valueUint8(m.out, &m.cpu.B1, args[1:])
+		return valueUint8(m.mon, &m.cpu.B1, args[1:])
 	case "r.c1":
-		return valueUint8(m.out, &m.cpu.C1, args[1:])
+		return valueUint8(m.mon, &m.cpu.C1, args[1:])
 	case "r.d1":
-		return valueUint8(m.out, &m.cpu.D1, args[1:])
+		return valueUint8(m.mon, &m.cpu.D1, args[1:])
 	case "r.e1":
-		return valueUint8(m.out, &m.cpu.E1, args[1:])
+		return valueUint8(m.mon, &m.cpu.E1, args[1:])
 	case "r.h1":
-		return valueUint8(m.out, &m.cpu.H1, args[1:])
+		return valueUint8(m.mon, &m.cpu.H1, args[1:])
 	case "r.l1":
-		return valueUint8(m.out, &m.cpu.L1, args[1:])
+		return valueUint8(m.mon, &m.cpu.L1, args[1:])
 
 	case "r.af":
-		return valueUint16HL(m.out, &m.cpu.A, &m.cpu.F, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.A, &m.cpu.F, args[1:])
 	case "r.bc":
-		return valueUint16HL(m.out, &m.cpu.B, &m.cpu.C, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.B, &m.cpu.C, args[1:])
 	case "r.de":
-		return valueUint16HL(m.out, &m.cpu.D, &m.cpu.E, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.D, &m.cpu.E, args[1:])
 	case "r.hl":
-		return valueUint16HL(m.out, &m.cpu.H, &m.cpu.L, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.H, &m.cpu.L, args[1:])
 
 	case "r.af1":
-		return valueUint16HL(m.out, &m.cpu.A1, &m.cpu.F1, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.A1, &m.cpu.F1, args[1:])
 	case "r.bc1":
-		return valueUint16HL(m.out, &m.cpu.B1, &m.cpu.C1, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.B1, &m.cpu.C1, args[1:])
 	case "r.de1":
-		return valueUint16HL(m.out, &m.cpu.D1, &m.cpu.E1, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.D1, &m.cpu.E1, args[1:])
 	case "r.hl1":
-		return valueUint16HL(m.out, &m.cpu.H1, &m.cpu.L1, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.H1, &m.cpu.L1, args[1:])
 
 	case "r.i":
-		return valueUint8(m.out, &m.cpu.I, args[1:])
+		return valueUint8(m.mon, &m.cpu.I, args[1:])
 	case "r.r":
-		return valueUint8(m.out, &m.cpu.R, args[1:])
+		return valueUint8(m.mon, &m.cpu.R, args[1:])
 	case "r.ixh":
-		return valueUint8(m.out, &m.cpu.IXH, args[1:])
+		return valueUint8(m.mon, &m.cpu.IXH, args[1:])
 	case "r.ixl":
-		return valueUint8(m.out, &m.cpu.IXL, args[1:])
+		return valueUint8(m.mon, &m.cpu.IXL, args[1:])
 	case "r.iyh":
-		return valueUint8(m.out, &m.cpu.IYH, args[1:])
+		return valueUint8(m.mon, &m.cpu.IYH, args[1:])
 	case "r.iyl":
-		return valueUint8(m.out, &m.cpu.IYL, args[1:])
+		return valueUint8(m.mon, &m.cpu.IYL, args[1:])
 	case "r.sp":
-		return valueUint16(m.out, &m.cpu.SP, args[1:])
+		return valueUint16(m.mon, &m.cpu.SP, args[1:])
 
 	case "r.ix":
-		return valueUint16HL(m.out, &m.cpu.IXH, &m.cpu.IXL, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.IXH, &m.cpu.IXL, args[1:])
 	case "r.iy":
-		return valueUint16HL(m.out, &m.cpu.IYH, &m.cpu.IYL, args[1:])
+		return valueUint16HL(m.mon, &m.cpu.IYH, &m.cpu.IYL, args[1:])
 
 	case "r.iff1":
 		return valueBool(m.out, &m.cpu.IFF1, args[1:])
 	case "r.iff2":
 		return valueBool(m.out, &m.cpu.IFF2, args[1:])
 	case "r.im":
-		return valueUint8(m.out, &m.cpu.IM, args[1:])
+		return valueUint8(m.mon, &m.cpu.IM, args[1:])
 
 	case "watch-irq":
 		return valueBool(m.out, &m.cpu.WatchIRQ, args[1:])
@@ -500,6 +715,64 @@ func (m *modZ80) Command(args []string) error {
 	return m.parent.Command(args)
 }
 
+func (m *modZ80) Value(name string) (int, bool) {
+	c := m.cpu
+	hl := func(hi uint8, lo uint8) int { return int(hi)<<8 | int(lo) }
+	switch name {
+	case "a":
+		return int(c.A), true
+	case "f":
+		return int(c.F), true
+	case "b":
+		return int(c.B), true
+	case "c":
+		return int(c.C), true
+	case "d":
+		return int(c.D), true
+	case "e":
+		return int(c.E), true
+	case "h":
+		return int(c.H), true
+	case "l":
+		return int(c.L), true
+	case "af":
+		return hl(c.A, c.F), true
+	case "bc":
+		return hl(c.B, c.C), true
+	case "de":
+		return hl(c.D, c.E), true
+	case "hl":
+		return hl(c.H, c.L), true
+	case "af1":
+		return hl(c.A1, c.F1), true
+	case "bc1":
+		return hl(c.B1, c.C1), true
+	case "de1":
+		return hl(c.D1, c.E1), true
+	case "hl1":
+		return hl(c.H1, c.L1), true
+	case "i":
+		return int(c.I), true
+	case "r":
+		return int(c.R), true
+	case "ixh":
+		return int(c.IXH), true
+	case "ixl":
+		return int(c.IXL), true
+	case "iyh":
+		return int(c.IYH), true
+	case "iyl":
+		return int(c.IYL), true
+	case "ix":
+		return hl(c.IXH, c.IXL), true
+	case "iy":
+		return hl(c.IYH, c.IYL), true
+	case "sp":
+		return int(c.SP), true
+	}
+	return m.parent.(valuer).Value(name)
+}
+
 func (m *modZ80) AutoComplete() []readline.PrefixCompleterInterface {
 	cmd := m.parent.AutoComplete()
 	cmd = append(cmd, []readline.PrefixCompleterInterface{
@@ -0,0 +1,331 @@
+package monitor
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+/*
+Numeric arguments to monitor commands are expressions. Arguments are split
+on whitespace so an expression cannot contain spaces.
+
+Literals use the same prefixes as before: $ff or 0xff for hexadecimal,
+%1010 or 0b1010 for binary, and no prefix for decimal. A bare name is
+never a number; "a" is register A and not the value 10.
+
+Operators, from highest to lowest precedence:
+
+	[e] [e].b [e].w    8-bit or 16-bit (little endian) value in memory
+	- ~                negate, bitwise not
+	* / %              multiply, divide, modulo
+	+ -                add, subtract
+	<< >>              shift
+	&                  bitwise and
+	^                  bitwise exclusive or
+	|                  bitwise or
+
+Parentheses can be used for grouping. Names are the registers of the
+selected CPU (pc, a, hl, ix, ...) followed by symbols defined with the
+symbol command. A single dot is the current address: the address that
+follows the last memory dump or disassembly listing.
+
+Memory is read with Peek so evaluating an expression never changes the
+state of a device.
+*/
+
+// valuer is implemented by modules that provide named values, such as CPU
+// registers, for use in expressions.
+type valuer interface {
+	Value(name string) (int, bool)
+}
+
+type evaluator struct {
+	mem    *rcs.Memory
+	lookup func(name string) (int, bool)
+	dot    int
+
+	in  string
+	pos int
+}
+
+func (e *evaluator) eval(in string) (int, error) {
+	e.in = in
+	e.pos = 0
+	if in == "" {
+		return 0, fmt.Errorf("invalid value: %v", in)
+	}
+	v, err := e.parseOr()
+	if err != nil {
+		return 0, err
+	}
+	if e.pos < len(e.in) {
+		return 0, fmt.Errorf("unexpected '%v' in %v", e.in[e.pos:], in)
+	}
+	return v, nil
+}
+
+func (e *evaluator) peek() byte {
+	if e.pos >= len(e.in) {
+		return 0
+	}
+	return e.in[e.pos]
+}
+
+func (e *evaluator) accept(op string) bool {
+	if strings.HasPrefix(e.in[e.pos:], op) {
+		e.pos += len(op)
+		return true
+	}
+	return false
+}
+
+func (e *evaluator) parseOr() (int, error) {
+	v, err := e.parseXor()
+	if err != nil {
+		return 0, err
+	}
+	for e.accept("|") {
+		r, err := e.parseXor()
+		if err != nil {
+			return 0, err
+		}
+		v |= r
+	}
+	return v, nil
+}
+
+func (e *evaluator) parseXor() (int, error) {
+	v, err := e.parseAnd()
+	if err != nil {
+		return 0, err
+	}
+	for e.accept("^") {
+		r, err := e.parseAnd()
+		if err != nil {
+			return 0, err
+		}
+		v ^= r
+	}
+	return v, nil
+}
+
+func (e *evaluator) parseAnd() (int, error) {
+	v, err := e.parseShift()
+	if err != nil {
+		return 0, err
+	}
+	for e.accept("&") {
+		r, err := e.parseShift()
+		if err != nil {
+			return 0, err
+		}
+		v &= r
+	}
+	return v, nil
+}
+
+func (e *evaluator) parseShift() (int, error) {
+	v, err := e.parseSum()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch {
+		case e.accept("<<"):
+			r, err := e.parseSum()
+			if err != nil {
+				return 0, err
+			}
+			v <<= uint(r)
+		case e.accept(">>"):
+			r, err := e.parseSum()
+			if err != nil {
+				return 0, err
+			}
+			v >>= uint(r)
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (e *evaluator) parseSum() (int, error) {
+	v, err := e.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch {
+		case e.accept("+"):
+			r, err := e.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			v += r
+		case e.accept("-"):
+			r, err := e.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			v -= r
+		default:
+			return v, nil
+		}
+	}
+}
+
+func (e *evaluator) parseProduct() (int, error) {
+	v, err := e.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		op := e.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return v, nil
+		}
+		e.pos++
+		r, err := e.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			v *= r
+		case '/', '%':
+			if r == 0 {
+				return 0, fmt.Errorf("division by zero: %v", e.in)
+			}
+			if op == '/' {
+				v /= r
+			} else {
+				v %= r
+			}
+		}
+	}
+}
+
+func (e *evaluator) parseUnary() (int, error) {
+	switch {
+	case e.accept("-"):
+		v, err := e.parseUnary()
+		return -v, err
+	case e.accept("~"):
+		v, err := e.parseUnary()
+		return ^v, err
+	case e.accept("+"):
+		return e.parseUnary()
+	}
+	return e.parsePrimary()
+}
+
+func (e *evaluator) parsePrimary() (int, error) {
+	ch := e.peek()
+	switch {
+	case ch == '(':
+		e.pos++
+		v, err := e.parseOr()
+		if err != nil {
+			return 0, err
+		}
+		if !e.accept(")") {
+			return 0, fmt.Errorf("missing ')' in %v", e.in)
+		}
+		return v, nil
+	case ch == '[':
+		return e.parseDeref()
+	case ch == '.' && !isNameChar(e.peekAt(1)):
+		e.pos++
+		return e.dot, nil
+	case ch == '$' || ch == '%' || isDigit(ch):
+		return e.parseNumber()
+	case isNameStart(ch):
+		return e.parseName()
+	}
+	if ch == 0 {
+		return 0, fmt.Errorf("unexpected end of expression: %v", e.in)
+	}
+	return 0, fmt.Errorf("unexpected '%c' in %v", ch, e.in)
+}
+
+func (e *evaluator) peekAt(n int) byte {
+	if e.pos+n >= len(e.in) {
+		return 0
+	}
+	return e.in[e.pos+n]
+}
+
+func (e *evaluator) parseDeref() (int, error) {
+	e.pos++ // [
+	addr, err := e.parseOr()
+	if err != nil {
+		return 0, err
+	}
+	if !e.accept("]") {
+		return 0, fmt.Errorf("missing ']' in %v", e.in)
+	}
+	if e.mem == nil {
+		return 0, fmt.Errorf("no memory to read: %v", e.in)
+	}
+	if addr < 0 || addr > e.mem.MaxAddr {
+		return 0, fmt.Errorf("invalid address: %v", formatAddress(addr))
+	}
+	switch {
+	case e.accept(".w"):
+		if addr+1 > e.mem.MaxAddr {
+			return 0, fmt.Errorf("invalid address: %v", formatAddress(addr+1))
+		}
+		return e.mem.PeekLE(addr), nil
+	case e.accept(".b"):
+	}
+	return int(e.mem.Peek(addr)), nil
+}
+
+func (e *evaluator) parseNumber() (int, error) {
+	start := e.pos
+	e.pos++
+	for e.pos < len(e.in) {
+		ch := e.in[e.pos]
+		// binary values may use formatting characters as printed by
+		// formatBits
+		if !isNameChar(ch) && !(e.in[start] == '%' && (ch == '.' || ch == ':')) {
+			break
+		}
+		e.pos++
+	}
+	str := e.in[start:e.pos]
+	v, err := parseUint(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value: %v", str)
+	}
+	return int(v), nil
+}
+
+func (e *evaluator) parseName() (int, error) {
+	start := e.pos
+	for e.pos < len(e.in) && isNameChar(e.in[e.pos]) {
+		e.pos++
+	}
+	name := e.in[start:e.pos]
+	if e.lookup != nil {
+		if v, ok := e.lookup(name); ok {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown name: %v", name)
+}
+
+func isDigit(ch byte) bool {
+	return ch >= '0' && ch <= '9'
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || unicode.IsLetter(rune(ch))
+}
+
+func isNameChar(ch byte) bool {
+	return isNameStart(ch) || isDigit(ch)
+}
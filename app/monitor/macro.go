@@ -0,0 +1,484 @@
+package monitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/config"
+)
+
+/*
+Commands can be grouped into blocks that span multiple lines. Each block
+ends with "end".
+
+A macro is a named sequence of commands:
+
+	define frame
+	    m $4ff0 $4fff
+	    go
+	    wait frames 1
+	    pause
+	    m $4ff0 $4fff
+	end
+
+and is run by using its name as a command. Arguments given to the macro
+replace $arg1, $arg2, and so on, and $argc is the number of arguments.
+Macros are saved in the "macros" file in the user directory. Use
+"macro name off" to remove a macro.
+
+User variables are set with "set $x = [$c6]" and $x is then replaced by the
+value in the commands that follow. A variable name cannot also be a
+hexadecimal value, such as $ab.
+
+Commands in an if block are run only when the condition is true. The
+condition is an expression that is not zero or a comparison as used by
+assert. An else block is optional:
+
+	if [$c6] != 0
+	    m $0277
+	else
+	    step
+	end
+
+Commands in a repeat block are run a number of times. If commands follow
+the count on the same line, only that command is repeated:
+
+	repeat 10 step
+*/
+
+const (
+	maxMacroDepth = 100
+	macroFile     = "macros"
+)
+
+var (
+	varRegex = regexp.MustCompile(`\$[A-Za-z_][A-Za-z0-9_]*`)
+	hexRegex = regexp.MustCompile(`^\$[0-9A-Fa-f]+$`)
+)
+
+// builtins are the names of commands that cannot be used as the name of a
+// macro.
+var builtins = map[string]bool{
+	"a": true, "assemble": true, "assert": true, "backtrace": true, "bt": true, "breakpoint": true,
+	"bp": true, "cheat": true, "config": true, "d": true, "define": true,
+	"disassemble": true, "e": true, "else": true, "encoding": true,
+	"end": true, "export": true, "g": true, "go": true, "i": true,
+	"if": true, "import": true, "info": true, "m": true, "macro": true,
+	"n": true, "next": true, "note": true, "out": true, "over": true,
+	"p": true, "pause": true, "project": true, "peek": true, "poke": true, "q": true,
+	"quit": true, "repeat": true, "s": true, "set": true, "sleep": true,
+	"snap": true, "snapshot": true, "source": true, "step": true, "sym": true,
+	"symbol": true, "t": true, "trace": true, "until": true, "w": true,
+	"wait": true, "watch": true, "x": true,
+}
+
+// feed handles a line that was typed or read from a script. A line that
+// starts a block is collected along with the lines that follow until the
+// block is complete.
+func (m *Monitor) feed(line string) error {
+	args := splitArgs(line)
+	if m.pending == nil && m.asmCPU != "" {
+		return m.feedAssembler(line)
+	}
+	if m.pending != nil {
+		m.pending = append(m.pending, line)
+		if len(args) > 0 {
+			m.depth += blockDelta(args)
+		}
+		if m.depth > 0 {
+			return nil
+		}
+		lines := m.pending
+		m.pending = nil
+		return m.runLines(lines)
+	}
+	if len(args) > 0 && isBlockStart(args) {
+		m.pending = []string{line}
+		m.depth = 1
+		return nil
+	}
+	return m.runLines([]string{line})
+}
+
+// runLines runs commands and blocks. Running stops at the first error.
+func (m *Monitor) runLines(lines []string) error {
+	for i := 0; i < len(lines); i++ {
+		args := splitArgs(lines[i])
+		if len(args) == 0 {
+			continue
+		}
+		if !isBlockStart(args) {
+			if err := m.runCommand(args); err != nil {
+				return err
+			}
+			continue
+		}
+		end := findEnd(lines, i)
+		if end < 0 {
+			return fmt.Errorf("missing end for: %v", strings.TrimSpace(lines[i]))
+		}
+		if err := m.runBlock(args, lines[i+1:end]); err != nil {
+			return err
+		}
+		i = end
+	}
+	return nil
+}
+
+func (m *Monitor) runBlock(args []string, body []string) error {
+	switch args[0] {
+	case "define":
+		if err := checkLen(args, 2, 2); err != nil {
+			return err
+		}
+		return m.define(args[1], body)
+	case "if":
+		if err := checkLen(args, 2, 5); err != nil {
+			return err
+		}
+		ok, err := m.test(m.expand(args[1:]))
+		if err != nil {
+			return err
+		}
+		then, otherwise := splitElse(body)
+		if ok {
+			return m.runLines(then)
+		}
+		return m.runLines(otherwise)
+	case "repeat":
+		if err := checkLen(args, 2, 2); err != nil {
+			return err
+		}
+		return m.repeat(m.expand(args[1:])[0], func() error {
+			return m.runLines(body)
+		})
+	}
+	return fmt.Errorf("no such block: %v", args[0])
+}
+
+// runCommand runs a single command after replacing variables. A macro is
+// run if one exists with the name of the command.
+func (m *Monitor) runCommand(args []string) error {
+	switch args[0] {
+	case "set":
+		// do not replace the name of the variable being set
+		if len(args) > 1 {
+			args = append(args[:2:2], m.expand(args[2:])...)
+		}
+	case "repeat":
+		// variables in the command are replaced each time it is run
+		return m.repeat(m.expand(args[1:2])[0], func() error {
+			return m.runCommand(args[2:])
+		})
+	default:
+		args = m.expand(args)
+	}
+	if body, ok := m.macros[args[0]]; ok {
+		if len(m.macroArgs) >= maxMacroDepth {
+			return fmt.Errorf("macro nesting too deep: %v", args[0])
+		}
+		m.macroArgs = append(m.macroArgs, args[1:])
+		defer func() {
+			m.macroArgs = m.macroArgs[:len(m.macroArgs)-1]
+		}()
+		return m.runLines(body)
+	}
+	return m.exec(args)
+}
+
+func (m *Monitor) repeat(count string, fn func() error) error {
+	var n int
+	err := m.sync(func() (err error) {
+		n, err = m.parseValue(count)
+		return
+	})
+	if err != nil {
+		return err
+	}
+	for i := 0; i < n; i++ {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// test evaluates the condition for an if block.
+func (m *Monitor) test(args []string) (bool, error) {
+	var ok bool
+	err := m.sync(func() error {
+		if len(args) == 1 {
+			v, err := m.parseValue(args[0])
+			ok = v != 0
+			return err
+		}
+		cond, err := m.parseCondition(args)
+		if err != nil {
+			return err
+		}
+		ok, _, err = cond()
+		return err
+	})
+	return ok, err
+}
+
+// expand replaces macro arguments and variables in args.
+func (m *Monitor) expand(args []string) []string {
+	var params []string
+	if len(m.macroArgs) > 0 {
+		params = m.macroArgs[len(m.macroArgs)-1]
+	}
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = varRegex.ReplaceAllStringFunc(arg, func(s string) string {
+			name := s[1:]
+			if name == "argc" && len(m.macroArgs) > 0 {
+				return strconv.Itoa(len(params))
+			}
+			if strings.HasPrefix(name, "arg") {
+				if n, err := strconv.Atoi(name[3:]); err == nil && n > 0 && n <= len(params) {
+					return params[n-1]
+				}
+			}
+			if v, ok := m.vars[name]; ok {
+				return strconv.Itoa(v)
+			}
+			return s
+		})
+	}
+	return out
+}
+
+func (m *Monitor) define(name string, body []string) error {
+	if !nameRegex.MatchString(name) || builtins[name] {
+		return fmt.Errorf("invalid macro name: %v", name)
+	}
+	if _, ok := m.mods[name]; ok {
+		return fmt.Errorf("invalid macro name: %v", name)
+	}
+	lines := make([]string, 0, len(body))
+	for _, line := range body {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	m.macros[name] = lines
+	return m.saveMacros()
+}
+
+func (m *Monitor) cmdMacro(args []string) error {
+	if err := checkLen(args, 0, 2); err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] == "list" {
+		names := make([]string, 0, len(m.macros))
+		for name := range m.macros {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) > 0 {
+			m.out.Println(strings.Join(names, "\n"))
+		}
+		return nil
+	}
+	name := args[0]
+	body, ok := m.macros[name]
+	if !ok {
+		return fmt.Errorf("no such macro: %v", name)
+	}
+	if len(args) == 1 {
+		m.out.Print(formatMacro(name, body))
+		return nil
+	}
+	if args[1] != "off" {
+		return fmt.Errorf("invalid argument: %v", args[1])
+	}
+	delete(m.macros, name)
+	return m.saveMacros()
+}
+
+func (m *Monitor) cmdSet(args []string) error {
+	if err := checkLen(args, 0, 3); err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		names := make([]string, 0, len(m.vars))
+		for name := range m.vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			m.out.Printf("$%-15v %v\n", name, formatValue(m.vars[name]))
+		}
+		return nil
+	}
+	name := args[0]
+	if !varRegex.MatchString(name) || varRegex.FindString(name) != name || hexRegex.MatchString(name) {
+		return fmt.Errorf("invalid variable: %v", name)
+	}
+	name = name[1:]
+	if len(args) == 1 {
+		v, ok := m.vars[name]
+		if !ok {
+			return fmt.Errorf("no such variable: $%v", name)
+		}
+		m.out.Print(formatValue(v))
+		return nil
+	}
+	expr := args[1]
+	if len(args) == 3 {
+		if args[1] != "=" {
+			return fmt.Errorf("invalid argument: %v", args[1])
+		}
+		expr = args[2]
+	}
+	if expr == "off" {
+		delete(m.vars, name)
+		return nil
+	}
+	v, err := m.parseValue(expr)
+	if err != nil {
+		return err
+	}
+	m.vars[name] = v
+	return nil
+}
+
+func (m *Monitor) loadMacros() {
+	if config.UserDir == "" {
+		return
+	}
+	data, err := ioutil.ReadFile(filepath.Join(config.UserDir, macroFile))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("unable to load macros: %v", err)
+		}
+		return
+	}
+	lines := strings.Split(string(data), "\n")
+	for i := 0; i < len(lines); i++ {
+		args := splitArgs(lines[i])
+		if len(args) == 0 {
+			continue
+		}
+		end := findEnd(lines, i)
+		if args[0] != "define" || len(args) != 2 || end < 0 {
+			log.Printf("unable to load macros: invalid line %v", i+1)
+			return
+		}
+		m.macros[args[1]] = lines[i+1 : end]
+		i = end
+	}
+	for name, body := range m.macros {
+		trimmed := make([]string, 0, len(body))
+		for _, line := range body {
+			if line = strings.TrimSpace(line); line != "" {
+				trimmed = append(trimmed, line)
+			}
+		}
+		m.macros[name] = trimmed
+	}
+}
+
+func (m *Monitor) saveMacros() error {
+	if config.UserDir == "" {
+		return nil
+	}
+	names := make([]string, 0, len(m.macros))
+	for name := range m.macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var text strings.Builder
+	for _, name := range names {
+		text.WriteString(formatMacro(name, m.macros[name]))
+	}
+	filename := filepath.Join(config.UserDir, macroFile)
+	if err := ioutil.WriteFile(filename, []byte(text.String()), 0644); err != nil {
+		return fmt.Errorf("unable to save macros: %v", err)
+	}
+	return nil
+}
+
+// formatMacro returns the definition of a macro with the body indented.
+func formatMacro(name string, body []string) string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "define %v\n", name)
+	depth := 1
+	for _, line := range body {
+		args := splitArgs(line)
+		if len(args) == 0 {
+			fmt.Fprintf(&text, "%v%v\n", strings.Repeat("    ", depth), line)
+			continue
+		}
+		indent := depth
+		if args[0] == "end" || args[0] == "else" {
+			indent--
+		}
+		fmt.Fprintf(&text, "%v%v\n", strings.Repeat("    ", indent), line)
+		depth += blockDelta(args)
+	}
+	text.WriteString("end\n")
+	return text.String()
+}
+
+func isBlockStart(args []string) bool {
+	switch args[0] {
+	case "define", "if":
+		return true
+	case "repeat":
+		return len(args) <= 2
+	}
+	return false
+}
+
+func blockDelta(args []string) int {
+	if isBlockStart(args) {
+		return 1
+	}
+	if args[0] == "end" {
+		return -1
+	}
+	return 0
+}
+
+// findEnd returns the index of the line that ends the block that starts at
+// lines[start] or -1 if there is no end.
+func findEnd(lines []string, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		args := splitArgs(lines[i])
+		if len(args) == 0 {
+			continue
+		}
+		depth += blockDelta(args)
+		if depth == 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// splitElse divides the body of an if block at the else that is not in a
+// nested block.
+func splitElse(body []string) ([]string, []string) {
+	depth := 0
+	for i, line := range body {
+		args := splitArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		if depth == 0 && args[0] == "else" {
+			return body[:i], body[i+1:]
+		}
+		depth += blockDelta(args)
+	}
+	return body, nil
+}
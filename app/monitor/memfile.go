@@ -0,0 +1,279 @@
+package monitor
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Memory files can be loaded and saved in the following formats:
+//
+//	bin    raw binary without an address
+//	ihex   Intel HEX
+//	srec   Motorola S-record
+//	prg    Commodore program with a two byte load address
+//
+// When the format is not given, it is found using the extension of the
+// file name and is raw binary if the extension is not known.
+var memFormats = map[string]string{
+	"bin":  "bin",
+	"raw":  "bin",
+	"ihex": "ihex",
+	"hex":  "ihex",
+	"srec": "srec",
+	"prg":  "prg",
+}
+
+var memFormatExts = map[string]string{
+	".hex":  "ihex",
+	".ihx":  "ihex",
+	".srec": "srec",
+	".s19":  "srec",
+	".s28":  "srec",
+	".s37":  "srec",
+	".mot":  "srec",
+	".prg":  "prg",
+}
+
+// memBlock is a run of bytes that starts at an address
+type memBlock struct {
+	addr int
+	data []uint8
+}
+
+func memFormatFor(filename string) string {
+	if format, ok := memFormatExts[strings.ToLower(filepath.Ext(filename))]; ok {
+		return format
+	}
+	return "bin"
+}
+
+// decodeMem returns the blocks of memory found in the file data. Raw
+// binary data is placed at address zero.
+func decodeMem(format string, data []byte) ([]memBlock, error) {
+	switch format {
+	case "bin":
+		return []memBlock{{addr: 0, data: data}}, nil
+	case "ihex":
+		return decodeIHex(data)
+	case "srec":
+		return decodeSRec(data)
+	case "prg":
+		if len(data) < 2 {
+			return nil, fmt.Errorf("invalid prg file")
+		}
+		addr := int(data[0]) | int(data[1])<<8
+		return []memBlock{{addr: addr, data: data[2:]}}, nil
+	}
+	return nil, fmt.Errorf("invalid format: %v", format)
+}
+
+// encodeMem returns the file data for a block of memory.
+func encodeMem(format string, b memBlock) ([]byte, error) {
+	switch format {
+	case "bin":
+		return b.data, nil
+	case "ihex":
+		return encodeIHex(b), nil
+	case "srec":
+		return encodeSRec(b), nil
+	case "prg":
+		if b.addr > 0xffff {
+			return nil, fmt.Errorf("address out of range for prg: %v", b.addr)
+		}
+		out := []byte{uint8(b.addr), uint8(b.addr >> 8)}
+		return append(out, b.data...), nil
+	}
+	return nil, fmt.Errorf("invalid format: %v", format)
+}
+
+// recordBytes decodes the hexadecimal digits of a record
+func recordBytes(text string) ([]uint8, error) {
+	if len(text)%2 != 0 {
+		return nil, fmt.Errorf("odd number of digits")
+	}
+	return hex.DecodeString(text)
+}
+
+func decodeIHex(data []byte) ([]memBlock, error) {
+	var blocks []memBlock
+	base := 0
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line[0] != ':' {
+			return nil, fmt.Errorf("line %v: missing start code", n)
+		}
+		rec, err := recordBytes(line[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", n, err)
+		}
+		if len(rec) < 5 || len(rec) != int(rec[0])+5 {
+			return nil, fmt.Errorf("line %v: invalid length", n)
+		}
+		sum := uint8(0)
+		for _, v := range rec {
+			sum += v
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %v: invalid checksum", n)
+		}
+		addr := int(rec[1])<<8 | int(rec[2])
+		payload := rec[4 : len(rec)-1]
+		switch rec[3] {
+		case 0x00: // data
+			blocks = append(blocks, memBlock{addr: base + addr, data: payload})
+		case 0x01: // end of file
+			return blocks, nil
+		case 0x02: // extended segment address
+			if len(payload) != 2 {
+				return nil, fmt.Errorf("line %v: invalid length", n)
+			}
+			base = (int(payload[0])<<8 | int(payload[1])) << 4
+		case 0x04: // extended linear address
+			if len(payload) != 2 {
+				return nil, fmt.Errorf("line %v: invalid length", n)
+			}
+			base = (int(payload[0])<<8 | int(payload[1])) << 16
+		case 0x03, 0x05: // start address, ignored
+		default:
+			return nil, fmt.Errorf("line %v: invalid record type: %02x", n, rec[3])
+		}
+	}
+	return blocks, scanner.Err()
+}
+
+func ihexRecord(kind uint8, addr int, data []uint8) string {
+	rec := []uint8{uint8(len(data)), uint8(addr >> 8), uint8(addr), kind}
+	rec = append(rec, data...)
+	sum := uint8(0)
+	for _, v := range rec {
+		sum += v
+	}
+	rec = append(rec, -sum)
+	return ":" + strings.ToUpper(hex.EncodeToString(rec)) + "\n"
+}
+
+func encodeIHex(b memBlock) []byte {
+	var out strings.Builder
+	upper := 0
+	for i := 0; i < len(b.data); {
+		addr := b.addr + i
+		if addr>>16 != upper {
+			upper = addr >> 16
+			out.WriteString(ihexRecord(0x04, 0, []uint8{uint8(upper >> 8), uint8(upper)}))
+		}
+		// a record cannot cross into the next 64K segment
+		n := 16
+		if rem := 0x10000 - addr&0xffff; n > rem {
+			n = rem
+		}
+		if n > len(b.data)-i {
+			n = len(b.data) - i
+		}
+		out.WriteString(ihexRecord(0x00, addr, b.data[i:i+n]))
+		i += n
+	}
+	out.WriteString(ihexRecord(0x01, 0, nil))
+	return []byte(out.String())
+}
+
+func decodeSRec(data []byte) ([]memBlock, error) {
+	var blocks []memBlock
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if len(line) < 2 || line[0] != 'S' {
+			return nil, fmt.Errorf("line %v: missing start code", n)
+		}
+		rec, err := recordBytes(line[2:])
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", n, err)
+		}
+		if len(rec) < 2 || len(rec) != int(rec[0])+1 {
+			return nil, fmt.Errorf("line %v: invalid length", n)
+		}
+		sum := uint8(0)
+		for _, v := range rec {
+			sum += v
+		}
+		if sum != 0xff {
+			return nil, fmt.Errorf("line %v: invalid checksum", n)
+		}
+		var addrLen int
+		switch line[1] {
+		case '1':
+			addrLen = 2
+		case '2':
+			addrLen = 3
+		case '3':
+			addrLen = 4
+		case '0', '5', '6', '7', '8', '9': // header, count, start address
+			continue
+		default:
+			return nil, fmt.Errorf("line %v: invalid record type: S%c", n, line[1])
+		}
+		if len(rec) < addrLen+2 {
+			return nil, fmt.Errorf("line %v: invalid length", n)
+		}
+		addr := 0
+		for _, v := range rec[1 : 1+addrLen] {
+			addr = addr<<8 | int(v)
+		}
+		blocks = append(blocks, memBlock{addr: addr, data: rec[1+addrLen : len(rec)-1]})
+	}
+	return blocks, scanner.Err()
+}
+
+func srecRecord(kind byte, addr int, addrLen int, data []uint8) string {
+	rec := []uint8{uint8(addrLen + len(data) + 1)}
+	for i := addrLen - 1; i >= 0; i-- {
+		rec = append(rec, uint8(addr>>(uint(i)*8)))
+	}
+	rec = append(rec, data...)
+	sum := uint8(0)
+	for _, v := range rec {
+		sum += v
+	}
+	rec = append(rec, ^sum)
+	return "S" + string(kind) + strings.ToUpper(hex.EncodeToString(rec)) + "\n"
+}
+
+func encodeSRec(b memBlock) []byte {
+	var out strings.Builder
+	data, end := byte('1'), byte('9')
+	addrLen := 2
+	if b.addr+len(b.data) > 0x10000 {
+		data, end = '2', '8'
+		addrLen = 3
+	}
+	out.WriteString(srecRecord('0', 0, 2, nil))
+	count := 0
+	for i := 0; i < len(b.data); i += 16 {
+		j := i + 16
+		if j > len(b.data) {
+			j = len(b.data)
+		}
+		out.WriteString(srecRecord(data, b.addr+i, addrLen, b.data[i:j]))
+		count++
+	}
+	if count <= 0xffff {
+		out.WriteString(srecRecord('5', count, 2, nil))
+	}
+	out.WriteString(srecRecord(end, b.addr, addrLen, nil))
+	return []byte(out.String())
+}
+
+// formatRange returns the range of addresses as used in messages
+func formatRange(start int, end int) string {
+	return fmt.Sprintf("$%04x-$%04x", start, end)
+}
@@ -0,0 +1,201 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestEncodeMem(t *testing.T) {
+	block := memBlock{addr: 0xc000, data: []uint8{0xa9, 0x41, 0x8d, 0x00, 0x04, 0x60}}
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"ihex", ":06C00000A9418D0004605F\n:00000001FF\n"},
+		{"srec", "S0030000FC\nS109C000A9418D0004605B\nS5030001FB\nS903C0003C\n"},
+		{"prg", "\x00\xc0\xa9\x41\x8d\x00\x04\x60"},
+		{"bin", "\xa9\x41\x8d\x00\x04\x60"},
+	}
+	for _, test := range tests {
+		t.Run(test.format, func(t *testing.T) {
+			have, err := encodeMem(test.format, block)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(have) != test.want {
+				t.Fatalf("\n have: %q \n want: %q", have, test.want)
+			}
+			blocks, err := decodeMem(test.format, have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []memBlock{block}
+			if test.format == "bin" {
+				want = []memBlock{{addr: 0, data: block.data}}
+			}
+			if !reflect.DeepEqual(blocks, want) {
+				t.Errorf("\n have: %v \n want: %v", blocks, want)
+			}
+		})
+	}
+}
+
+func TestEncodeIHexSegment(t *testing.T) {
+	data := make([]uint8, 8)
+	have := string(encodeIHex(memBlock{addr: 0xfffc, data: data}))
+	want := ":04FFFC000000000001\n" +
+		":020000040001F9\n" +
+		":0400000000000000FC\n" +
+		":00000001FF\n"
+	if have != want {
+		t.Fatalf("\n have: %q \n want: %q", have, want)
+	}
+	blocks, err := decodeIHex([]byte(have))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[1].addr != 0x10000 {
+		t.Errorf("unexpected blocks: %v", blocks)
+	}
+}
+
+func TestDecodeMemErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		data   string
+		err    string
+	}{
+		{"ihex", "06C00000A9418D0004605F", "line 1: missing start code"},
+		{"ihex", ":06C00000A9418D00046060", "line 1: invalid checksum"},
+		{"ihex", ":07C00000A9418D0004605F", "line 1: invalid length"},
+		{"srec", "S109C000A9418D0004605C", "line 1: invalid checksum"},
+		{"srec", "X109C000A9418D0004605B", "line 1: missing start code"},
+		{"prg", "\x00", "invalid prg file"},
+	}
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			_, err := decodeMem(test.format, []byte(test.data))
+			if err == nil || err.Error() != test.err {
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestMemLoadSave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, ext := range []string{".bin", ".hex", ".s19", ".prg"} {
+		t.Run(ext, func(t *testing.T) {
+			f := newMonitorFixture()
+			go f.mon.mach.Run()
+			defer func() {
+				f.mon.mach.Call(rcs.MachQuit)
+			}()
+			filename := filepath.Join(dir, "test"+ext)
+			f.mon.Eval(strings.Join([]string{
+				"poke $20 1 2 3",
+				"mem save " + filename + " $20 $22",
+				"mem fill $20 $22 0",
+				"mem load " + filename + " $40",
+				"m $40 $42",
+			}, "\n"))
+			want := strings.Join([]string{
+				"+ poke $20 1 2 3",
+				"+ mem save " + filename + " $20 $22",
+				"saved $0020-$0022",
+				"+ mem fill $20 $22 0",
+				"+ mem load " + filename + " $40",
+				"loaded $0040-$0042",
+				"+ m $40 $42",
+				"$0040  01 02 03                                          ...",
+			}, "\n")
+			have := strings.TrimSpace(f.output())
+			if have != want {
+				t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+			}
+		})
+	}
+}
+
+func TestMemLoadSaveWorkDir(t *testing.T) {
+	dir, dataDir, done := workDir(t)
+	defer done()
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval(strings.Join([]string{
+		"poke $20 1 2 3",
+		"mem save test.bin $20 $22",
+		"mem fill $20 $22 0",
+		"mem load test.bin $20",
+		"m $20 $22",
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ poke $20 1 2 3",
+		"+ mem save test.bin $20 $22",
+		"saved $0020-$0022",
+		"+ mem fill $20 $22 0",
+		"+ mem load test.bin $20",
+		"loaded $0020-$0022",
+		"+ m $20 $22",
+		"$0020  01 02 03                                          ...",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "test.bin")); err != nil {
+		t.Errorf("not saved to working directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "test.bin")); err == nil {
+		t.Errorf("saved to data directory")
+	}
+}
+
+func TestMemLoadComplete(t *testing.T) {
+	dir, dataDir, done := workDir(t)
+	defer done()
+	for _, name := range []string{
+		filepath.Join(dir, "a.bin"),
+		filepath.Join(dir, "b.hex"),
+		filepath.Join(dir, "sub", "c.bin"),
+		filepath.Join(dataDir, "rom.bin"),
+	} {
+		if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f := newMonitorFixture()
+	complete := acUserFiles(f.mon, "")
+	sep := string(filepath.Separator)
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"mem load ", []string{"a.bin", "b.hex", "sub" + sep}},
+		{"mem load a", []string{"a.bin"}},
+		{"mem load sub" + sep, []string{"sub" + sep + "c.bin"}},
+		{"mem load r", []string{}},
+	}
+	for _, test := range tests {
+		have := complete(test.line)
+		if !reflect.DeepEqual(have, test.want) {
+			t.Errorf("%q: \n have: %v \n want: %v", test.line, have, test.want)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package monitor
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"sort"
 	"strings"
@@ -19,6 +20,8 @@ type modMemory struct {
 	mem     *rcs.Memory
 	ptr     *rcs.Pointer
 	watches map[int]string
+	view    int // start address of the last dump
+	cheat   *cheatFinder
 }
 
 func newModMemory(mon *Monitor, comp rcs.Component) module {
@@ -43,14 +46,24 @@ func (m *modMemory) Command(args []string) error {
 		return m.cmdDump(args[0:])
 	}
 	switch args[0] {
+	case "cdl":
+		return m.cmdCDL(args[1:])
+	case "cheat":
+		return m.cmdCheat(args[1:])
 	case "dump":
 		return m.cmdDump(args[1:])
 	case "fill":
 		return m.cmdFill(args[1:])
+	case "find":
+		return m.cmdFind(args[1:])
+	case "load":
+		return m.cmdLoad(args[1:])
 	case "peek":
 		return m.cmdPeek(args[1:])
 	case "poke":
 		return m.cmdPoke(args[1:])
+	case "save":
+		return m.cmdSave(args[1:])
 	case "watch", "w":
 		return m.cmdWatch(args[1:])
 	}
@@ -66,7 +79,7 @@ func (m *modMemory) cmdDump(args []string) error {
 		addrStart = m.ptr.Addr()
 	}
 	if len(args) > 0 {
-		addr, err := parseAddress(m.mem, args[0])
+		addr, err := m.mon.parseAddress(m.mem, args[0])
 		if err != nil {
 			return err
 		}
@@ -74,7 +87,7 @@ func (m *modMemory) cmdDump(args []string) error {
 	}
 	addrEnd := addrStart + (m.mon.memLines * 16)
 	if len(args) > 1 {
-		addr, err := parseAddress(m.mem, args[1])
+		addr, err := m.mon.parseAddress(m.mem, args[1])
 		if err != nil {
 			return err
 		}
@@ -84,8 +97,11 @@ func (m *modMemory) cmdDump(args []string) error {
 	if !ok {
 		return fmt.Errorf("invalid encoding: %v", m.mon.encoding)
 	}
-	m.mon.out.Println(dump(m.mem, addrStart, addrEnd, decoder, m.prefix()))
+	notes := m.mon.annotations(m.mem).Notes
+	m.mon.out.Println(dump(m.mem, addrStart, addrEnd, decoder, m.prefix(), notes))
+	m.view = addrStart
 	m.ptr.SetAddr(addrEnd)
+	m.mon.dot = addrEnd
 	m.mon.defaultCmd = fmt.Sprintf("%v dump", m.name)
 	return nil
 }
@@ -94,15 +110,15 @@ func (m *modMemory) cmdFill(args []string) error {
 	if err := checkLen(args, 3, 3); err != nil {
 		return err
 	}
-	startAddr, err := parseAddress(m.mem, args[0])
+	startAddr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return err
 	}
-	endAddr, err := parseAddress(m.mem, args[1])
+	endAddr, err := m.mon.parseAddress(m.mem, args[1])
 	if err != nil {
 		return err
 	}
-	value, err := parseValue8(args[2])
+	value, err := m.mon.parseValue8(args[2])
 	if err != nil {
 		return err
 	}
@@ -115,11 +131,74 @@ func (m *modMemory) cmdFill(args []string) error {
 	return nil
 }
 
+// cmdLoad writes the contents of a file to the selected bank. The address,
+// when given, is where the lowest address found in the file is loaded.
+func (m *modMemory) cmdLoad(args []string) error {
+	if err := checkLen(args, 1, 3); err != nil {
+		return err
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	format := memFormatFor(filename)
+	addr := -1
+	for _, arg := range args[1:] {
+		if f, ok := memFormats[arg]; ok {
+			format = f
+			continue
+		}
+		a, err := m.mon.parseAddress(m.mem, arg)
+		if err != nil {
+			return err
+		}
+		addr = a
+	}
+	if format == "bin" && addr < 0 {
+		return fmt.Errorf("address required for raw binary")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	blocks, err := decodeMem(format, data)
+	if err != nil {
+		return fmt.Errorf("unable to load %v: %v", args[0], err)
+	}
+	if len(blocks) == 0 {
+		return fmt.Errorf("unable to load %v: no data", args[0])
+	}
+	start, end := blocks[0].addr, blocks[0].addr
+	for _, b := range blocks {
+		if b.addr < start {
+			start = b.addr
+		}
+		if last := b.addr + len(b.data) - 1; last > end {
+			end = last
+		}
+	}
+	offset := 0
+	if addr >= 0 {
+		offset = addr - start
+	}
+	start, end = start+offset, end+offset
+	if end > m.mem.MaxAddr {
+		return fmt.Errorf("unable to load %v: %v does not fit in memory", args[0], formatRange(start, end))
+	}
+	for _, b := range blocks {
+		for i, v := range b.data {
+			m.mem.Write(b.addr+offset+i, v)
+		}
+	}
+	m.mon.out.Printf("loaded %v", formatRange(start, end))
+	return nil
+}
+
 func (m *modMemory) cmdPeek(args []string) error {
 	if err := checkLen(args, 1, 1); err != nil {
 		return err
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return err
 	}
@@ -132,13 +211,13 @@ func (m *modMemory) cmdPoke(args []string) error {
 	if err := checkLen(args, 2, maxArgs); err != nil {
 		return err
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return err
 	}
 	values := []uint8{}
 	for _, str := range args[1:] {
-		v, err := parseValue8(str)
+		v, err := m.mon.parseValue8(str)
 		if err != nil {
 			return err
 		}
@@ -148,6 +227,50 @@ func (m *modMemory) cmdPoke(args []string) error {
 	return nil
 }
 
+// cmdSave writes the contents of the selected bank from the start address
+// to the end address, inclusive, to a file.
+func (m *modMemory) cmdSave(args []string) error {
+	if err := checkLen(args, 3, 4); err != nil {
+		return err
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	format := memFormatFor(filename)
+	if len(args) == 4 {
+		f, ok := memFormats[args[3]]
+		if !ok {
+			return fmt.Errorf("invalid format: %v", args[3])
+		}
+		format = f
+	}
+	start, err := m.mon.parseAddress(m.mem, args[1])
+	if err != nil {
+		return err
+	}
+	end, err := m.mon.parseAddress(m.mem, args[2])
+	if err != nil {
+		return err
+	}
+	if end < start {
+		return fmt.Errorf("invalid range: %v", formatRange(start, end))
+	}
+	data := make([]uint8, 0, end-start+1)
+	for addr := start; addr <= end; addr++ {
+		data = append(data, m.mem.Peek(addr))
+	}
+	out, err := encodeMem(format, memBlock{addr: start, data: data})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, out, 0644); err != nil {
+		return err
+	}
+	m.mon.out.Printf("saved %v", formatRange(start, end))
+	return nil
+}
+
 func (m *modMemory) cmdWatch(args []string) error {
 	if len(args) == 0 {
 		return m.cmdWatchList(args[0:])
@@ -165,7 +288,7 @@ func (m *modMemory) cmdWatchClear(args []string) error {
 	if err := checkLen(args, 1, 1); err != nil {
 		return err
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return err
 	}
@@ -207,7 +330,7 @@ func (m *modMemory) cmdWatchSwitch(args []string) error {
 	if err := checkLen(args, 1, 2); err != nil {
 		return nil
 	}
-	addr, err := parseAddress(m.mem, args[0])
+	addr, err := m.mon.parseAddress(m.mem, args[0])
 	if err != nil {
 		return fmt.Errorf("invalid address: %v", args[0])
 	}
@@ -269,10 +392,39 @@ func (m *modMemory) prefix() string {
 
 func (m *modMemory) AutoComplete() []readline.PrefixCompleterInterface {
 	return []readline.PrefixCompleterInterface{
+		readline.PcItem("cdl",
+			readline.PcItem("clear"),
+			readline.PcItem("load",
+				readline.PcItemDynamic(acUserFiles(m.mon, "")),
+			),
+			readline.PcItem("off"),
+			readline.PcItem("on"),
+			readline.PcItem("save"),
+			readline.PcItem("usage"),
+		),
+		readline.PcItem("cheat",
+			readline.PcItem("start"),
+			readline.PcItem("changed"),
+			readline.PcItem("unchanged"),
+			readline.PcItem("increased"),
+			readline.PcItem("decreased"),
+			readline.PcItem("equal"),
+			readline.PcItem("list"),
+			readline.PcItem("off"),
+		),
 		readline.PcItem("dump"),
 		readline.PcItem("fill"),
+		readline.PcItem("find",
+			readline.PcItem("bytes"),
+			readline.PcItem("text"),
+			readline.PcItem("word"),
+		),
+		readline.PcItem("load",
+			readline.PcItemDynamic(acUserFiles(m.mon, "")),
+		),
 		readline.PcItem("peek"),
 		readline.PcItem("poke"),
+		readline.PcItem("save"),
 		readline.PcItem("watch-clear"),
 		readline.PcItem("watch-list"),
 		readline.PcItem("watch-none"),
@@ -284,7 +436,9 @@ func (m *modMemory) Silence() error {
 	return m.cmdWatchNone([]string{})
 }
 
-func dump(m *rcs.Memory, start int, end int, decode rcs.CharDecoder, prefix string) string {
+// dump formats memory from start to end. Notes for the addresses in a row
+// are shown at the end of the row.
+func dump(m *rcs.Memory, start int, end int, decode rcs.CharDecoder, prefix string, notes map[int]string) string {
 	var buf bytes.Buffer
 	var chars bytes.Buffer
 
@@ -302,7 +456,7 @@ func dump(m *rcs.Memory, start int, end int, decode rcs.CharDecoder, prefix stri
 			buf.WriteString("   ")
 			chars.WriteString(" ")
 		} else {
-			value := m.Read(addr)
+			value := m.Peek(addr)
 			buf.WriteString(fmt.Sprintf(" %02x", value))
 			ch, printable := decode(value)
 			if printable {
@@ -316,6 +470,7 @@ func dump(m *rcs.Memory, start int, end int, decode rcs.CharDecoder, prefix stri
 		}
 		if addr%0x10 == 0x0f {
 			buf.WriteString("  " + chars.String())
+			buf.WriteString(rowNotes(notes, addr-0x0f, start, end))
 			if addr < end-1 {
 				buf.WriteString("\n")
 			}
@@ -60,7 +60,20 @@ type Monitor struct {
 	defaultCmd string
 	memLines   int
 	dasmLines  int
+	symbols    map[string]int
+	dot        int // current address, used in expressions
 	cw         *consoleWriter
+	statusFunc func(rcs.Status) // if set, called instead of showing status
+	waitLimit  int              // milliseconds before a wait fails, 0 for none
+	autosave   bool             // save the default project on close
+	notes      map[*rcs.Memory]*rcs.Annotations
+	asmCPU     string // CPU that lines are assembled for, if assembling
+	asmAddr    int    // address of the next line to assemble
+	macros     map[string][]string
+	macroArgs  [][]string // arguments for each macro being run
+	vars       map[string]int
+	pending    []string // lines of a block that is not yet complete
+	depth      int      // nesting level of the pending block
 }
 
 var silencers = make([]func(), 0, 0)
@@ -78,6 +91,10 @@ func New(mach *rcs.Mach) (*Monitor, error) {
 		mods:    make(map[string]module),
 		cpu:     make(map[string]rcs.CPU),
 		tracers: make(map[string]*rcs.Disassembler),
+		symbols: make(map[string]int),
+		notes:   make(map[*rcs.Memory]*rcs.Annotations),
+		macros:  make(map[string][]string),
+		vars:    make(map[string]int),
 		in:      readline.NewCancelableStdin(os.Stdin),
 		out:     log.New(cw, "", 0),
 		//out:      log.New(os.Stdout, "", 0),
@@ -107,12 +124,7 @@ func New(mach *rcs.Mach) (*Monitor, error) {
 				m.sc = comp.Name
 			}
 
-			var tracer *rcs.Disassembler
-			cpud, ok := cpu.(rcs.CPUDisassembler)
-			if ok {
-				tracer = cpud.NewDisassembler()
-			}
-			m.tracers[comp.Name] = tracer
+			m.tracers[comp.Name] = m.newDisassembler(cpu)
 		}
 	}
 
@@ -132,6 +144,7 @@ func New(mach *rcs.Mach) (*Monitor, error) {
 	m.rl = rl
 	cw.RefreshFunc = func() { m.rl.Refresh() }
 	m.rl.SetPrompt(m.getPrompt())
+	m.loadMacros()
 	return m, nil
 }
 
@@ -152,9 +165,9 @@ func (m *Monitor) Eval(str string) error {
 	lines := strings.Split(str, "\n")
 	for _, line := range lines {
 		args := splitArgs(line)
-		if len(args) > 0 {
+		if len(args) > 0 || m.asmCPU != "" {
 			m.out.Printf("+ %v\n", line)
-			err := m.dispatch(args)
+			err := m.feed(line)
 			if err != nil {
 				m.out.Printf("%v", err)
 			}
@@ -164,37 +177,89 @@ func (m *Monitor) Eval(str string) error {
 }
 
 func (m *Monitor) Close() {
+	if err := m.autosaveProject(); err != nil {
+		m.out.Println(err)
+	}
 	m.in.Close()
 	m.rl.Close()
 	m.cw.Flush()
 }
 
 func (m *Monitor) parse(line string) {
+	err := m.enter(line)
+	m.rl.SetPrompt(m.getPrompt())
+	if err != nil {
+		m.out.Printf("%v", err)
+	}
+}
+
+// enter runs a line typed by the user. An empty line repeats the previous
+// command, if any, except while assembling where it ends the assembler.
+// The prompt is left for the front end to update.
+func (m *Monitor) enter(line string) error {
 	line = strings.TrimSpace(line)
+	if m.asmCPU != "" && m.pending == nil {
+		m.defaultCmd = ""
+		return m.feed(line)
+	}
 	if line == "" && m.defaultCmd != "" {
 		line = m.defaultCmd
 		m.defaultCmd = ""
 	}
 	if line == "" {
-		return
+		return nil
 	}
-	args := splitArgs(line)
-	err := m.dispatch(args)
-	if err != nil {
-		m.out.Printf("%v", err)
-		return
+	return m.feed(line)
+}
+
+// exec runs the command in args. Commands run on the goroutine of the
+// machine so that they do not race with the executing CPUs. The exceptions
+// are those that block or stop the monitor.
+func (m *Monitor) exec(args []string) error {
+	switch args[0] {
+	case "sleep", "q", "quit", "wait":
+		return m.dispatch(args)
 	}
+	return m.sync(func() error {
+		return m.dispatch(args)
+	})
+}
+
+// capture runs the command in args and returns the output instead of
+// printing it. It must be called on the goroutine of the machine.
+func (m *Monitor) capture(args ...string) string {
+	var buf bytes.Buffer
+	out := m.out
+	m.out = log.New(&buf, "", 0)
+	if err := m.dispatch(args); err != nil {
+		m.out.Println(err)
+	}
+	m.out = out
+	return buf.String()
+}
+
+// sync calls fn on the goroutine of the machine and waits for it to
+// return.
+func (m *Monitor) sync(fn func() error) error {
+	_, err := m.mach.Call(rcs.MachDo, fn)
+	return err
 }
 
 func (m *Monitor) dispatch(args []string) error {
 	switch args[0] {
 	case
+		"assemble", "a",
+		"backtrace", "bt",
 		"breakpoint", "bp",
 		"disassemble", "d",
 		"info", "i",
 		"next", "n",
+		"out",
+		"over",
+		"source",
 		"step", "s",
-		"trace", "t":
+		"trace", "t",
+		"until":
 		return m.mods[m.sc].Command(args)
 	case "m":
 		parent := m.comps[m.sc].Parent
@@ -207,6 +272,8 @@ func (m *Monitor) dispatch(args []string) error {
 		return m.mods[parent].Command(args)
 	case "config":
 		return m.cmdConfig(args[1:])
+	case "cheat":
+		return m.cmdCheat(args[1:])
 	case "encoding", "e":
 		return m.cmdEncoding(args[1:])
 	case "export":
@@ -215,14 +282,28 @@ func (m *Monitor) dispatch(args []string) error {
 		return m.cmdGo(args[1:])
 	case "import":
 		return m.cmdImport(args[1:])
+	case "note":
+		return m.cmdNote(args[1:])
 	case "pause", "p":
 		return m.cmdPause(args[1:])
+	case "project":
+		return m.cmdProject(args[1:])
 	case "sleep":
 		return m.cmdSleep(args[1:])
 	case "snapshot", "snap":
 		return m.cmdSnapshot(args[1:])
+	case "assert":
+		return m.cmdAssert(args[1:])
+	case "macro":
+		return m.cmdMacro(args[1:])
+	case "set":
+		return m.cmdSet(args[1:])
+	case "symbol", "sym":
+		return m.cmdSymbol(args[1:])
 	case "q", "quit":
 		return m.cmdQuit(args[1:])
+	case "wait":
+		return m.cmdWait(args[1:])
 	case "x":
 		return m.cmdSilence()
 	}
@@ -231,13 +312,15 @@ func (m *Monitor) dispatch(args []string) error {
 		return mod.Command(args[1:])
 	}
 
-	val, err := parseValue(args[0])
+	val, err := m.parseValue(args[0])
 	if err == nil {
 		m.out.Print(formatValue(val))
 		return nil
 	}
-
-	return fmt.Errorf("no such command: %v", args[0])
+	if nameRegex.MatchString(args[0]) {
+		return fmt.Errorf("no such command: %v", args[0])
+	}
+	return err
 }
 
 // ============================================================================
@@ -249,9 +332,13 @@ func (m *Monitor) cmdConfig(args []string) error {
 	}
 	switch args[0] {
 	case "lines-memory":
-		return valueInt(m.out, &m.memLines, args[1:])
+		return valueInt(m, &m.memLines, args[1:])
 	case "lines-disassembly":
-		return valueInt(m.out, &m.dasmLines, args[1:])
+		return valueInt(m, &m.dasmLines, args[1:])
+	case "project-autosave":
+		return valueBool(m.out, &m.autosave, args[1:])
+	case "wait-limit":
+		return valueInt(m, &m.waitLimit, args[1:])
 	}
 	return fmt.Errorf("no such configuration: %v", args[0])
 }
@@ -308,6 +395,9 @@ func (m *Monitor) cmdPause(args []string) error {
 }
 
 func (m *Monitor) cmdQuit(args []string) error {
+	if err := m.autosaveProject(); err != nil {
+		m.out.Println(err)
+	}
 	m.rl.Close()
 	m.mach.Command(rcs.MachQuit)
 	runtime.Goexit()
@@ -327,7 +417,11 @@ func (m *Monitor) cmdSleep(args []string) error {
 	}
 	duration := 1 * time.Second
 	if len(args) > 0 {
-		v, err := parseValue(args[0])
+		var v int
+		err := m.sync(func() (err error) {
+			v, err = m.parseValue(args[0])
+			return
+		})
 		if err != nil {
 			return err
 		}
@@ -350,6 +444,70 @@ func (m *Monitor) cmdSnapshot(args []string) error {
 	return nil
 }
 
+func (m *Monitor) cmdSymbol(args []string) error {
+	if len(args) == 0 {
+		return m.cmdSymbolList(args[0:])
+	}
+	switch args[0] {
+	case "list":
+		return m.cmdSymbolList(args[1:])
+	case "none":
+		return m.cmdSymbolNone(args[1:])
+	}
+	return m.cmdSymbolSwitch(args[0:])
+}
+
+func (m *Monitor) cmdSymbolList(args []string) error {
+	if err := checkLen(args, 0, 0); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(m.symbols))
+	for name := range m.symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		m.out.Printf("%-16v %v\n", name, formatAddress(m.symbols[name]))
+	}
+	return nil
+}
+
+func (m *Monitor) cmdSymbolNone(args []string) error {
+	if err := checkLen(args, 0, 0); err != nil {
+		return err
+	}
+	m.symbols = make(map[string]int)
+	return nil
+}
+
+func (m *Monitor) cmdSymbolSwitch(args []string) error {
+	if err := checkLen(args, 1, 2); err != nil {
+		return err
+	}
+	name := args[0]
+	if !nameRegex.MatchString(name) || strings.Contains(name, "-") {
+		return fmt.Errorf("invalid symbol: %v", name)
+	}
+	if len(args) == 1 {
+		v, ok := m.symbols[name]
+		if !ok {
+			return fmt.Errorf("no such symbol: %v", name)
+		}
+		m.out.Print(formatValue(v))
+		return nil
+	}
+	if args[1] == "off" {
+		delete(m.symbols, name)
+		return nil
+	}
+	v, err := m.parseValue(args[1])
+	if err != nil {
+		return err
+	}
+	m.symbols[name] = v
+	return nil
+}
+
 // ============================================================================
 // autocomplete
 
@@ -363,10 +521,29 @@ func (n byName) Less(i, j int) bool {
 
 func newCompleter(m *Monitor) *readline.PrefixCompleter {
 	cmds := []readline.PrefixCompleterInterface{
+		readline.PcItem("assert",
+			readline.PcItem("mem"),
+			readline.PcItem("reg"),
+		),
+		readline.PcItem("assemble"),
+		readline.PcItem("backtrace",
+			readline.PcItem("track"),
+		),
 		readline.PcItem("breakpoint"),
+		readline.PcItem("cheat",
+			readline.PcItem("add"),
+			readline.PcItem("clear"),
+			readline.PcItem("import",
+				readline.PcItemDynamic(acUserFiles(m, "")),
+			),
+			readline.PcItem("list"),
+		),
+		readline.PcItem("define"),
 		readline.PcItem("config",
 			readline.PcItem("lines-memory"),
 			readline.PcItem("lines-disassembly"),
+			readline.PcItem("project-autosave"),
+			readline.PcItem("wait-limit"),
 		),
 		readline.PcItem("encoding",
 			readline.PcItemDynamic(acEncodings(m)),
@@ -374,12 +551,40 @@ func newCompleter(m *Monitor) *readline.PrefixCompleter {
 		readline.PcItem("export"),
 		readline.PcItem("disassemble"),
 		readline.PcItem("import"),
+		readline.PcItem("if"),
 		readline.PcItem("info"),
+		readline.PcItem("macro",
+			readline.PcItemDynamic(acMacros(m)),
+		),
 		readline.PcItem("next"),
+		readline.PcItem("note",
+			readline.PcItem("data"),
+			readline.PcItem("list"),
+		),
+		readline.PcItem("out"),
+		readline.PcItem("over"),
+		readline.PcItem("project",
+			readline.PcItem("load"),
+			readline.PcItem("save"),
+		),
 		readline.PcItem("quit"),
+		readline.PcItem("repeat"),
+		readline.PcItem("set"),
+		readline.PcItem("source"),
 		readline.PcItem("step"),
 		readline.PcItem("sleep"),
 		readline.PcItem("snapshot"),
+		readline.PcItem("symbol",
+			readline.PcItem("list"),
+			readline.PcItem("none"),
+		),
+		readline.PcItem("until"),
+		readline.PcItem("wait",
+			readline.PcItem("break"),
+			readline.PcItem("frames"),
+			readline.PcItem("mem"),
+			readline.PcItem("reg"),
+		),
 		readline.PcItem("watch"),
 	}
 	for key, mod := range m.mods {
@@ -423,6 +628,41 @@ func acDataFiles(m *Monitor, suffix string) func(string) []string {
 	}
 }
 
+// acUserFiles completes the names of files for commands that find them
+// with userPath. Relative names are relative to the working directory.
+func acUserFiles(m *Monitor, suffix string) func(string) []string {
+	return func(line string) []string {
+		results := make([]string, 0, 0)
+		arg := ""
+		if i := strings.LastIndex(line, " "); i >= 0 {
+			arg = line[i+1:]
+		}
+		dir, prefix := filepath.Split(arg)
+		path, err := userPath(dir)
+		if err != nil {
+			m.out.Println(err)
+			return results
+		}
+		files, err := ioutil.ReadDir(path)
+		if err != nil {
+			m.out.Println(err)
+			return results
+		}
+		for _, f := range files {
+			name := f.Name()
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if f.IsDir() {
+				results = append(results, dir+name+string(filepath.Separator))
+			} else if strings.HasSuffix(name, suffix) {
+				results = append(results, dir+name)
+			}
+		}
+		return results
+	}
+}
+
 func acEncodings(m *Monitor) func(string) []string {
 	return func(line string) []string {
 		names := make([]string, 0)
@@ -460,6 +700,10 @@ func (m *Monitor) cpuCallback(evt rcs.MachEvent, args ...interface{}) {
 		m.out.Println(args[0])
 	case rcs.StatusEvent:
 		status := args[0].(rcs.Status)
+		if m.statusFunc != nil {
+			m.statusFunc(status)
+			return
+		}
 		if status == rcs.Break {
 			m.out.Println()
 			m.dispatch([]string{"i"})
@@ -482,11 +726,14 @@ func checkLen(args []string, min int, max int) error {
 func parseUint(str string, bitSize int) (uint64, error) {
 	base := 10
 	switch {
-	case strings.HasPrefix(str, "$"), strings.HasPrefix(str, "0x"):
+	case strings.HasPrefix(str, "$"):
 		str = str[1:]
 		base = 16
+	case strings.HasPrefix(str, "0x"):
+		str = str[2:]
+		base = 16
 	case strings.HasPrefix(str, "%"), strings.HasPrefix(str, "0b"):
-		str = str[1:]
+		str = strings.TrimPrefix(str[1:], "b")
 		str = strings.Replace(str, ":", "", -1)
 		str = strings.Replace(str, ".", "", -1)
 		base = 2
@@ -501,7 +748,17 @@ func loadPath(name string) string {
 	return filepath.Join(config.DataDir, name)
 }
 
-var whitespaceRegex = regexp.MustCompile("\\s+")
+// userPath returns the name of a file that the monitor writes, or reads
+// back, for the user. Unlike loadPath, which finds files in the read-only
+// data directory, a relative name is relative to the working directory.
+func userPath(name string) (string, error) {
+	return filepath.Abs(name)
+}
+
+var (
+	whitespaceRegex = regexp.MustCompile("\\s+")
+	nameRegex       = regexp.MustCompile("^[A-Za-z_][A-Za-z0-9_-]*$")
+)
 
 func splitArgs(line string) []string {
 	line = strings.TrimSpace(line)
@@ -511,25 +768,77 @@ func splitArgs(line string) []string {
 	return whitespaceRegex.Split(line, -1)
 }
 
-func parseValue(str string) (int, error) {
-	value, err := parseUint(str, 64)
-	if err != nil {
-		return 0, fmt.Errorf("invalid value: %v", str)
+// eval evaluates the expression in str. Names are resolved using the
+// registers of the selected CPU and then symbols. Memory references read
+// from the memory of the selected CPU.
+func (m *Monitor) eval(str string) (int, error) {
+	var mem *rcs.Memory
+	if cpu, ok := m.cpu[m.sc]; ok {
+		mem = cpu.Memory()
+	}
+	return m.evalMem(mem, str)
+}
+
+// evalMem evaluates the expression in str using mem for memory references.
+func (m *Monitor) evalMem(mem *rcs.Memory, str string) (int, error) {
+	e := &evaluator{
+		mem:    mem,
+		lookup: m.lookup,
+		dot:    m.dot,
+	}
+	return e.eval(str)
+}
+
+func (m *Monitor) lookup(name string) (int, bool) {
+	if mod, ok := m.mods[m.sc].(valuer); ok {
+		if v, ok := mod.Value(name); ok {
+			return v, true
+		}
 	}
-	return int(value), nil
+	v, ok := m.symbols[name]
+	return v, ok
+}
+
+// symbolize returns the name of the nearest symbol at or below addr, with
+// an offset if needed, such as "main+4". An empty string is returned if
+// there is no symbol within $100 bytes.
+func (m *Monitor) symbolize(addr int) string {
+	best, bestAddr := "", -1
+	for name, v := range m.symbols {
+		if v > addr || addr-v >= 0x100 {
+			continue
+		}
+		if v > bestAddr || (v == bestAddr && name < best) {
+			best, bestAddr = name, v
+		}
+	}
+	if best == "" || bestAddr == addr {
+		return best
+	}
+	return fmt.Sprintf("%v+%v", best, addr-bestAddr)
+}
+
+func (m *Monitor) parseValue(str string) (int, error) {
+	return m.eval(str)
 }
 
-func parseValue8(str string) (uint8, error) {
-	value, err := parseUint(str, 8)
+func (m *Monitor) parseValue8(str string) (uint8, error) {
+	value, err := m.eval(str)
 	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > 0xff {
 		return 0, fmt.Errorf("invalid value: %v", str)
 	}
 	return uint8(value), nil
 }
 
-func parseValue16(str string) (uint16, error) {
-	value, err := parseUint(str, 16)
+func (m *Monitor) parseValue16(str string) (uint16, error) {
+	value, err := m.eval(str)
 	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > 0xffff {
 		return 0, fmt.Errorf("invalid value: %v", str)
 	}
 	return uint16(value), nil
@@ -593,15 +902,15 @@ func valueBool(out *log.Logger, val *bool, args []string) error {
 	return nil
 }
 
-func valueInt(out *log.Logger, val *int, args []string) error {
+func valueInt(mon *Monitor, val *int, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
-		out.Println(formatValue(int(*val)))
+		mon.out.Println(formatValue(int(*val)))
 		return nil
 	}
-	v, err := parseValue(args[0])
+	v, err := mon.parseValue(args[0])
 	if err != nil {
 		return err
 	}
@@ -609,15 +918,15 @@ func valueInt(out *log.Logger, val *int, args []string) error {
 	return nil
 }
 
-func valueUint8(out *log.Logger, val *uint8, args []string) error {
+func valueUint8(mon *Monitor, val *uint8, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
-		out.Println(formatValue(int(*val)))
+		mon.out.Println(formatValue(int(*val)))
 		return nil
 	}
-	v, err := parseValue8(args[0])
+	v, err := mon.parseValue8(args[0])
 	if err != nil {
 		return err
 	}
@@ -625,15 +934,15 @@ func valueUint8(out *log.Logger, val *uint8, args []string) error {
 	return nil
 }
 
-func valueUint16(out *log.Logger, val *uint16, args []string) error {
+func valueUint16(mon *Monitor, val *uint16, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
-		out.Println(formatValue(int(*val)))
+		mon.out.Println(formatValue(int(*val)))
 		return nil
 	}
-	v, err := parseValue16(args[0])
+	v, err := mon.parseValue16(args[0])
 	if err != nil {
 		return err
 	}
@@ -641,16 +950,16 @@ func valueUint16(out *log.Logger, val *uint16, args []string) error {
 	return nil
 }
 
-func valueUint16HL(out *log.Logger, hi *uint8, lo *uint8, args []string) error {
+func valueUint16HL(mon *Monitor, hi *uint8, lo *uint8, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
 		val := int(*hi)<<8 | int(*lo)
-		out.Println(formatValue(val))
+		mon.out.Println(formatValue(val))
 		return nil
 	}
-	v, err := parseValue16(args[0])
+	v, err := mon.parseValue16(args[0])
 	if err != nil {
 		return err
 	}
@@ -659,15 +968,15 @@ func valueUint16HL(out *log.Logger, hi *uint8, lo *uint8, args []string) error {
 	return nil
 }
 
-func valueIntF(out *log.Logger, load rcs.Load, store rcs.Store, args []string) error {
+func valueIntF(mon *Monitor, load rcs.Load, store rcs.Store, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
-		out.Println(formatValue(load()))
+		mon.out.Println(formatValue(load()))
 		return nil
 	}
-	v, err := parseValue(args[0])
+	v, err := mon.parseValue(args[0])
 	if err != nil {
 		return err
 	}
@@ -711,15 +1020,15 @@ func valueBit(out *log.Logger, val *uint8, mask uint8, args []string) error {
 	return nil
 }
 
-func valueFunc8(out *log.Logger, load rcs.Load8, store rcs.Store8, args []string) error {
+func valueFunc8(mon *Monitor, load rcs.Load8, store rcs.Store8, args []string) error {
 	if err := checkLen(args, 0, 1); err != nil {
 		return err
 	}
 	if len(args) == 0 {
-		out.Println(formatValue(int(load())))
+		mon.out.Println(formatValue(int(load())))
 		return nil
 	}
-	val, err := parseValue8(args[0])
+	val, err := mon.parseValue8(args[0])
 	if err != nil {
 		return err
 	}
@@ -770,12 +1079,15 @@ func terminal(args []string, fn func() error) error {
 	return fn()
 }
 
-func parseAddress(mem *rcs.Memory, str string) (int, error) {
-	value, err := parseUint(str, 64)
-	if err != nil || int(value) > mem.MaxAddr {
+func (m *Monitor) parseAddress(mem *rcs.Memory, str string) (int, error) {
+	value, err := m.evalMem(mem, str)
+	if err != nil {
+		return 0, err
+	}
+	if value < 0 || value > mem.MaxAddr {
 		return 0, fmt.Errorf("invalid address: %v", str)
 	}
-	return int(value), nil
+	return value, nil
 }
 
 // =========================================================================
@@ -791,6 +1103,12 @@ const (
 )
 
 func (m *Monitor) getPrompt() string {
+	if m.pending != nil {
+		return strings.Repeat("  ", m.depth) + "> "
+	}
+	if m.asmCPU != "" {
+		return fmt.Sprintf("%v> ", formatAddress(m.asmAddr))
+	}
 	c := ""
 	if len(m.mach.CPU) > 1 {
 		c = fmt.Sprintf(":%v%v%v", ansiLightBlue, m.sc, ansiReset)
@@ -935,3 +1253,14 @@ func (r *repeatWriter) eoln() {
 	r.buf.Reset()
 	r.prev = str
 }
+
+func acMacros(m *Monitor) func(string) []string {
+	return func(line string) []string {
+		names := make([]string, 0)
+		for k := range m.macros {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+		return names
+	}
+}
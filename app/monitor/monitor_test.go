@@ -2,10 +2,13 @@ package monitor
 
 import (
 	"bytes"
+	"io/ioutil"
 	"log"
+	"os"
 	"strings"
 	"testing"
 
+	"github.com/blackchip-org/retro-cs/config"
 	"github.com/blackchip-org/retro-cs/mock"
 	"github.com/blackchip-org/retro-cs/rcs"
 )
@@ -32,16 +35,57 @@ func newMonitorFixture() *monitorFixture {
 	return f
 }
 
+// workDir changes the working directory to a new temporary directory and
+// points the data directory at another one so that tests can check where
+// files with relative names end up. Call done to change back and remove
+// both directories.
+func workDir(t *testing.T) (dir string, dataDir string, done func()) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataDir, err = ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	prevDataDir := config.DataDir
+	config.DataDir = dataDir
+	return dir, dataDir, func() {
+		config.DataDir = prevDataDir
+		os.Chdir(wd)
+		os.RemoveAll(dir)
+		os.RemoveAll(dataDir)
+	}
+}
+
+// output returns everything written by the monitor. Machine events also
+// write to the monitor so the output is read on the machine goroutine.
+func (f *monitorFixture) output() string {
+	var out string
+	f.mon.sync(func() error {
+		out = f.out.String()
+		return nil
+	})
+	return out
+}
+
 func TestMonitor(t *testing.T) {
 	for _, test := range monitorTests {
 		t.Run(test.name, func(t *testing.T) {
 			f := newMonitorFixture()
 			go f.mon.mach.Run()
 			defer func() {
-				f.mon.mach.Command(rcs.MachQuit)
+				f.mon.mach.Call(rcs.MachQuit)
 			}()
 			f.mon.Eval(strings.Join(test.in, "\n"))
-			have := strings.TrimSpace(f.out.String())
+			have := strings.TrimSpace(f.output())
 			want := strings.TrimSpace(test.want)
 			if have != want {
 				t.Errorf("\n have: \n%v \n want: \n%v", have, want)
@@ -66,6 +110,471 @@ var monitorTests = []struct {
 + %101010
 42 $2a %10.1010
 		`,
+	}, {
+		"expressions",
+		[]string{
+			"$10+2*3",
+			"($10+2)*3",
+			"%1010.1010&$0f",
+			"1<<8|1",
+			"~0&$ff",
+			"poke $20 $34 $12",
+			"[$20].w",
+			"[$20]+1",
+			"pc+1",
+			"$10+",
+			"foo",
+			"1/0",
+		},
+		`
++ $10+2*3
+22 $16 %1.0110
++ ($10+2)*3
+54 $36 %11.0110
++ %1010.1010&$0f
+10 $a %1010
++ 1<<8|1
+257 $101 %1:0000.0001
++ ~0&$ff
+255 $ff %1111.1111
++ poke $20 $34 $12
++ [$20].w
+4660 $1234 %1.0010:0011.0100
++ [$20]+1
+53 $35 %11.0101
++ pc+1
+1 $1 %1
++ $10+
+unexpected end of expression: $10+
++ foo
+no such command: foo
++ 1/0
+division by zero: 1/0
+		`,
+	}, {
+		"symbols",
+		[]string{
+			"sym start $10",
+			"sym end start+$0f",
+			"poke start+1 $ff",
+			"peek start+1",
+			"sym",
+			"sym start",
+			"sym start off",
+			"sym start",
+			"sym a-b 1",
+		},
+		`
++ sym start $10
++ sym end start+$0f
++ poke start+1 $ff
++ peek start+1
+255 $ff %1111.1111
++ sym
+end              $001f
+start            $0010
++ sym start
+16 $10 %1.0000
++ sym start off
++ sym start
+no such symbol: start
++ sym a-b 1
+invalid symbol: a-b
+		`,
+	}, {
+		"assert",
+		[]string{
+			"poke $10 $41",
+			"assert mem $10 == $41",
+			"assert mem $10 != $41",
+			"assert reg pc == 0",
+			"assert reg q == 0",
+			"assert [$10]+1 > $41",
+			"assert [$10] <> 0",
+		},
+		`
++ poke $10 $41
++ assert mem $10 == $41
++ assert mem $10 != $41
+assertion failed: mem $10 != $41 (have $41)
++ assert reg pc == 0
++ assert reg q == 0
+no such register: q
++ assert [$10]+1 > $41
++ assert [$10] <> 0
+invalid operator: <>
+		`,
+	}, {
+		"mem find",
+		[]string{
+			"poke $10 $41 $42 $01 $02",
+			"poke $30 $41 $42",
+			"mem find bytes 0 $ff $41 $42",
+			"mem find bytes 0 $30 $41 $42",
+			"mem find word 0 $ff $4241",
+			"mem find text 0 $ff AB",
+			"mem find text 0 $ff A",
+			"mem find text 0 $ff ab",
+		},
+		`
++ poke $10 $41 $42 $01 $02
++ poke $30 $41 $42
++ mem find bytes 0 $ff $41 $42
+$0010
+$0030
++ mem find bytes 0 $30 $41 $42
+$0010
++ mem find word 0 $ff $4241
+$0010
+$0030
++ mem find text 0 $ff AB
+$0010 ascii
+$0012 az26
+$0030 ascii
++ mem find text 0 $ff A
+$0010 ascii
+$0012 az26
+$0030 ascii
++ mem find text 0 $ff ab
+		`,
+	}, {
+		"mem cheat",
+		[]string{
+			"mem cheat",
+			"mem cheat start $10 $17",
+			"poke $12 3",
+			"poke $14 5",
+			"mem cheat changed",
+			"poke $12 2",
+			"poke $14 6",
+			"mem cheat decreased",
+			"mem cheat list",
+			"mem cheat start $10 $17",
+			"mem cheat equal 6",
+			"mem cheat unchanged",
+			"poke $14 7",
+			"mem cheat increased",
+			"mem cheat off",
+			"mem cheat",
+		},
+		`
++ mem cheat
+cheat finder not started
++ mem cheat start $10 $17
+8 candidates
++ poke $12 3
++ poke $14 5
++ mem cheat changed
+2 candidates
++ poke $12 2
++ poke $14 6
++ mem cheat decreased
+1 candidates
++ mem cheat list
+$0012 $02 -> $02
++ mem cheat start $10 $17
+8 candidates
++ mem cheat equal 6
+1 candidates
++ mem cheat unchanged
+1 candidates
++ poke $14 7
++ mem cheat increased
+1 candidates
++ mem cheat off
++ mem cheat
+cheat finder not started
+		`,
+	}, {
+		"set",
+		[]string{
+			"poke $10 $41",
+			"set $x = [$10]",
+			"set $x",
+			"set $y $x+1",
+			"poke $11 $y",
+			"set",
+			"set $ab = 1",
+			"set $x off",
+			"set $x",
+		},
+		`
++ poke $10 $41
++ set $x = [$10]
++ set $x
+65 $41 %100.0001
++ set $y $x+1
++ poke $11 $y
++ set
+$x               65 $41 %100.0001
+$y               66 $42 %100.0010
++ set $ab = 1
+invalid variable: $ab
++ set $x off
++ set $x
+no such variable: $x
+		`,
+	}, {
+		"define",
+		[]string{
+			"define fill",
+			"poke $arg1 $arg2",
+			"poke $arg1+1 $argc",
+			"end",
+			"fill $20 $aa",
+			"peek $20",
+			"peek $21",
+			"macro fill",
+			"macro",
+			"macro fill off",
+			"macro fill",
+			"define step",
+			"end",
+		},
+		`
++ define fill
++ poke $arg1 $arg2
++ poke $arg1+1 $argc
++ end
++ fill $20 $aa
++ peek $20
+170 $aa %1010.1010
++ peek $21
+2 $2 %10
++ macro fill
+define fill
+    poke $arg1 $arg2
+    poke $arg1+1 $argc
+end
++ macro
+fill
++ macro fill off
++ macro fill
+no such macro: fill
++ define step
++ end
+invalid macro name: step
+		`,
+	}, {
+		"if",
+		[]string{
+			"poke $10 1",
+			"if [$10] == 1",
+			"poke $11 $aa",
+			"else",
+			"poke $11 $bb",
+			"end",
+			"peek $11",
+			"if [$10]-1",
+			"poke $11 $aa",
+			"else",
+			"poke $11 $bb",
+			"end",
+			"peek $11",
+		},
+		`
++ poke $10 1
++ if [$10] == 1
++ poke $11 $aa
++ else
++ poke $11 $bb
++ end
++ peek $11
+170 $aa %1010.1010
++ if [$10]-1
++ poke $11 $aa
++ else
++ poke $11 $bb
++ end
++ peek $11
+187 $bb %1011.1011
+		`,
+	}, {
+		"repeat",
+		[]string{
+			"set $i = 0",
+			"repeat 3",
+			"set $i = $i+1",
+			"poke $10+$i $i",
+			"end",
+			"m $10 $13",
+			"repeat 2 set $i = $i+1",
+			"set $i",
+		},
+		`
++ set $i = 0
++ repeat 3
++ set $i = $i+1
++ poke $10+$i $i
++ end
++ m $10 $13
+$0010  00 01 02 03                                       ....            
++ repeat 2 set $i = $i+1
++ set $i
+5 $5 %101
+		`,
+	}, {
+		"wait break",
+		[]string{
+			"bp 3 on",
+			"go",
+			"wait break",
+		},
+		`
++ bp 3 on
++ go
++ wait break
+
+[break]
+pc:0003 a:00 b:00 q:false z:false
+		`,
+	}, {
+		"wait frames",
+		[]string{
+			"go",
+			"wait frames 2",
+			"pause",
+			"wait frames 2",
+		},
+		`
++ go
++ wait frames 2
++ pause
++ wait frames 2
+wait failed: machine status is pause
+		`,
+	}, {
+		"cheat",
+		[]string{
+			"poke $10 1",
+			"cheat add $10 5 Infinite Lives",
+			"cheat add $11 6 if 2 bank 1",
+			"cheat add $11 6 if 2",
+			"cheat",
+			"go",
+			"wait mem $10 == 5",
+			"pause",
+			"cheat 1 off",
+			"cheat 2 remove",
+			"cheat 3 on",
+			"cheat list",
+			"cheat clear",
+			"cheat",
+		},
+		`
++ poke $10 1
++ cheat add $10 5 Infinite Lives
++ cheat add $11 6 if 2 bank 1
+invalid bank: 1
++ cheat add $11 6 if 2
++ cheat
+  1 on cpu $0010 $05 - - Infinite Lives
+  2 on cpu $0011 $06 - $02
++ go
++ wait mem $10 == 5
++ pause
++ cheat 1 off
++ cheat 2 remove
++ cheat 3 on
+no such cheat: 3
++ cheat list
+  1 off cpu $0010 $05 - - Infinite Lives
++ cheat clear
++ cheat
+		`,
+	}, {
+		"wait limit",
+		[]string{
+			"config wait-limit 50",
+			"go",
+			"wait mem 0 != 0",
+		},
+		`
++ config wait-limit 50
++ go
++ wait mem 0 != 0
+wait failed: limit of 50ms exceeded
+		`,
+	}, {
+		"assemble",
+		[]string{
+			"a $10 i01",
+			"a $11",
+			"i12 $34",
+			"i23 $5678",
+			"i99",
+			"i04",
+			"",
+			"d $10 $17",
+			"a $10",
+			"",
+		},
+		`
++ a $10 i01
+$0010:  01        i01
++ a $11
++ i12 $34
+$0011:  12 34     i12 $34
++ i23 $5678
+$0013:  23 78 56  i23 $5678
++ i99
+invalid instruction: i99
++ i04
+$0016:  04        i04
++ 
++ d $10 $17
+$0010:  01        i01
+$0011:  12 34     i12 $34
+$0013:  23 78 56  i23 $5678
+$0016:  04        i04
+$0017:  00        i00
++ a $10
++ 
+		`,
+	}, {
+		"backtrace",
+		[]string{
+			"poke 0 $2f $10 $00",
+			"poke $10 $00 $2f $20 $00",
+			"symbol main 0",
+			"symbol sub $10",
+			"bt track on",
+			"s", "s", "s",
+			"bt",
+		},
+		`
++ poke 0 $2f $10 $00
++ poke $10 $00 $2f $20 $00
++ symbol main 0
++ symbol sub $10
++ bt track on
++ s
+$0010:  00        i00
++ s
+$0011:  2f 20 00  i2f $0020
++ s
+$0020:  00        i00
++ bt
+#0   $0020 sub+16           ret $0014 sub+4            sp $fefc
+#1   $0010 sub              ret $0003 main+3           sp $fefe
+		`,
+	}, {
+		"backtrace scanned",
+		[]string{
+			"poke 0 $2f $10 $00",
+			"poke $10 $00",
+			"s",
+			"bt",
+		},
+		`
++ poke 0 $2f $10 $00
++ poke $10 $00
++ s
+$0010:  00        i00
++ bt
+(scanned)
+#0   $0010                  ret $0003                  sp $fefe
+		`,
 	}, {
 		"break",
 		[]string{
@@ -160,6 +669,81 @@ $0001:  19 ab     i19 $ab
 $0011:  19 ab     i19 $ab
 $0013:  29 cd ab  i29 $abcd
 		`,
+	}, {
+		"note",
+		[]string{
+			"poke $10 $09 $41 $42 $43 $01 $02 $03 $04 $05",
+			"poke $1a $34 $12 $78 $56 $00",
+			"note $10 entry point",
+			"note data $11 $13 text",
+			"note data $14 $19 bytes",
+			"note $16 counter",
+			"note data $1a $1b words",
+			"note data $1c $1e pointers",
+			"d $10 $1e",
+			"m $10 $1f",
+			"note",
+			"note $16",
+			"note $16 off",
+			"note $16",
+			"note data $10 $1f code",
+			"note data $10 $1f floats",
+			"note none",
+			"note",
+		},
+		`
++ poke $10 $09 $41 $42 $43 $01 $02 $03 $04 $05
++ poke $1a $34 $12 $78 $56 $00
++ note $10 entry point
++ note data $11 $13 text
++ note data $14 $19 bytes
++ note $16 counter
++ note data $1a $1b words
++ note data $1c $1e pointers
++ d $10 $1e
+$0010:  09        i09  ; entry point
+$0011:  41 42 43  .text "ABC"
+$0014:  01 02     .byte $01,$02
+$0016:  03 04 05 00  .byte $03,$04,$05,$00  ; counter
+$001a:  34 12     .word $1234
+$001c:  78 56     .addr $5678
+$001e:  00        .byte $00
++ m $10 $1f
+$0010  09 41 42 43 01 02 03 04  05 00 34 12 78 56 00 00  .ABC......4.xV..  ; $0010 entry point; $0016 counter
++ note
+$0010 entry point
+$0011-$0013 text
+$0014-$0019 bytes
+$0016 counter
+$001a-$001b words
+$001c-$001e pointers
++ note $16
+counter
++ note $16 off
++ note $16
+no note at $0016
++ note data $10 $1f code
++ note data $10 $1f floats
+invalid data type: floats
++ note none
++ note
+		`,
+	}, {
+		"note operand",
+		[]string{
+			"poke $10 $29 $cd $ab $09",
+			"note $10 load",
+			"note $12 high byte",
+			"d $10 $13",
+		},
+		`
++ poke $10 $29 $cd $ab $09
++ note $10 load
++ note $12 high byte
++ d $10 $13
+$0010:  29 cd ab  i29 $abcd  ; load; $0012 high byte
+$0013:  09        i09
+		`,
 	}, {
 		"go",
 		[]string{"bp $10 on", "g", "sleep 100"},
@@ -268,6 +852,52 @@ $0000  00 00 00 00 ff ff ff ff  ff ff ff ff 00 00 00 00  ................
 + n
 $0000:  00        i00
 		`,
+	}, {
+		"out",
+		[]string{
+			"poke 0 $2f $10 $00",
+			"poke $10 $00 $0f",
+			"s",
+			"out",
+			"sleep 100",
+		},
+		`
++ poke 0 $2f $10 $00
++ poke $10 $00 $0f
++ s
+$0010:  00        i00
++ out
++ sleep 100
+
+[break]
+pc:0003 a:00 b:00 q:false z:false
+		`,
+	}, {
+		"over",
+		[]string{"over", "over"},
+		`
++ over
+$0001:  00        i00
++ over
+$0002:  00        i00
+		`,
+	}, {
+		"over call",
+		[]string{
+			"poke 0 $2f $10 $00",
+			"poke $10 $00 $0f",
+			"over",
+			"sleep 100",
+		},
+		`
++ poke 0 $2f $10 $00
++ poke $10 $00 $0f
++ over
++ sleep 100
+
+[break]
+pc:0003 a:00 b:00 q:false z:false
+		`,
 	}, {
 		"peek",
 		[]string{"poke $1234 $ab", "peek $1234"},
@@ -320,6 +950,16 @@ pc:0001 a:00 b:00 q:false z:false
 + trace
 + go
 		`,
+	}, {
+		"until",
+		[]string{"until $5", "sleep 100"},
+		`
++ until $5
++ sleep 100
+
+[break]
+pc:0005 a:00 b:00 q:false z:false
+		`,
 	}, {
 		"watch",
 		[]string{
@@ -382,11 +1022,11 @@ sleep 1000
 	`
 	go f.mon.mach.Run()
 	defer func() {
-		f.mon.mach.Command(rcs.MachQuit)
+		f.mon.mach.Call(rcs.MachQuit)
 	}()
 	f.mon.Eval(cmds)
 	f.mon.Close()
-	have := strings.TrimSpace(f.out.String())
+	have := strings.TrimSpace(f.output())
 	want := strings.TrimSpace(`
 + bp $10 on
 + g
@@ -461,7 +1101,7 @@ func TestDump(t *testing.T) {
 			for i, value := range test.data() {
 				m.Write(test.start+i, uint8(value))
 			}
-			have := dump(m, test.showFrom, test.showTo, rcs.ASCIIDecoder, "")
+			have := dump(m, test.showFrom, test.showTo, rcs.ASCIIDecoder, "", nil)
 			have = strings.TrimSpace(have)
 			if have != test.want {
 				t.Errorf("\n have: \n%v \n want: \n%v \n", have, test.want)
@@ -508,6 +1148,80 @@ func TestRepeatWriter(t *testing.T) {
 	}
 }
 
+func TestEval(t *testing.T) {
+	mem := rcs.NewMemory(1, 0x100)
+	mem.MapRAM(0, make([]uint8, 0x100, 0x100))
+	mem.WriteLE(0xfc, 0x1234)
+	mem.Write(0x34, 0x56)
+	lookup := func(name string) (int, bool) {
+		switch name {
+		case "a":
+			return 0x0a, true
+		case "hl":
+			return 0x30, true
+		}
+		return 0, false
+	}
+	tests := []struct {
+		in   string
+		want int
+		err  string
+	}{
+		{"42", 42, ""},
+		{"$2a", 42, ""},
+		{"0x2a", 42, ""},
+		{"%10.1010", 42, ""},
+		{"0b101010", 42, ""},
+		{"-1", -1, ""},
+		{"--1", 1, ""},
+		{"2+3*4", 14, ""},
+		{"(2+3)*4", 20, ""},
+		{"10-2-3", 5, ""},
+		{"17%5", 2, ""},
+		{"17/5", 3, ""},
+		{"1<<4", 16, ""},
+		{"$f0>>4", 0x0f, ""},
+		{"$f0&$3c", 0x30, ""},
+		{"$f0|$0f", 0xff, ""},
+		{"$ff^$0f", 0xf0, ""},
+		{"1|2&3", 3, ""},
+		{"a", 0x0a, ""},
+		{"hl+2", 0x32, ""},
+		{".", 0x80, ""},
+		{".+a", 0x8a, ""},
+		{"[$fc]", 0x34, ""},
+		{"[$fc].b", 0x34, ""},
+		{"[$fc].w", 0x1234, ""},
+		{"[[$fc]]", 0x56, ""},
+		{"[$ff].w", 0, "invalid address: $0100"},
+		{"[$100]", 0, "invalid address: $0100"},
+		{"x", 0, "unknown name: x"},
+		{"(1", 0, "missing ')' in (1"},
+		{"[1", 0, "missing ']' in [1"},
+		{"1)", 0, "unexpected ')' in 1)"},
+		{"$zz", 0, "invalid value: $zz"},
+		{"", 0, "invalid value: "},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			e := &evaluator{mem: mem, lookup: lookup, dot: 0x80}
+			have, err := e.eval(test.in)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Errorf("\n have err: %v \n want err: %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != test.want {
+				t.Errorf("\n have: %v \n want: %v", have, test.want)
+			}
+		})
+	}
+}
+
 func TestFormatBits(t *testing.T) {
 	tests := []struct {
 		in  int
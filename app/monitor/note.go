@@ -0,0 +1,140 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// annotations returns the notes and data regions for memory. Processors
+// that share memory also share annotations.
+func (m *Monitor) annotations(mem *rcs.Memory) *rcs.Annotations {
+	a, ok := m.notes[mem]
+	if !ok {
+		a = rcs.NewAnnotations()
+		m.notes[mem] = a
+	}
+	return a
+}
+
+// newDisassembler returns a disassembler for the processor that shows
+// annotations and decodes text with the selected encoding. Nil is
+// returned if the processor cannot be disassembled.
+func (m *Monitor) newDisassembler(cpu rcs.CPU) *rcs.Disassembler {
+	cpud, ok := cpu.(rcs.CPUDisassembler)
+	if !ok {
+		return nil
+	}
+	dasm := cpud.NewDisassembler()
+	dasm.Annotations = m.annotations(cpu.Memory())
+	dasm.Decoder = func(code uint8) (rune, bool) {
+		decode, ok := m.mach.CharDecoders[m.encoding]
+		if !ok {
+			decode = rcs.ASCIIDecoder
+		}
+		return decode(code)
+	}
+	return dasm
+}
+
+func (m *Monitor) cmdNote(args []string) error {
+	cpu, ok := m.cpu[m.sc]
+	if !ok {
+		return fmt.Errorf("no cpu selected")
+	}
+	mem := cpu.Memory()
+	a := m.annotations(mem)
+	if len(args) == 0 || (args[0] == "list" && len(args) == 1) {
+		m.out.Print(strings.Join(noteList(a), "\n"))
+		return nil
+	}
+	if args[0] == "none" && len(args) == 1 {
+		a.Notes = make(map[int]string)
+		a.Regions = nil
+		return nil
+	}
+	if args[0] == "data" {
+		return m.cmdNoteData(mem, a, args[1:])
+	}
+	addr, err := m.parseAddress(mem, args[0])
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		note, ok := a.Notes[addr]
+		if !ok {
+			return fmt.Errorf("no note at %v", formatAddress(addr))
+		}
+		m.out.Print(note)
+		return nil
+	}
+	if len(args) == 2 && args[1] == "off" {
+		delete(a.Notes, addr)
+		return nil
+	}
+	a.Notes[addr] = strings.Join(args[1:], " ")
+	return nil
+}
+
+func (m *Monitor) cmdNoteData(mem *rcs.Memory, a *rcs.Annotations, args []string) error {
+	if err := checkLen(args, 3, 3); err != nil {
+		return err
+	}
+	start, err := m.parseAddress(mem, args[0])
+	if err != nil {
+		return err
+	}
+	end, err := m.parseAddress(mem, args[1])
+	if err != nil {
+		return err
+	}
+	if end < start {
+		return fmt.Errorf("invalid range: %v", formatRange(start, end))
+	}
+	kind, ok := rcs.ParseDataKind(args[2])
+	if !ok {
+		return fmt.Errorf("invalid data type: %v", args[2])
+	}
+	a.Mark(start, end, kind)
+	return nil
+}
+
+// noteList returns the data regions and notes sorted by address.
+func noteList(a *rcs.Annotations) []string {
+	type entry struct {
+		addr int
+		text string
+	}
+	var entries []entry
+	for _, r := range a.Regions {
+		entries = append(entries, entry{r.Start, fmt.Sprintf("%v %v", formatRange(r.Start, r.End), r.Kind)})
+	}
+	for addr, note := range a.Notes {
+		entries = append(entries, entry{addr, fmt.Sprintf("%v %v", formatAddress(addr), note)})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].addr < entries[j].addr
+	})
+	list := make([]string, len(entries))
+	for i, e := range entries {
+		list[i] = e.text
+	}
+	return list
+}
+
+// rowNotes returns the notes for the row of a memory dump that starts at
+// the given address.
+func rowNotes(notes map[int]string, row int, start int, end int) string {
+	var list []string
+	for addr := row; addr < row+0x10; addr++ {
+		if note, ok := notes[addr]; ok && addr >= start && addr <= end {
+			list = append(list, fmt.Sprintf("%v %v", formatAddress(addr), note))
+		}
+	}
+	if len(list) == 0 {
+		return ""
+	}
+	return "  ; " + strings.Join(list, "; ")
+}
@@ -0,0 +1,213 @@
+package monitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/config"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+/*
+A project saves the state of the monitor that is built up while debugging:
+breakpoints, memory watches, symbols, notes and data regions, the selected
+CPU and encoding, and configuration values. Projects are stored for each
+system in the "projects" directory within the user directory and the
+default project is loaded when the monitor starts. With
+"config project-autosave on", the default project is saved when the
+monitor quits or is closed.
+
+The project file is a list of monitor commands that restore this state
+and may be edited by hand.
+*/
+
+const defaultProject = "default"
+
+func projectFile(name string) string {
+	if config.UserDir == "" || config.System == "" {
+		return ""
+	}
+	return filepath.Join(config.UserDir, "projects", config.System, name)
+}
+
+func (m *Monitor) cmdProject(args []string) error {
+	if err := checkLen(args, 1, 2); err != nil {
+		return err
+	}
+	name := defaultProject
+	if len(args) > 1 {
+		name = args[1]
+		if !nameRegex.MatchString(name) {
+			return fmt.Errorf("invalid project name: %v", name)
+		}
+	}
+	switch args[0] {
+	case "load":
+		return m.loadProject(name)
+	case "save":
+		return m.saveProject(name)
+	}
+	return fmt.Errorf("invalid argument: %v", args[0])
+}
+
+// LoadProject loads the default project, if it exists.
+func (m *Monitor) LoadProject() error {
+	filename := projectFile(defaultProject)
+	if filename == "" {
+		return nil
+	}
+	if _, err := os.Stat(filename); os.IsNotExist(err) {
+		return nil
+	}
+	return m.sync(func() error {
+		return m.loadProject(defaultProject)
+	})
+}
+
+// loadProject runs the commands in the project file. It must be called on
+// the goroutine of the machine.
+func (m *Monitor) loadProject(name string) error {
+	filename := projectFile(name)
+	if filename == "" {
+		return fmt.Errorf("no user directory")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.Split(string(data), "\n") {
+		args := splitArgs(line)
+		if len(args) == 0 {
+			continue
+		}
+		if err := m.dispatch(args); err != nil {
+			return fmt.Errorf("%v:%v: %v", filename, i+1, err)
+		}
+	}
+	return nil
+}
+
+func (m *Monitor) saveProject(name string) error {
+	filename := projectFile(name)
+	if filename == "" {
+		return fmt.Errorf("no user directory")
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return fmt.Errorf("unable to save project: %v", err)
+	}
+	if err := ioutil.WriteFile(filename, []byte(m.project()), 0644); err != nil {
+		return fmt.Errorf("unable to save project: %v", err)
+	}
+	return nil
+}
+
+// autosaveProject saves the default project once if autosave is on. The
+// state of the monitor is read on the goroutine of the machine, so call this
+// before asking the machine to quit. If the machine has already stopped,
+// nothing else changes the state and it is read on this goroutine instead.
+func (m *Monitor) autosaveProject() error {
+	save := func() error {
+		if !m.autosave {
+			return nil
+		}
+		err := m.saveProject(defaultProject)
+		m.autosave = false
+		return err
+	}
+	err := m.sync(save)
+	if err == rcs.ErrNotRunning {
+		return save()
+	}
+	return err
+}
+
+// project returns the commands that restore the state of the monitor.
+func (m *Monitor) project() string {
+	var out []string
+	add := func(format string, args ...interface{}) {
+		out = append(out, fmt.Sprintf(format, args...))
+	}
+	add("config lines-memory %v", m.memLines)
+	add("config lines-disassembly %v", m.dasmLines)
+	add("config wait-limit %v", m.waitLimit)
+	add("config project-autosave %v", onOff(m.autosave))
+	add("encoding %v", m.encoding)
+
+	add("symbol none")
+	names := make([]string, 0, len(m.symbols))
+	for name := range m.symbols {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("symbol %v %v", name, formatAddress(m.symbols[name]))
+	}
+
+	for _, comp := range m.mach.Comps {
+		switch mod := m.mods[comp.Name].(type) {
+		case *modCPU:
+			add("%v breakpoint none", comp.Name)
+			for _, addr := range sortedKeys(mod.brkpts) {
+				add("%v breakpoint %v on", comp.Name, formatAddress(addr))
+			}
+		case *modMemory:
+			add("%v watch none", comp.Name)
+			addrs := make([]int, 0, len(mod.watches))
+			for addr := range mod.watches {
+				addrs = append(addrs, addr)
+			}
+			sort.Ints(addrs)
+			for _, addr := range addrs {
+				add("%v watch %v %v", comp.Name, formatAddress(addr), mod.watches[addr])
+			}
+		}
+	}
+	// the note command works on the memory of the selected CPU, so select
+	// the first CPU that uses each memory before listing its notes
+	seen := make(map[*rcs.Memory]bool)
+	for _, comp := range m.mach.Comps {
+		cpu, ok := comp.C.(rcs.CPU)
+		if !ok || seen[cpu.Memory()] {
+			continue
+		}
+		seen[cpu.Memory()] = true
+		a := m.annotations(cpu.Memory())
+		add("%v select", comp.Name)
+		add("note none")
+		for _, r := range a.Regions {
+			add("note data %v %v %v", formatAddress(r.Start), formatAddress(r.End), r.Kind)
+		}
+		addrs := make([]int, 0, len(a.Notes))
+		for addr := range a.Notes {
+			addrs = append(addrs, addr)
+		}
+		sort.Ints(addrs)
+		for _, addr := range addrs {
+			add("note %v %v", formatAddress(addr), a.Notes[addr])
+		}
+	}
+	if m.sc != "" {
+		add("%v select", m.sc)
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+func sortedKeys(set map[int]struct{}) []int {
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func onOff(v bool) string {
+	if v {
+		return "on"
+	}
+	return "off"
+}
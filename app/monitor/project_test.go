@@ -0,0 +1,132 @@
+package monitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/config"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestProject(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	userDir, system := config.UserDir, config.System
+	config.UserDir, config.System = dir, "mock"
+	defer func() {
+		config.UserDir, config.System = userDir, system
+	}()
+
+	want := strings.TrimSpace(`
+config lines-memory 4
+config lines-disassembly 0
+config wait-limit 0
+config project-autosave on
+encoding az26
+symbol none
+symbol start $1000
+mem watch none
+mem watch $0010 rw
+cpu breakpoint none
+cpu breakpoint $0002 on
+cpu breakpoint $1000 on
+cpu select
+note none
+note data $2000 $2003 text
+note $1000 start here
+cpu select
+	`) + "\n"
+
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	f.mon.Eval(strings.Join([]string{
+		"config lines-memory 4",
+		"config project-autosave on",
+		"encoding az26",
+		"symbol start $1000",
+		"watch $10 rw",
+		"bp start on",
+		"bp 2 on",
+		"note data $2000 $2003 text",
+		"note start start here",
+		"project save",
+	}, "\n"))
+	f.mon.mach.Call(rcs.MachQuit)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "projects", "mock", "default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Fatalf("\n have: \n%v \n want: \n%v", string(data), want)
+	}
+
+	f = newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	if err := f.mon.LoadProject(); err != nil {
+		t.Fatal(err)
+	}
+	var have string
+	f.mon.sync(func() error {
+		have = f.mon.project()
+		return nil
+	})
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+}
+
+// Run with -race to check that autosave, used when the monitor quits or is
+// closed, does not read the state of the monitor while the machine is
+// changing it.
+func TestProjectAutosave(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	userDir, system := config.UserDir, config.System
+	config.UserDir, config.System = dir, "mock"
+	defer func() {
+		config.UserDir, config.System = userDir, system
+	}()
+
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval("config project-autosave on")
+	started, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		for i := 0; i < 1000; i++ {
+			f.mon.Eval(fmt.Sprintf("bp %v on", i))
+			if i == 0 {
+				close(started)
+			}
+		}
+		close(done)
+	}()
+	<-started
+	if err := f.mon.autosaveProject(); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "projects", "mock", "default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "cpu breakpoint none") {
+		t.Errorf("breakpoints not saved: \n%v", string(data))
+	}
+}
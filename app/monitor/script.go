@@ -0,0 +1,254 @@
+package monitor
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+/*
+A script is a file of monitor commands that is run without user
+interaction, such as with the -script command line option. Each command is
+echoed before it is run and the script stops at the first command that
+fails.
+
+Scripts use the wait command to let the machine run until something
+happens:
+
+	wait break                 until the machine stops
+	wait frames 300            until 300 frames have been drawn
+	wait mem $c6 != 0          until the value at $c6 is not zero
+	wait reg a == $41          until register A is $41
+	wait [$fb].w == $1000      until an expression is true
+
+and the assert command to check the state of the machine:
+
+	assert mem $0400 == $08
+	assert reg pc == $e5cd
+	assert [$c6]+1 > 1
+
+The comparison operators are ==, !=, <, <=, > and >=. A wait fails if the
+machine stops before the condition is met or if it takes longer than the
+number of milliseconds in "config wait-limit", when that is not zero. A
+script that starts without a limit uses scriptWaitLimit so that a wait
+that is never met fails instead of running forever.
+*/
+
+// waitPoll is the time between checks while waiting
+const waitPoll = 5 * time.Millisecond
+
+// scriptWaitLimit is the wait limit, in milliseconds, used while running a
+// script when "config wait-limit" is zero.
+var scriptWaitLimit = 60000
+
+// RunScript runs the monitor commands in the named file. Running stops at
+// the first command that returns an error, such as a failed assert, and
+// that error is returned with the line number. The "quit" command ends the
+// script early without an error.
+func (m *Monitor) RunScript(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	m.sync(func() error {
+		if m.waitLimit == 0 {
+			m.waitLimit = scriptWaitLimit
+		}
+		return nil
+	})
+	defer m.sync(func() error {
+		if m.waitLimit == scriptWaitLimit {
+			m.waitLimit = 0
+		}
+		return nil
+	})
+	for i, line := range strings.Split(string(data), "\n") {
+		args := splitArgs(line)
+		if len(args) == 0 && m.asmCPU == "" {
+			continue
+		}
+		m.out.Printf("+ %v\n", strings.TrimSpace(line))
+		if len(args) > 0 && (args[0] == "q" || args[0] == "quit") {
+			return nil
+		}
+		if err := m.feed(line); err != nil {
+			return fmt.Errorf("%v:%v: %v", filename, i+1, err)
+		}
+	}
+	if m.pending != nil {
+		m.pending = nil
+		return fmt.Errorf("%v: missing end", filename)
+	}
+	return nil
+}
+
+func (m *Monitor) cmdAssert(args []string) error {
+	cond, err := m.parseCondition(args)
+	if err != nil {
+		return err
+	}
+	ok, have, err := cond()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("assertion failed: %v (have %v)", strings.Join(args, " "), have)
+	}
+	return nil
+}
+
+// cmdWait runs on the goroutine of the monitor and checks the condition on
+// the goroutine of the machine between each jiffy.
+func (m *Monitor) cmdWait(args []string) error {
+	if err := checkLen(args, 1, maxArgs); err != nil {
+		return err
+	}
+	var done func() (bool, error)
+	switch args[0] {
+	case "break":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		done = func() (bool, error) {
+			return m.mach.Status != rcs.Run, nil
+		}
+	case "frames":
+		if err := checkLen(args[1:], 1, 1); err != nil {
+			return err
+		}
+		var end int
+		err := m.sync(func() error {
+			n, err := m.parseValue(args[1])
+			end = m.mach.Frames + n
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		done = func() (bool, error) {
+			return m.mach.Frames >= end, nil
+		}
+	default:
+		cond, err := m.parseCondition(args)
+		if err != nil {
+			return err
+		}
+		done = func() (bool, error) {
+			ok, _, err := cond()
+			return ok, err
+		}
+	}
+
+	start := time.Now()
+	for {
+		var ok bool
+		var status rcs.Status
+		var limit time.Duration
+		err := m.sync(func() (err error) {
+			ok, err = done()
+			status = m.mach.Status
+			limit = time.Duration(m.waitLimit) * time.Millisecond
+			return
+		})
+		if err != nil || ok {
+			return err
+		}
+		if status != rcs.Run {
+			return fmt.Errorf("wait failed: machine status is %v", status)
+		}
+		if limit > 0 && time.Since(start) > limit {
+			return fmt.Errorf("wait failed: limit of %v exceeded", limit)
+		}
+		time.Sleep(waitPoll)
+	}
+}
+
+// parseCondition returns a function that compares two values. The
+// function must be called on the goroutine of the machine and returns the
+// result of the comparison and the formatted value on the left side. The
+// arguments are in one of the following forms:
+//
+//	mem address op value
+//	reg name op value
+//	expr op expr
+func (m *Monitor) parseCondition(args []string) (func() (bool, string, error), error) {
+	if err := checkLen(args, 3, 4); err != nil {
+		return nil, err
+	}
+	var lhs func() (int, string, error)
+	arg0, arg1 := args[0], args[1]
+	switch {
+	case args[0] == "mem" && len(args) == 4:
+		lhs = func() (int, string, error) {
+			cpu, ok := m.cpu[m.sc]
+			if !ok {
+				return 0, "", fmt.Errorf("no memory to read")
+			}
+			addr, err := m.parseAddress(cpu.Memory(), arg1)
+			if err != nil {
+				return 0, "", err
+			}
+			v := int(cpu.Memory().Peek(addr))
+			return v, rcs.X8(uint8(v)), nil
+		}
+		args = args[2:]
+	case args[0] == "reg" && len(args) == 4:
+		lhs = func() (int, string, error) {
+			mod, ok := m.mods[m.sc].(valuer)
+			if !ok {
+				return 0, "", fmt.Errorf("no registers to read")
+			}
+			v, ok := mod.Value(arg1)
+			if !ok {
+				return 0, "", fmt.Errorf("no such register: %v", arg1)
+			}
+			return v, rcs.X(v), nil
+		}
+		args = args[2:]
+	case len(args) == 3:
+		lhs = func() (int, string, error) {
+			v, err := m.parseValue(arg0)
+			return v, rcs.X(v), err
+		}
+		args = args[1:]
+	default:
+		return nil, fmt.Errorf("invalid condition: %v", strings.Join(args, " "))
+	}
+	op, rhsExpr := args[0], args[1]
+	if _, err := compare(op, 0, 0); err != nil {
+		return nil, err
+	}
+	return func() (bool, string, error) {
+		a, have, err := lhs()
+		if err != nil {
+			return false, "", err
+		}
+		b, err := m.parseValue(rhsExpr)
+		if err != nil {
+			return false, "", err
+		}
+		ok, err := compare(op, a, b)
+		return ok, have, err
+	}, nil
+}
+
+func compare(op string, a int, b int) (bool, error) {
+	switch op {
+	case "==":
+		return a == b, nil
+	case "!=":
+		return a != b, nil
+	case "<":
+		return a < b, nil
+	case "<=":
+		return a <= b, nil
+	case ">":
+		return a > b, nil
+	case ">=":
+		return a >= b, nil
+	}
+	return false, fmt.Errorf("invalid operator: %v", op)
+}
@@ -0,0 +1,89 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestRunScript(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		err    string
+	}{
+		{"pass", "poke $10 $41\n\nassert mem $10 == $41\n", ""},
+		{"fail", "poke $10 $41\nassert mem $10 == 0\nassert mem $10 == $41\n",
+			"2: assertion failed: mem $10 == 0 (have $41)"},
+		{"quit", "quit\nassert mem $10 == 1\n", ""},
+		{"error", "foo\n", "1: no such command: foo"},
+	}
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			f := newMonitorFixture()
+			go f.mon.mach.Run()
+			defer func() {
+				f.mon.mach.Call(rcs.MachQuit)
+			}()
+			filename := filepath.Join(dir, test.name+".mon")
+			if err := ioutil.WriteFile(filename, []byte(test.script), 0644); err != nil {
+				t.Fatal(err)
+			}
+			err := f.mon.RunScript(filename)
+			switch {
+			case test.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.err != "" && err == nil:
+				t.Errorf("expected error")
+			case test.err != "" && !strings.HasSuffix(err.Error(), test.err):
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestRunScriptWaitLimit(t *testing.T) {
+	limit := scriptWaitLimit
+	scriptWaitLimit = 50
+	defer func() {
+		scriptWaitLimit = limit
+	}()
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	filename := filepath.Join(dir, "wait.mon")
+	script := "go\nwait mem 0 == $ff\n"
+	if err := ioutil.WriteFile(filename, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f.mon.Eval("config wait-limit 0")
+	err = f.mon.RunScript(filename)
+	want := "2: wait failed: limit of 50ms exceeded"
+	if err == nil || !strings.HasSuffix(err.Error(), want) {
+		t.Errorf("\n have: %v \n want: %v", err, want)
+	}
+	var have int
+	f.mon.sync(func() error {
+		have = f.mon.waitLimit
+		return nil
+	})
+	if have != 0 {
+		t.Errorf("wait limit not restored: %v", have)
+	}
+}
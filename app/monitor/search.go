@@ -0,0 +1,265 @@
+package monitor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// maxMatches is the number of addresses shown by a search or by the cheat
+// finder before the rest are counted instead.
+const maxMatches = 64
+
+// cheatFinder keeps the candidate addresses and the value seen at each
+// one when the list was last narrowed.
+type cheatFinder struct {
+	addrs  []int
+	values []uint8
+}
+
+func (m *modMemory) cmdFind(args []string) error {
+	if err := checkLen(args, 4, maxArgs); err != nil {
+		return err
+	}
+	start, err := m.mon.parseAddress(m.mem, args[1])
+	if err != nil {
+		return err
+	}
+	end, err := m.mon.parseAddress(m.mem, args[2])
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "bytes", "b":
+		pattern := make([]uint8, 0, len(args)-3)
+		for _, arg := range args[3:] {
+			v, err := m.mon.parseValue8(arg)
+			if err != nil {
+				return err
+			}
+			pattern = append(pattern, v)
+		}
+		m.printMatches(search(m.mem, start, end, pattern), nil)
+		return nil
+	case "word", "w":
+		if err := checkLen(args, 4, 4); err != nil {
+			return err
+		}
+		v, err := m.mon.parseValue16(args[3])
+		if err != nil {
+			return err
+		}
+		m.printMatches(search(m.mem, start, end, []uint8{uint8(v), uint8(v >> 8)}), nil)
+		return nil
+	case "text", "t":
+		return m.findText(start, end, strings.Join(args[3:], " "))
+	}
+	return fmt.Errorf("invalid argument: %v", args[0])
+}
+
+// findText searches for the text using every character encoding of the
+// machine. Encodings that cannot represent the text are skipped.
+func (m *modMemory) findText(start int, end int, text string) error {
+	names := make([]string, 0, len(m.mon.mach.CharDecoders))
+	for name := range m.mon.mach.CharDecoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var matches []int
+	labels := make(map[int]string)
+	encoded := false
+	for _, name := range names {
+		pattern, ok := encodeText(m.mon.mach.CharDecoders[name], text)
+		if !ok {
+			continue
+		}
+		encoded = true
+		for _, addr := range search(m.mem, start, end, pattern) {
+			if _, seen := labels[addr]; !seen {
+				matches = append(matches, addr)
+				labels[addr] = name
+			} else {
+				labels[addr] += " " + name
+			}
+		}
+	}
+	if !encoded {
+		return fmt.Errorf("no encoding for text: %v", text)
+	}
+	sort.Ints(matches)
+	m.printMatches(matches, labels)
+	return nil
+}
+
+func (m *modMemory) printMatches(matches []int, labels map[int]string) {
+	for i, addr := range matches {
+		if i == maxMatches {
+			m.mon.out.Printf("(%v more)", len(matches)-maxMatches)
+			break
+		}
+		line := fmt.Sprintf("%v%v", m.prefix(), formatAddress(addr))
+		if label, ok := labels[addr]; ok {
+			line += " " + label
+		}
+		m.mon.out.Println(line)
+	}
+}
+
+func (m *modMemory) cmdCheat(args []string) error {
+	if len(args) == 0 {
+		return m.cheatList()
+	}
+	switch args[0] {
+	case "start":
+		return m.cheatStart(args[1:])
+	case "list":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		return m.cheatList()
+	case "off":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		m.cheat = nil
+		return nil
+	case "changed", "unchanged", "increased", "decreased":
+		if err := checkLen(args[1:], 0, 0); err != nil {
+			return err
+		}
+		return m.cheatNarrow(cheatTests[args[0]], 0)
+	case "equal", "eq":
+		if err := checkLen(args[1:], 1, 1); err != nil {
+			return err
+		}
+		v, err := m.mon.parseValue8(args[1])
+		if err != nil {
+			return err
+		}
+		return m.cheatNarrow(cheatTests["equal"], v)
+	}
+	return fmt.Errorf("invalid argument: %v", args[0])
+}
+
+var cheatTests = map[string]func(prev uint8, now uint8, v uint8) bool{
+	"changed":   func(prev, now, v uint8) bool { return now != prev },
+	"unchanged": func(prev, now, v uint8) bool { return now == prev },
+	"increased": func(prev, now, v uint8) bool { return now > prev },
+	"decreased": func(prev, now, v uint8) bool { return now < prev },
+	"equal":     func(prev, now, v uint8) bool { return now == v },
+}
+
+func (m *modMemory) cheatStart(args []string) error {
+	if err := checkLen(args, 0, 2); err != nil {
+		return err
+	}
+	start, end := 0, m.mem.MaxAddr
+	if len(args) > 0 {
+		addr, err := m.mon.parseAddress(m.mem, args[0])
+		if err != nil {
+			return err
+		}
+		start = addr
+	}
+	if len(args) > 1 {
+		addr, err := m.mon.parseAddress(m.mem, args[1])
+		if err != nil {
+			return err
+		}
+		end = addr
+	}
+	if end < start {
+		return fmt.Errorf("invalid range: %v", formatRange(start, end))
+	}
+	c := &cheatFinder{
+		addrs:  make([]int, 0, end-start+1),
+		values: make([]uint8, 0, end-start+1),
+	}
+	for addr := start; addr <= end; addr++ {
+		c.addrs = append(c.addrs, addr)
+		c.values = append(c.values, m.mem.Peek(addr))
+	}
+	m.cheat = c
+	m.mon.out.Printf("%v candidates", len(c.addrs))
+	return nil
+}
+
+// cheatNarrow keeps the candidates that pass the test and remembers the
+// current values for the next comparison.
+func (m *modMemory) cheatNarrow(test func(uint8, uint8, uint8) bool, v uint8) error {
+	if m.cheat == nil {
+		return fmt.Errorf("cheat finder not started")
+	}
+	c := m.cheat
+	addrs, values := c.addrs[:0], c.values[:0]
+	for i, addr := range c.addrs {
+		now := m.mem.Peek(addr)
+		if test(c.values[i], now, v) {
+			addrs = append(addrs, addr)
+			values = append(values, now)
+		}
+	}
+	c.addrs, c.values = addrs, values
+	m.mon.out.Printf("%v candidates", len(c.addrs))
+	return nil
+}
+
+func (m *modMemory) cheatList() error {
+	if m.cheat == nil {
+		return fmt.Errorf("cheat finder not started")
+	}
+	c := m.cheat
+	for i, addr := range c.addrs {
+		if i == maxMatches {
+			m.mon.out.Printf("(%v more)", len(c.addrs)-maxMatches)
+			break
+		}
+		m.mon.out.Printf("%v%v %v -> %v", m.prefix(), formatAddress(addr),
+			rcs.X8(c.values[i]), rcs.X8(m.mem.Peek(addr)))
+	}
+	return nil
+}
+
+// search returns the addresses from start to end where the pattern is
+// found. A match may not extend past the end address.
+func search(mem *rcs.Memory, start int, end int, pattern []uint8) []int {
+	var matches []int
+	if len(pattern) == 0 {
+		return matches
+	}
+	for addr := start; addr+len(pattern)-1 <= end; addr++ {
+		found := true
+		for i, v := range pattern {
+			if mem.Peek(addr+i) != v {
+				found = false
+				break
+			}
+		}
+		if found {
+			matches = append(matches, addr)
+		}
+	}
+	return matches
+}
+
+// encodeText returns the codes that the decoder turns into the text. The
+// lowest code is used when more than one decodes to the same character.
+func encodeText(decode rcs.CharDecoder, text string) ([]uint8, bool) {
+	codes := make(map[rune]uint8)
+	for i := 0xff; i >= 0; i-- {
+		if ch, printable := decode(uint8(i)); printable {
+			codes[ch] = uint8(i)
+		}
+	}
+	var pattern []uint8
+	for _, ch := range text {
+		code, ok := codes[ch]
+		if !ok {
+			return nil, false
+		}
+		pattern = append(pattern, code)
+	}
+	return pattern, len(pattern) > 0
+}
@@ -0,0 +1,84 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// cmdSource writes source code for the range of memory that can be
+// assembled back to the same bytes. Code is found by following the flow
+// of execution from the entry points given and those of the processor.
+// Symbols are used for labels and the notes and data regions are used as
+// well. Jump targets in the code/data log are also entry points. The last
+// argument may be the name of the assembler syntax.
+func (m *modCPU) cmdSource(args []string) error {
+	if err := checkLen(args, 3, maxArgs); err != nil {
+		return err
+	}
+	src, ok := m.cpu.(rcs.CPUSource)
+	if !ok {
+		return fmt.Errorf("cannot generate source for this processor")
+	}
+	syntaxes := src.Syntaxes()
+	syn := syntaxes[0]
+	for _, s := range syntaxes {
+		if s.Name == args[len(args)-1] {
+			syn = s
+			args = args[:len(args)-1]
+		}
+	}
+	start, err := m.mon.parseAddress(m.mem, args[1])
+	if err != nil {
+		return err
+	}
+	end, err := m.mon.parseAddress(m.mem, args[2])
+	if err != nil {
+		return err
+	}
+	if end < start {
+		return fmt.Errorf("invalid range: %v", formatRange(start, end))
+	}
+	var entries []int
+	for _, arg := range args[3:] {
+		addr, err := m.mon.parseAddress(m.mem, arg)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, addr)
+	}
+	// code executed after a jump, branch, call or interrupt while the
+	// code/data log was on is also an entry point
+	if m.mem.CDL != nil {
+		entries = append(entries, m.mem.CDL.Targets()...)
+	}
+	// when more than one symbol has the same address, use the first by name
+	symbols := make([]string, 0, len(m.mon.symbols))
+	for name := range m.mon.symbols {
+		symbols = append(symbols, name)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(symbols)))
+	names := make(map[int]string)
+	for _, name := range symbols {
+		names[m.mon.symbols[name]] = strings.Replace(name, "-", "_", -1)
+	}
+
+	an := rcs.Analyze(m.mem, syn, start, end, entries, names, m.mon.annotations(m.mem))
+	var out bytes.Buffer
+	if err := an.WriteSource(&out); err != nil {
+		return err
+	}
+	filename, err := userPath(args[0])
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filename, out.Bytes(), 0644); err != nil {
+		return err
+	}
+	m.mon.out.Printf("saved %v, %v instructions, %v labels", formatRange(start, end), len(an.Code), len(an.Labels))
+	return nil
+}
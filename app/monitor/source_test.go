@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	filename := filepath.Join(dir, "test.s")
+	f.mon.Eval(strings.Join([]string{
+		"poke $60 $01 $2f $68 $00 $0f $41 $42 $43 $44 $45 $46 $47 $48 $49",
+		"poke $68 $10 $22 $0f",
+		"symbol print $68",
+		"note $65 table",
+		"source " + filename + " $60 $6f $60 mock",
+		"source " + filename + " $6f $60",
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ poke $60 $01 $2f $68 $00 $0f $41 $42 $43 $44 $45 $46 $47 $48 $49",
+		"+ poke $68 $10 $22 $0f",
+		"+ symbol print $68",
+		"+ note $65 table",
+		"+ source " + filename + " $60 $6f $60 mock",
+		"saved $0060-$006f, 5 instructions, 1 labels",
+		"+ source " + filename + " $6f $60",
+		"invalid range: $006f-$0060",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have = strings.TrimSpace(string(data))
+	want = strings.TrimSpace(`
+; $0060-$006f, mock
+
+	org $0060
+	i01
+	i2f print
+	i0f
+	.byte $41,$42,$43               ; table
+print:
+	i10 $22
+	i0f
+	.byte $47,$48,$49,$00,$00
+`)
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+}
+
+func TestSourceWorkDir(t *testing.T) {
+	dir, dataDir, done := workDir(t)
+	defer done()
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval(strings.Join([]string{
+		"poke $60 $01 $0f",
+		"source test.s $60 $61 $60 mock",
+	}, "\n"))
+	want := strings.Join([]string{
+		"+ poke $60 $01 $0f",
+		"+ source test.s $60 $61 $60 mock",
+		"saved $0060-$0061, 2 instructions, 0 labels",
+	}, "\n")
+	have := strings.TrimSpace(f.output())
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "test.s"))
+	if err != nil {
+		t.Fatalf("not saved to working directory: %v", err)
+	}
+	have = strings.TrimSpace(string(data))
+	want = strings.TrimSpace(`
+; $0060-$0061, mock
+
+	org $0060
+	i01
+	i0f
+`)
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "test.s")); err == nil {
+		t.Errorf("saved to data directory")
+	}
+}
@@ -0,0 +1,517 @@
+package monitor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Key sequences sent by the terminal
+const (
+	keyCtrlC     = "\x03"
+	keyCtrlD     = "\x04"
+	keyCtrlH     = "\x08"
+	keyEnter     = "\r"
+	keyBackspace = "\x7f"
+	keyPageUp    = "\x1b[5~"
+	keyPageDown  = "\x1b[6~"
+	keyF5        = "\x1b[15~"
+	keyF6        = "\x1b[17~"
+	keyF10       = "\x1b[21~"
+	keyF11       = "\x1b[23~"
+	keyF12       = "\x1b[24~"
+)
+
+// Commands run by function keys
+var tuiKeys = map[string]string{
+	keyF5:  "go",
+	keyF6:  "pause",
+	keyF10: "over",
+	keyF11: "step",
+	keyF12: "out",
+}
+
+const (
+	tuiHelp    = "F5 go  F6 pause  F10 over  F11 step  F12 out  ^C quit"
+	tuiPrompt  = "> "
+	tuiRightW  = 24   // width of the breakpoint and watch panes
+	tuiLogMax  = 1000 // number of log lines kept
+	tuiRefresh = 50 * time.Millisecond
+
+	ansiAltScreen  = "\033[?1049h"
+	ansiMainScreen = "\033[?1049l"
+	ansiHome       = "\033[H"
+	ansiClearEOL   = "\033[K"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiReverse    = "\033[7m"
+)
+
+var ansiRegex = regexp.MustCompile("\033\\[[0-9;?]*[A-Za-z]|\r")
+
+// TUI is a full screen front end to the monitor. The screen is divided
+// into panes for the registers of the selected CPU, the disassembly around
+// the program counter, a memory dump, breakpoints, watches and the log.
+// Commands are typed on the bottom line and are handled the same way as
+// in the line-oriented monitor.
+//
+// The screen is redrawn after each key press, whenever the status of the
+// machine changes, and when new lines are written to the log.
+type TUI struct {
+	mon *Monitor
+	in  io.Reader
+	out io.Writer
+	log *logPane
+
+	dasm      map[string]*rcs.Disassembler
+	dasmAddrs []int // addresses shown in the last disassembly
+	memAddr   int
+	memView   int // last dump address seen from the memory module
+	memRows   int
+	line      []rune
+	dirty     int32
+	quit      bool
+}
+
+// NewTUI creates a full screen front end for the monitor that uses the
+// standard input and output.
+func NewTUI(mon *Monitor) *TUI {
+	t := &TUI{
+		mon:  mon,
+		in:   os.Stdin,
+		out:  os.Stdout,
+		dasm: make(map[string]*rcs.Disassembler),
+	}
+	t.log = &logPane{max: tuiLogMax, changed: t.invalidate}
+	for name, cpu := range mon.cpu {
+		if dasm := mon.newDisassembler(cpu); dasm != nil {
+			t.dasm[name] = dasm
+		}
+	}
+	return t
+}
+
+// Run takes over the terminal until the user quits. The machine is asked
+// to quit when this function returns.
+func (t *TUI) Run() error {
+	err := t.run()
+	if err := t.mon.autosaveProject(); err != nil {
+		t.mon.out.Println(err)
+	}
+	t.mon.mach.Command(rcs.MachQuit)
+	return err
+}
+
+func (t *TUI) run() error {
+	fd := termFd(t.in)
+	if fd < 0 {
+		return errors.New("input is not a terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer readline.Restore(fd, state)
+
+	// send all output to the log pane
+	cw := t.mon.cw
+	cw.mutex.Lock()
+	prevW, prevRefresh := cw.w, cw.RefreshFunc
+	cw.w, cw.RefreshFunc = t.log, func() {}
+	cw.mutex.Unlock()
+	defer func() {
+		cw.mutex.Lock()
+		cw.w, cw.RefreshFunc = prevW, prevRefresh
+		cw.mutex.Unlock()
+	}()
+
+	err = t.mon.sync(func() error {
+		t.mon.statusFunc = func(rcs.Status) { t.invalidate() }
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	defer t.mon.sync(func() error {
+		t.mon.statusFunc = nil
+		return nil
+	})
+
+	io.WriteString(t.out, ansiAltScreen)
+	defer io.WriteString(t.out, ansiMainScreen+ansiShowCursor)
+
+	keys := make(chan string)
+	go readKeys(t.in, keys)
+	ticker := time.NewTicker(tuiRefresh)
+	defer ticker.Stop()
+
+	t.draw()
+	for !t.quit {
+		select {
+		case k, ok := <-keys:
+			if !ok {
+				return nil
+			}
+			t.key(k)
+			t.draw()
+		case <-ticker.C:
+			if atomic.SwapInt32(&t.dirty, 0) == 1 {
+				t.draw()
+			}
+		}
+	}
+	return nil
+}
+
+// invalidate marks the screen for a redraw on the next refresh. It is safe
+// to call from any goroutine.
+func (t *TUI) invalidate() {
+	atomic.StoreInt32(&t.dirty, 1)
+}
+
+func (t *TUI) key(k string) {
+	if cmd, ok := tuiKeys[k]; ok {
+		t.exec(cmd)
+		return
+	}
+	switch k {
+	case keyCtrlC, keyCtrlD:
+		t.quit = true
+	case keyEnter:
+		line := strings.TrimSpace(string(t.line))
+		t.line = t.line[:0]
+		args := splitArgs(line)
+		if len(args) > 0 && (args[0] == "q" || args[0] == "quit") {
+			t.quit = true
+			return
+		}
+		t.exec(line)
+	case keyBackspace, keyCtrlH:
+		if len(t.line) > 0 {
+			t.line = t.line[:len(t.line)-1]
+		}
+	case keyPageUp:
+		t.memAddr -= t.memRows * 16
+		if t.memAddr < 0 {
+			t.memAddr = 0
+		}
+	case keyPageDown:
+		t.memAddr += t.memRows * 16
+	default:
+		r, _ := utf8.DecodeRuneInString(k)
+		if utf8.RuneCountInString(k) == 1 && unicode.IsPrint(r) {
+			t.line = append(t.line, r)
+		}
+	}
+}
+
+// exec runs a command line and echos it to the log. An empty line repeats
+// the previous command, if any, like the line-oriented monitor.
+func (t *TUI) exec(line string) {
+	echo := line
+	if echo == "" {
+		echo = t.mon.defaultCmd
+	}
+	if echo != "" {
+		fmt.Fprintf(t.log, "%v%v\n", t.prompt(), echo)
+	}
+	if err := t.mon.enter(line); err != nil {
+		t.mon.out.Printf("%v", err)
+	}
+}
+
+// prompt returns the prompt for the command line. Blocks that are being
+// entered and the assembler use the prompts of the line-oriented monitor.
+func (t *TUI) prompt() string {
+	if t.mon.pending != nil || t.mon.asmCPU != "" {
+		return t.mon.getPrompt()
+	}
+	return tuiPrompt
+}
+
+func (t *TUI) draw() {
+	w, h, err := readline.GetSize(termFd(t.out))
+	if err != nil {
+		w, h = 80, 24
+	}
+	frame, err := t.frame(w, h)
+	if err != nil {
+		// the machine is no longer running
+		t.quit = true
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(ansiHideCursor + ansiHome)
+	for i, line := range frame {
+		if i == 0 {
+			line = ansiReverse + line + ansiReset
+		}
+		buf.WriteString(line)
+		buf.WriteString(ansiClearEOL)
+		if i < len(frame)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	col := len(t.prompt()) + len(t.line) + 1
+	fmt.Fprintf(&buf, "\033[%v;%vH", len(frame), col)
+	buf.WriteString(ansiShowCursor)
+	t.out.Write(buf.Bytes())
+}
+
+// frame returns the lines of text to be shown on a screen that has a
+// width of w and a height of h.
+func (t *TUI) frame(w, h int) ([]string, error) {
+	if w < 40 {
+		w = 40
+	}
+	if h < 16 {
+		h = 16
+	}
+	leftW := w - tuiRightW - 1
+	logRows := h / 4
+	t.memRows = 4
+	if h >= 40 {
+		t.memRows = 8
+	}
+	topRows := h - 4 - t.memRows - logRows
+
+	var title string
+	var left, right, mem []string
+	err := t.mon.sync(func() error {
+		m := t.mon
+		title = fmt.Sprintf(" %v [%v]", m.sc, m.mach.Status)
+		regs := lines(fmt.Sprint(m.cpu[m.sc]))
+		left = append(left, header("registers", leftW))
+		left = append(left, regs...)
+		left = append(left, header("disassembly", leftW))
+		left = append(left, t.disassembly(topRows-len(left))...)
+
+		bps := lines(m.capture("breakpoint", "list"))
+		watches := lines(m.capture("watch", "list"))
+		right = append(right, header("breakpoints", tuiRightW))
+		right = append(right, bps...)
+		right = append(right, header("watches", tuiRightW))
+		right = append(right, watches...)
+
+		mem = t.memory(t.memRows)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	frame := make([]string, 0, h)
+	frame = append(frame, fit(title+"  "+tuiHelp, w))
+	for i := 0; i < topRows; i++ {
+		frame = append(frame, fit(row(left, i), leftW)+" "+fit(row(right, i), tuiRightW))
+	}
+	frame = append(frame, header("memory", w))
+	for i := 0; i < t.memRows; i++ {
+		frame = append(frame, fit(row(mem, i), w))
+	}
+	frame = append(frame, header("log", w))
+	logLines := t.log.tail(logRows)
+	for i := 0; i < logRows; i++ {
+		frame = append(frame, fit(row(logLines, i), w))
+	}
+	frame = append(frame, fit(t.prompt()+string(t.line), w))
+	return frame, nil
+}
+
+// disassembly lists instructions starting at the program counter. If the
+// program counter is in the top part of the previous listing, that listing
+// is kept so the instructions that were just executed remain visible.
+func (t *TUI) disassembly(rows int) []string {
+	if rows < 1 {
+		return nil
+	}
+	m := t.mon
+	dasm := t.dasm[m.sc]
+	if dasm == nil {
+		return []string{"cannot disassemble this processor"}
+	}
+	cpu := m.cpu[m.sc]
+	pc := cpu.PC() + cpu.Offset()
+	start := pc
+	for i, addr := range t.dasmAddrs {
+		if addr == pc && i < rows*2/3 {
+			start = t.dasmAddrs[0]
+			break
+		}
+	}
+	brkpts := m.mach.Breakpoints[m.sc]
+	t.dasmAddrs = t.dasmAddrs[:0]
+	list := make([]string, 0, rows)
+	dasm.SetPC(start)
+	for i := 0; i < rows; i++ {
+		addr := dasm.PC()
+		gutter := []byte("  ")
+		if _, ok := brkpts[addr]; ok {
+			gutter[0] = '*'
+		}
+		if addr == pc {
+			gutter[1] = '>'
+		}
+		list = append(list, string(gutter)+dasm.Next())
+		t.dasmAddrs = append(t.dasmAddrs, addr)
+	}
+	return list
+}
+
+// memory dumps memory at the current memory address. When the memory module
+// dumps memory, the pane follows to that address.
+func (t *TUI) memory(rows int) []string {
+	m := t.mon
+	if mod, ok := m.mods[m.comps[m.sc].Parent].(*modMemory); ok {
+		if mod.view != t.memView {
+			t.memView = mod.view
+			t.memAddr = mod.view
+		}
+	}
+	mem := m.cpu[m.sc].Memory()
+	if t.memAddr > mem.MaxAddr {
+		t.memAddr = mem.MaxAddr / 16 * 16
+	}
+	decoder, ok := m.mach.CharDecoders[m.encoding]
+	if !ok {
+		return []string{fmt.Sprintf("invalid encoding: %v", m.encoding)}
+	}
+	end := t.memAddr + rows*16 - 1
+	if end > mem.MaxAddr {
+		end = mem.MaxAddr
+	}
+	return lines(dump(mem, t.memAddr, end, decoder, "", nil))
+}
+
+// readKeys sends each key, or the escape sequence for a special key, read
+// from in to the keys channel. The channel is closed when in can no longer
+// be read.
+// termFd returns the file descriptor of the terminal behind the reader or
+// writer, or -1 if there is none.
+func termFd(v interface{}) int {
+	if f, ok := v.(interface{ Fd() uintptr }); ok {
+		return int(f.Fd())
+	}
+	return -1
+}
+
+func readKeys(in io.Reader, keys chan<- string) {
+	buf := make([]byte, 64)
+	for {
+		n, err := in.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for _, k := range splitKeys(buf[:n]) {
+			keys <- k
+		}
+	}
+}
+
+func splitKeys(b []byte) []string {
+	var keys []string
+	for len(b) > 0 {
+		n := 1
+		switch {
+		case b[0] == 0x1b && len(b) > 2 && b[1] == '[':
+			// control sequence ends with a byte in the range of @ to ~
+			for n = 2; n < len(b); n++ {
+				if b[n] >= 0x40 && b[n] <= 0x7e {
+					n++
+					break
+				}
+			}
+		case b[0] == 0x1b && len(b) > 2 && b[1] == 'O':
+			n = 3
+		case b[0] >= 0x80:
+			_, n = utf8.DecodeRune(b)
+		}
+		keys = append(keys, string(b[:n]))
+		b = b[n:]
+	}
+	return keys
+}
+
+// logPane collects the output of the monitor and keeps the most recent
+// lines.
+type logPane struct {
+	mutex   sync.Mutex
+	lines   []string
+	partial []byte
+	max     int
+	changed func()
+}
+
+func (l *logPane) Write(p []byte) (int, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	for _, b := range p {
+		if b != '\n' {
+			l.partial = append(l.partial, b)
+			continue
+		}
+		line := ansiRegex.ReplaceAllString(string(l.partial), "")
+		l.lines = append(l.lines, strings.Replace(line, "\t", "    ", -1))
+		l.partial = l.partial[:0]
+	}
+	if len(l.lines) > l.max {
+		l.lines = append(l.lines[:0], l.lines[len(l.lines)-l.max:]...)
+	}
+	if l.changed != nil {
+		l.changed()
+	}
+	return len(p), nil
+}
+
+// tail returns the last n lines.
+func (l *logPane) tail(n int) []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if n > len(l.lines) {
+		n = len(l.lines)
+	}
+	return append([]string{}, l.lines[len(l.lines)-n:]...)
+}
+
+// lines splits text into lines, ignoring the final newline.
+func lines(text string) []string {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+func row(list []string, i int) string {
+	if i < len(list) {
+		return list[i]
+	}
+	return ""
+}
+
+// fit pads or truncates s to exactly n characters.
+func fit(s string, n int) string {
+	count := utf8.RuneCountInString(s)
+	if count > n {
+		return string([]rune(s)[:n])
+	}
+	return s + strings.Repeat(" ", n-count)
+}
+
+func header(name string, n int) string {
+	return fit("── "+name+" "+strings.Repeat("─", n), n)
+}
@@ -0,0 +1,150 @@
+package monitor
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+func TestTUIFrame(t *testing.T) {
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	f.mon.Eval("poke 2 $10 $ab\nbp 2 on\nm 0")
+	tui := NewTUI(f.mon)
+	tui.log.Write([]byte("\r\033[2Khello\n"))
+
+	frame, err := tui.frame(80, 24)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frame) != 24 {
+		t.Fatalf("have %v lines, want 24", len(frame))
+	}
+	for i, line := range frame {
+		if n := len([]rune(line)); n != 80 {
+			t.Errorf("line %v: have width %v, want 80", i, n)
+		}
+	}
+	text := strings.Join(frame, "\n")
+	wants := []string{
+		" cpu [pause]",
+		"pc:0000 a:00 b:00 q:false z:false",
+		" >$0000:  00        i00",
+		"* $0002:  10 ab     i10 $ab",
+		"$0002",
+		"$0000  00 00 10 ab 00",
+		"hello",
+	}
+	for _, want := range wants {
+		if !strings.Contains(text, want) {
+			t.Errorf("missing %q in:\n%v", want, text)
+		}
+	}
+	if last := strings.TrimSpace(frame[23]); last != ">" {
+		t.Errorf("have prompt %q", last)
+	}
+}
+
+func TestTUIExec(t *testing.T) {
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	tui := NewTUI(f.mon)
+	prompt := func() string {
+		frame, err := tui.frame(80, 24)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimRight(frame[len(frame)-1], " ")
+	}
+	tests := []struct {
+		line   string
+		prompt string
+	}{
+		{"repeat 2", "  >"},
+		{"poke 0 1", "  >"},
+		{"end", ">"},
+		{"a $40", "$0040>"},
+		{"i01", "$0041>"},
+		{"", ">"},
+		{"foo", ">"},
+	}
+	for _, test := range tests {
+		tui.exec(test.line)
+		if have := prompt(); have != test.prompt {
+			t.Errorf("%q: have prompt %q, want %q", test.line, have, test.prompt)
+		}
+	}
+	log := strings.Join(tui.log.tail(10), "\n")
+	want := strings.Join([]string{
+		"> repeat 2",
+		"  > poke 0 1",
+		"  > end",
+		"> a $40",
+		"$0040> i01",
+		"> foo",
+	}, "\n")
+	if log != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", log, want)
+	}
+	out := strings.Join([]string{
+		"$0040:  01        i01",
+		"no such command: foo",
+	}, "\n")
+	if have := strings.TrimSpace(f.output()); have != out {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, out)
+	}
+}
+
+func TestTUIDrawOutput(t *testing.T) {
+	f := newMonitorFixture()
+	go f.mon.mach.Run()
+	defer func() {
+		f.mon.mach.Call(rcs.MachQuit)
+	}()
+	var out bytes.Buffer
+	tui := NewTUI(f.mon)
+	tui.out = &out
+	tui.draw()
+	// not a terminal so the default size is used
+	if n := strings.Count(out.String(), "\r\n"); n != 23 {
+		t.Errorf("have %v line breaks, want 23", n)
+	}
+}
+
+func TestTermFd(t *testing.T) {
+	if have := termFd(os.Stdout); have != int(os.Stdout.Fd()) {
+		t.Errorf("have %v want %v", have, os.Stdout.Fd())
+	}
+	if have := termFd(&bytes.Buffer{}); have != -1 {
+		t.Errorf("have %v want -1", have)
+	}
+}
+
+func TestSplitKeys(t *testing.T) {
+	have := splitKeys([]byte("a\x1b[15~\x1bOP\r\x7fé"))
+	want := []string{"a", keyF5, "\x1bOP", keyEnter, keyBackspace, "é"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %q \n want: %q", have, want)
+	}
+}
+
+func TestLogPane(t *testing.T) {
+	l := &logPane{max: 2}
+	l.Write([]byte("one\ntwo\n\033[1;34mthr"))
+	l.Write([]byte("ee\033[0m\npartial"))
+	have := l.tail(5)
+	want := []string{"two", "three"}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %q \n want: %q", have, want)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+	"github.com/blackchip-org/retro-cs/rcs/m6502"
+	"github.com/blackchip-org/retro-cs/rcs/z80"
+)
+
+// register is a processor register that can be traced and set from a
+// state file.
+type register struct {
+	name  string
+	width int // number of hex digits
+	get   func() int
+	set   func(int)
+	flags string // names of the bits, from highest to lowest, in a flag register
+}
+
+// core is a processor with the registers shown in a trace. The program
+// counter is traced first and is not in the list of registers.
+type core struct {
+	cpu  rcs.CPU
+	regs []register
+}
+
+var cores = map[string]func(*rcs.Memory) *core{
+	"m6502": newM6502,
+	"z80":   newZ80,
+}
+
+func reg8(name string, r *uint8) register {
+	return register{
+		name:  name,
+		width: 2,
+		get:   func() int { return int(*r) },
+		set:   func(v int) { *r = uint8(v) },
+	}
+}
+
+func reg16(name string, r *uint16) register {
+	return register{
+		name:  name,
+		width: 4,
+		get:   func() int { return int(*r) },
+		set:   func(v int) { *r = uint16(v) },
+	}
+}
+
+func pair(name string, hi *uint8, lo *uint8) register {
+	return register{
+		name:  name,
+		width: 4,
+		get:   func() int { return int(*hi)<<8 | int(*lo) },
+		set:   func(v int) { *hi, *lo = uint8(v>>8), uint8(v) },
+	}
+}
+
+func bit(name string, f *bool) register {
+	return register{
+		name:  name,
+		width: 1,
+		get: func() int {
+			if *f {
+				return 1
+			}
+			return 0
+		},
+		set: func(v int) { *f = v != 0 },
+	}
+}
+
+func newM6502(mem *rcs.Memory) *core {
+	cpu := m6502.New(mem)
+	sr := reg8("sr", &cpu.SR)
+	sr.flags = "nv-bdizc"
+	return &core{
+		cpu: cpu,
+		regs: []register{
+			reg8("a", &cpu.A),
+			reg8("x", &cpu.X),
+			reg8("y", &cpu.Y),
+			reg8("sp", &cpu.SP),
+			sr,
+		},
+	}
+}
+
+func newZ80(mem *rcs.Memory) *core {
+	cpu := z80.New(mem)
+	f := reg8("f", &cpu.F)
+	f.flags = "sz5h3pnc"
+	return &core{
+		cpu: cpu,
+		regs: []register{
+			reg8("a", &cpu.A),
+			f,
+			reg8("b", &cpu.B),
+			reg8("c", &cpu.C),
+			reg8("d", &cpu.D),
+			reg8("e", &cpu.E),
+			reg8("h", &cpu.H),
+			reg8("l", &cpu.L),
+			pair("ix", &cpu.IXH, &cpu.IXL),
+			pair("iy", &cpu.IYH, &cpu.IYL),
+			reg16("sp", &cpu.SP),
+			reg8("i", &cpu.I),
+			reg8("r", &cpu.R),
+			pair("af_", &cpu.A1, &cpu.F1),
+			pair("bc_", &cpu.B1, &cpu.C1),
+			pair("de_", &cpu.D1, &cpu.E1),
+			pair("hl_", &cpu.H1, &cpu.L1),
+			reg8("im", &cpu.IM),
+			bit("iff1", &cpu.IFF1),
+			bit("iff2", &cpu.IFF2),
+		},
+	}
+}
+
+// entry returns the current state of the processor.
+func (c *core) entry() *entry {
+	e := newEntry()
+	e.set("pc", c.cpu.PC()+c.cpu.Offset())
+	for _, r := range c.regs {
+		e.set(r.name, r.get())
+	}
+	return e
+}
+
+// load sets the registers and memory found in the entry.
+func (c *core) load(e *entry) error {
+	for _, key := range e.keys {
+		if key == "pc" {
+			c.cpu.SetPC(e.regs[key] - c.cpu.Offset())
+			continue
+		}
+		found := false
+		for _, r := range c.regs {
+			if r.name == key {
+				r.set(e.regs[key])
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("invalid register: %v", key)
+		}
+	}
+	for _, w := range e.writes {
+		c.cpu.Memory().Write(w.addr, uint8(w.value))
+	}
+	return nil
+}
+
+func (c *core) widths() map[string]int {
+	w := map[string]int{"pc": 4}
+	for _, r := range c.regs {
+		w[r.name] = r.width
+	}
+	return w
+}
+
+func (c *core) flags() map[string]string {
+	f := make(map[string]string)
+	for _, r := range c.regs {
+		if r.flags != "" {
+			f[r.name] = r.flags
+		}
+	}
+	return f
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+var (
+	count   int
+	context int
+	diff    string
+	loads   loadList
+)
+
+// loadList is the list of files given with -load in the form file@address.
+type loadList []string
+
+func (l *loadList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *loadList) Set(v string) error {
+	if !strings.Contains(v, "@") {
+		return fmt.Errorf("expecting file@address")
+	}
+	*l = append(*l, v)
+	return nil
+}
+
+func init() {
+	flag.IntVar(&count, "n", 1000, "maximum `number` of instructions to trace")
+	flag.IntVar(&context, "context", 5, "`number` of instructions shown before a divergence")
+	flag.StringVar(&diff, "diff", "", "compare with the trace in `file`")
+	flag.Var(&loads, "load", "load `file@address` into memory, address in hex")
+
+	flag.Usage = func() {
+		o := flag.CommandLine.Output()
+		fmt.Fprintf(o, "Usage: rcs-difftrace [options] <cpu> [state-file]\n\n")
+		flag.PrintDefaults()
+		fmt.Fprintf(o, "\nAvailable values for <cpu>:\n\n")
+		list := []string{}
+		for key := range cores {
+			list = append(list, key)
+		}
+		sort.Strings(list)
+		fmt.Fprintln(o, strings.Join(list, "\n"))
+		fmt.Fprintln(o)
+	}
+}
+
+func main() {
+	log.SetFlags(0)
+
+	flag.Parse()
+	if flag.NArg() < 1 || flag.NArg() > 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+	newCore, ok := cores[flag.Arg(0)]
+	if !ok {
+		log.Fatalf("no such cpu: %v", flag.Arg(0))
+	}
+
+	ram := make([]uint8, 0x10000)
+	mem := rcs.NewMemory(1, 0x10000)
+	mem.MapRAM(0, ram)
+	c := newCore(mem)
+
+	for _, l := range loads {
+		if err := loadFile(ram, l); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if flag.NArg() == 2 {
+		state, err := readTraceFile(flag.Arg(1))
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, e := range state {
+			if err := c.load(e); err != nil {
+				log.Fatalf("%v: line %v: %v", flag.Arg(1), e.line, err)
+			}
+		}
+	}
+
+	var writes []write
+	mem.Callback = func(evt rcs.MemoryEvent) {
+		if !evt.Read {
+			writes = append(writes, write{addr: evt.Addr, value: int(evt.Value)})
+		}
+	}
+	for addr := 0; addr < 0x10000; addr++ {
+		mem.WatchWO(addr)
+	}
+	next := func() *entry {
+		e := c.entry()
+		writes = nil
+		c.cpu.Next()
+		e.writes = writes
+		return e
+	}
+
+	if diff == "" {
+		out := bufio.NewWriter(os.Stdout)
+		defer out.Flush()
+		for i := 0; i < count; i++ {
+			fmt.Fprintln(out, next().format(c.widths()))
+		}
+		return
+	}
+
+	want, err := readTraceFile(diff)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if d := diverge(next, want, count, context, c.flags()); d != nil {
+		report(c, d)
+		os.Exit(1)
+	}
+	n := count
+	if len(want) < n {
+		n = len(want)
+	}
+	fmt.Printf("%v instructions match\n", n)
+}
+
+// loadFile reads the file given in the form file@address into memory at
+// that address.
+func loadFile(ram []uint8, spec string) error {
+	i := strings.LastIndex(spec, "@")
+	if i < 0 {
+		return fmt.Errorf("expecting file@address: %v", spec)
+	}
+	addr, err := parseHex(spec[i+1:])
+	if err != nil || addr >= len(ram) {
+		return fmt.Errorf("invalid address: %v", spec)
+	}
+	data, err := ioutil.ReadFile(spec[:i])
+	if err != nil {
+		return err
+	}
+	if addr+len(data) > len(ram) {
+		return fmt.Errorf("%v: %v bytes at $%04x does not fit in memory", spec[:i], len(data), addr)
+	}
+	copy(ram[addr:], data)
+	return nil
+}
+
+func readTraceFile(filename string) ([]*entry, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	trace, err := readTrace(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", filename, err)
+	}
+	return trace, nil
+}
+
+// report shows the instructions that matched before the divergence, the
+// entries that do not match, and each difference found. Registers are
+// traced before the instruction executes so a difference in a register
+// is caused by the instruction before it.
+func report(c *core, d *divergence) {
+	widths := c.widths()
+	var dasm *rcs.Disassembler
+	if cd, ok := c.cpu.(rcs.CPUDisassembler); ok {
+		dasm = cd.NewDisassembler()
+	}
+	show := func(label string, e *entry) {
+		if dasm != nil {
+			dasm.SetPC(e.regs["pc"])
+			fmt.Println(dasm.Next())
+		}
+		fmt.Printf("%-6v%v\n", label, e.format(widths))
+	}
+	fmt.Printf("diverged at instruction %v, line %v of %v\n\n", d.n+1, d.want.line, diff)
+	for _, e := range d.history {
+		show("", e)
+	}
+	fmt.Println()
+	show("have:", d.have)
+	fmt.Printf("%-6v%v\n\n", "want:", d.want.format(widths))
+	for _, line := range d.diffs {
+		fmt.Println(line)
+	}
+}
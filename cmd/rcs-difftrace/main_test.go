@@ -0,0 +1,53 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.bin")
+	if err := ioutil.WriteFile(filename, []byte{1, 2, 3, 4}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec string
+		addr int
+		err  string
+	}{
+		{filename + "@0100", 0x100, ""},
+		{filename + "@$fffc", 0xfffc, ""},
+		{filename + "@fffd", 0, filename + ": 4 bytes at $fffd does not fit in memory"},
+		{filename + "@10000", 0, "invalid address: " + filename + "@10000"},
+		{filename + "@zz", 0, "invalid address: " + filename + "@zz"},
+		{filename, 0, "expecting file@address: " + filename},
+	}
+	for _, test := range tests {
+		t.Run(test.spec, func(t *testing.T) {
+			ram := make([]uint8, 0x10000)
+			err := loadFile(ram, test.spec)
+			if test.err != "" {
+				if err == nil || err.Error() != test.err {
+					t.Fatalf("\n have: %v \n want: %v", err, test.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, want := range []uint8{1, 2, 3, 4} {
+				if have := ram[test.addr+i]; have != want {
+					t.Errorf("$%04x: have %02x want %02x", test.addr+i, have, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// entry is one line of a trace: the state of the processor before an
+// instruction is executed and the memory written by that instruction.
+type entry struct {
+	line   int            // line number in the trace file
+	keys   []string       // register names in the order found
+	regs   map[string]int // register values by name
+	writes []write
+}
+
+type write struct {
+	addr  int
+	value int
+}
+
+func newEntry() *entry {
+	return &entry{regs: make(map[string]int)}
+}
+
+func (e *entry) set(key string, value int) {
+	if _, ok := e.regs[key]; !ok {
+		e.keys = append(e.keys, key)
+	}
+	e.regs[key] = value
+}
+
+// format returns the entry as a line in a trace file. Registers are padded
+// to the number of digits given in widths or to two digits when not found.
+func (e *entry) format(widths map[string]int) string {
+	fields := make([]string, 0, len(e.keys)+len(e.writes))
+	for _, key := range e.keys {
+		width, ok := widths[key]
+		if !ok {
+			width = 2
+		}
+		fields = append(fields, fmt.Sprintf("%v=%0*x", key, width, e.regs[key]))
+	}
+	for _, w := range e.writes {
+		fields = append(fields, fmt.Sprintf("[%04x]=%02x", w.addr, w.value))
+	}
+	return strings.Join(fields, " ")
+}
+
+// parseEntry parses a line in the form:
+//
+//	pc=0100 a=00 f=44 [fffe]=03 [ffff]=01
+//
+// Values are in hex and may start with "$" or "0x". Registers are given
+// as name=value and memory writes as [address]=value.
+func parseEntry(text string) (*entry, error) {
+	e := newEntry()
+	for _, field := range strings.Fields(text) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid field: %v", field)
+		}
+		key := strings.ToLower(parts[0])
+		value, err := parseHex(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value: %v", field)
+		}
+		if strings.HasPrefix(key, "[") && strings.HasSuffix(key, "]") {
+			addr, err := parseHex(key[1 : len(key)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid address: %v", field)
+			}
+			e.writes = append(e.writes, write{addr: addr, value: value})
+			continue
+		}
+		e.set(key, value)
+	}
+	return e, nil
+}
+
+func parseHex(s string) (int, error) {
+	s = strings.TrimPrefix(strings.ToLower(s), "$")
+	s = strings.TrimPrefix(s, "0x")
+	v, err := strconv.ParseUint(s, 16, 32)
+	return int(v), err
+}
+
+// readTrace reads a trace file. Blank lines and lines starting with "#"
+// are ignored.
+func readTrace(r io.Reader) ([]*entry, error) {
+	var trace []*entry
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		e, err := parseEntry(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: %v", n, err)
+		}
+		e.line = n
+		trace = append(trace, e)
+	}
+	return trace, scanner.Err()
+}
+
+// compare returns a line for each difference between the entry that was
+// traced and the one that was expected. Registers that are not in both
+// entries are not compared. Writes are only compared when requested.
+func compare(have *entry, want *entry, flags map[string]string, writes bool) []string {
+	var diffs []string
+	for _, key := range want.keys {
+		h, ok := have.regs[key]
+		if !ok || h == want.regs[key] {
+			continue
+		}
+		diff := fmt.Sprintf("%v: have %02x want %02x", key, h, want.regs[key])
+		if names, ok := flags[key]; ok {
+			diff += " (" + flagNames(names, h^want.regs[key]) + ")"
+		}
+		diffs = append(diffs, diff)
+	}
+	if !writes {
+		return diffs
+	}
+	hw, ww := writeMap(have.writes), writeMap(want.writes)
+	var addrs []int
+	for addr := range hw {
+		addrs = append(addrs, addr)
+	}
+	for addr := range ww {
+		if _, ok := hw[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Ints(addrs)
+	for _, addr := range addrs {
+		h, hok := hw[addr]
+		w, wok := ww[addr]
+		switch {
+		case !wok:
+			diffs = append(diffs, fmt.Sprintf("[%04x]: have %02x want no write", addr, h))
+		case !hok:
+			diffs = append(diffs, fmt.Sprintf("[%04x]: have no write want %02x", addr, w))
+		case h != w:
+			diffs = append(diffs, fmt.Sprintf("[%04x]: have %02x want %02x", addr, h, w))
+		}
+	}
+	return diffs
+}
+
+// divergence is the first instruction where a trace does not match the
+// one that was expected.
+type divergence struct {
+	n       int      // index of the instruction in the expected trace
+	history []*entry // instructions that matched before it
+	have    *entry
+	want    *entry
+	diffs   []string
+}
+
+// diverge calls next to trace each instruction, up to count instructions,
+// and compares it with the expected trace. The first divergence is returned
+// along with up to context instructions before it, or nil if the traces
+// match. Writes are only compared if the expected trace contains at least
+// one.
+func diverge(next func() *entry, want []*entry, count int, context int, flags map[string]string) *divergence {
+	writes := false
+	for _, e := range want {
+		if len(e.writes) > 0 {
+			writes = true
+		}
+	}
+	var history []*entry
+	for i := 0; i < count && i < len(want); i++ {
+		have := next()
+		diffs := compare(have, want[i], flags, writes)
+		if len(diffs) > 0 {
+			return &divergence{
+				n:       i,
+				history: history,
+				have:    have,
+				want:    want[i],
+				diffs:   diffs,
+			}
+		}
+		history = append(history, have)
+		if len(history) > context {
+			history = history[1:]
+		}
+	}
+	return nil
+}
+
+// writeMap returns the last value written to each address.
+func writeMap(writes []write) map[int]int {
+	m := make(map[int]int)
+	for _, w := range writes {
+		m[w.addr] = w.value
+	}
+	return m
+}
+
+// flagNames returns the names of the bits set in the value. Names are
+// given from the highest bit to the lowest.
+func flagNames(names string, value int) string {
+	var list []string
+	for i, name := range names {
+		bit := len(names) - 1 - i
+		if value&(1<<uint(bit)) != 0 {
+			list = append(list, string(name))
+		}
+	}
+	return strings.Join(list, " ")
+}
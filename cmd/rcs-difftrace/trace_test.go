@@ -0,0 +1,281 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		text string
+		want *entry
+	}{
+		{"pc=0100 a=00", &entry{
+			keys: []string{"pc", "a"},
+			regs: map[string]int{"pc": 0x100, "a": 0},
+		}},
+		{"PC=$c000 SR=0x30", &entry{
+			keys: []string{"pc", "sr"},
+			regs: map[string]int{"pc": 0xc000, "sr": 0x30},
+		}},
+		{"pc=0100 [fffe]=03 [FFFF]=$01", &entry{
+			keys:   []string{"pc"},
+			regs:   map[string]int{"pc": 0x100},
+			writes: []write{{0xfffe, 0x03}, {0xffff, 0x01}},
+		}},
+		{"  pc=0100   a=12  ", &entry{
+			keys: []string{"pc", "a"},
+			regs: map[string]int{"pc": 0x100, "a": 0x12},
+		}},
+		{"a=01 a=02", &entry{
+			keys: []string{"a"},
+			regs: map[string]int{"a": 0x02},
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			have, err := parseEntry(test.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, test.want) {
+				t.Errorf("\n have: %+v \n want: %+v", have, test.want)
+			}
+		})
+	}
+}
+
+func TestParseEntryErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"pc", "invalid field: pc"},
+		{"pc=0100 a", "invalid field: a"},
+		{"pc=", "invalid value: pc="},
+		{"pc=zz", "invalid value: pc=zz"},
+		{"a=1ffffffff", "invalid value: a=1ffffffff"},
+		{"[zz]=01", "invalid address: [zz]=01"},
+		{"[ffff]=", "invalid value: [ffff]="},
+	}
+	for _, test := range tests {
+		t.Run(test.text, func(t *testing.T) {
+			_, err := parseEntry(test.text)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestReadTrace(t *testing.T) {
+	text := strings.Join([]string{
+		"# header",
+		"pc=0100 a=00",
+		"",
+		"   ",
+		"pc=0101 a=01 [2000]=01",
+	}, "\n")
+	trace, err := readTrace(strings.NewReader(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 2 {
+		t.Fatalf("have %v entries, want 2", len(trace))
+	}
+	widths := map[string]int{"pc": 4}
+	tests := []struct {
+		line   int
+		format string
+	}{
+		{2, "pc=0100 a=00"},
+		{5, "pc=0101 a=01 [2000]=01"},
+	}
+	for i, test := range tests {
+		if trace[i].line != test.line {
+			t.Errorf("entry %v: have line %v, want %v", i, trace[i].line, test.line)
+		}
+		if have := trace[i].format(widths); have != test.format {
+			t.Errorf("entry %v: \n have: %v \n want: %v", i, have, test.format)
+		}
+	}
+}
+
+func TestReadTraceErrors(t *testing.T) {
+	tests := []struct {
+		text string
+		err  string
+	}{
+		{"pc=0100\npc", "line 2: invalid field: pc"},
+		{"# header\n\npc=0100 a=xx", "line 3: invalid value: a=xx"},
+	}
+	for _, test := range tests {
+		t.Run(test.err, func(t *testing.T) {
+			_, err := readTrace(strings.NewReader(test.text))
+			if err == nil || err.Error() != test.err {
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	flags := map[string]string{"f": "sz5h3pnc"}
+	tests := []struct {
+		name   string
+		have   string
+		want   string
+		writes bool
+		diffs  []string
+	}{
+		{"match", "pc=0100 a=01", "pc=0100 a=01", false, nil},
+		{"register", "pc=0100 a=01", "pc=0100 a=02", false, []string{
+			"a: have 01 want 02",
+		}},
+		{"flags", "f=c1", "f=40", false, []string{
+			"f: have c1 want 40 (s c)",
+		}},
+		{"missing register", "pc=0100 a=01", "pc=0100 x=05", false, nil},
+		{"writes ignored", "pc=0100 [2000]=01", "pc=0100 [2000]=02", false, nil},
+		{"writes", "pc=0100 [2000]=01 [2001]=03", "pc=0100 [2000]=02 [2002]=04", true, []string{
+			"[2000]: have 01 want 02",
+			"[2001]: have 03 want no write",
+			"[2002]: have no write want 04",
+		}},
+		{"last write", "pc=0100 [2000]=01 [2000]=02", "pc=0100 [2000]=02", true, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			have, err := parseEntry(test.have)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := parseEntry(test.want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			diffs := compare(have, want, flags, test.writes)
+			if !reflect.DeepEqual(diffs, test.diffs) {
+				t.Errorf("\n have: %v \n want: %v", diffs, test.diffs)
+			}
+		})
+	}
+}
+
+func TestDiverge(t *testing.T) {
+	parse := func(lines ...string) []*entry {
+		trace, err := readTrace(strings.NewReader(strings.Join(lines, "\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return trace
+	}
+	have := parse(
+		"pc=0100 a=00",
+		"pc=0101 a=01",
+		"pc=0102 a=02",
+		"pc=0103 a=03 [2000]=01",
+		"pc=0104 a=04",
+	)
+	tests := []struct {
+		name    string
+		want    []*entry
+		count   int
+		n       int // -1 when the traces match
+		history []int
+		diffs   []string
+	}{
+		{"match", parse(
+			"pc=0100 a=00",
+			"pc=0101 a=01",
+			"pc=0102 a=02",
+		), 5, -1, nil, nil},
+		{"first", parse(
+			"pc=0100 a=01",
+		), 5, 0, nil, []string{"a: have 00 want 01"}},
+		{"third", parse(
+			"pc=0100 a=00",
+			"# comment",
+			"pc=0101 a=01",
+			"pc=0102 a=03",
+			"pc=0103 a=03",
+		), 5, 2, []int{0, 1}, []string{"a: have 02 want 03"}},
+		{"context", parse(
+			"pc=0100 a=00",
+			"pc=0101 a=01",
+			"pc=0102 a=02",
+			"pc=0103 a=03",
+			"pc=0105 a=04",
+		), 5, 4, []int{2, 3}, []string{"pc: have 104 want 105"}},
+		{"write", parse(
+			"pc=0100 a=00",
+			"pc=0101 a=01",
+			"pc=0102 a=02",
+			"pc=0103 a=03 [2000]=02",
+		), 5, 3, []int{1, 2}, []string{"[2000]: have 01 want 02"}},
+		{"count", parse(
+			"pc=0100 a=00",
+			"pc=0101 a=01",
+			"pc=0102 a=03",
+		), 2, -1, nil, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			i := 0
+			next := func() *entry {
+				e := have[i]
+				i++
+				return e
+			}
+			d := diverge(next, test.want, test.count, 2, nil)
+			if test.n < 0 {
+				if d != nil {
+					t.Fatalf("unexpected divergence at %v: %v", d.n, d.diffs)
+				}
+				return
+			}
+			if d == nil {
+				t.Fatalf("no divergence, want %v", test.n)
+			}
+			if d.n != test.n {
+				t.Errorf("have divergence at %v, want %v", d.n, test.n)
+			}
+			if d.have != have[test.n] || d.want != test.want[test.n] {
+				t.Errorf("wrong entries reported")
+			}
+			var history []int
+			for _, e := range d.history {
+				history = append(history, e.regs["a"])
+			}
+			if !reflect.DeepEqual(history, test.history) {
+				t.Errorf("\n have history: %v \n want history: %v", history, test.history)
+			}
+			if !reflect.DeepEqual(d.diffs, test.diffs) {
+				t.Errorf("\n have: %v \n want: %v", d.diffs, test.diffs)
+			}
+		})
+	}
+}
+
+func TestFlagNames(t *testing.T) {
+	tests := []struct {
+		names string
+		value int
+		want  string
+	}{
+		{"nv-bdizc", 0x00, ""},
+		{"nv-bdizc", 0x01, "c"},
+		{"nv-bdizc", 0x80, "n"},
+		{"nv-bdizc", 0xc3, "n v z c"},
+		{"nv-bdizc", 0x20, "-"},
+		{"sz5h3pnc", 0xff, "s z 5 h 3 p n c"},
+		{"sz5h3pnc", 0x100, ""},
+	}
+	for _, test := range tests {
+		have := flagNames(test.names, test.value)
+		if have != test.want {
+			t.Errorf("%v %02x: have %q want %q", test.names, test.value, have, test.want)
+		}
+	}
+}
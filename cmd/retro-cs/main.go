@@ -35,7 +35,9 @@ var (
 	optImport    string
 	optNoAudio   bool
 	optNoVideo   bool
+	optScript    string
 	optTrace     bool
+	optTUI       bool
 	optWait      bool
 )
 
@@ -48,7 +50,9 @@ func init() {
 	flag.BoolVar(&optMonitor, "m", false, "enable monitor")
 	flag.BoolVar(&optPanic, "panic", false, "install panic log writer")
 	flag.StringVar(&optSystem, "s", "c64", "start this `system`")
+	flag.StringVar(&optScript, "script", "", "run monitor commands in `filename` and exit")
 	flag.BoolVar(&optTrace, "t", false, "enable tracing")
+	flag.BoolVar(&optTUI, "tui", false, "use the full screen monitor")
 	flag.BoolVar(&optWait, "w", false, "wait for go command")
 }
 
@@ -71,7 +75,7 @@ func main() {
 			log.Println("profile saved")
 		}()
 	}
-	if optNoVideo || optTrace || optWait {
+	if optNoVideo || optTrace || optWait || optTUI || optScript != "" {
 		optMonitor = true
 	}
 
@@ -178,6 +182,10 @@ func main() {
 	if err != nil {
 		log.Fatalf("unable to create machine: \n%v", err)
 	}
+	mach.Cheats, err = rcs.LoadCheats(monitor.CheatFile())
+	if err != nil {
+		log.Printf("(!) unable to load cheats: %v", err)
+	}
 
 	var mon *monitor.Monitor
 	mon, err = monitor.New(mach)
@@ -188,15 +196,6 @@ func main() {
 		mon.Close()
 	}()
 
-	if optMonitor {
-		go func() {
-			err := mon.Run()
-			if err != nil {
-				log.Fatalf("monitor error: %v", err)
-			}
-		}()
-	}
-
 	mach.Status = rcs.Run
 	if optWait {
 		mach.Status = rcs.Pause
@@ -218,11 +217,38 @@ func main() {
 		log.SetOutput(&mock.PanicWriter{})
 	}
 
-	startFile := filepath.Join(config.UserDir, "startup")
-	cmds, err := ioutil.ReadFile(startFile)
-	if err == nil {
-		mon.Eval(string(cmds))
-	}
+	// monitor commands are handled by the machine so they must be run
+	// while the machine is running
+	exitStatus := 0
+	go func() {
+		if err := mon.LoadProject(); err != nil {
+			log.Printf("(!) unable to load project: %v", err)
+		}
+		startFile := filepath.Join(config.UserDir, "startup")
+		cmds, err := ioutil.ReadFile(startFile)
+		if err == nil {
+			mon.Eval(string(cmds))
+		}
+		if optScript != "" {
+			if err := mon.RunScript(optScript); err != nil {
+				log.Println(err)
+				exitStatus = 1
+			}
+			mach.Command(rcs.MachQuit)
+		} else if optTUI {
+			if err := monitor.NewTUI(mon).Run(); err != nil {
+				log.Fatalf("monitor error: %v", err)
+			}
+		} else if optMonitor {
+			if err := mon.Run(); err != nil {
+				log.Fatalf("monitor error: %v", err)
+			}
+		}
+	}()
 
 	mach.Run()
+	if exitStatus != 0 {
+		mon.Close()
+		os.Exit(exitStatus)
+	}
 }
@@ -723,6 +723,195 @@ func processXCB(tab *regtab, op uint8) string {
 	return ""
 }
 
+// T-states for unprefixed instructions. Conditional jumps, calls, and
+// returns are the number used when the condition is false and the repeat
+// instructions are the number used on the last repetition. The extra
+// T-states are added by the instruction.
+//
+// Timings are from the "Z80 Family CPU User Manual":
+//
+// http://www.z80.info/zip/z80cpu_um.pdf
+func timingMain(op uint8, fn string) int {
+	x := int(rcs.SliceBits(op, 6, 7))
+	y := int(rcs.SliceBits(op, 3, 5))
+	z := int(rcs.SliceBits(op, 0, 2))
+	p := int(rcs.SliceBits(op, 4, 5))
+	q := int(rcs.SliceBits(op, 3, 3))
+
+	if x == 0 {
+		if z == 0 {
+			if y == 0 || y == 1 {
+				return 4 // nop, ex af, af'
+			}
+			if y == 2 {
+				return 8 // djnz
+			}
+			if y == 3 {
+				return 12 // jr
+			}
+			return 7 // jr cc
+		}
+		if z == 1 {
+			if q == 0 {
+				return 10 // ld rp, nn
+			}
+			return 11 // add hl, rp
+		}
+		if z == 2 {
+			if p == 2 {
+				return 16 // ld (nn), hl; ld hl, (nn)
+			}
+			if p == 3 {
+				return 13 // ld (nn), a; ld a, (nn)
+			}
+			return 7
+		}
+		if z == 3 {
+			return 6 // inc rp, dec rp
+		}
+		if z == 4 || z == 5 {
+			if y == 6 {
+				return 11 // inc (hl), dec (hl)
+			}
+			return 4
+		}
+		if z == 6 {
+			if y == 6 {
+				return 10 // ld (hl), n
+			}
+			return 7
+		}
+		return 4
+	}
+	if x == 1 {
+		if y == 6 && z == 6 {
+			return 4 // halt
+		}
+		if y == 6 || z == 6 {
+			return 7
+		}
+		return 4
+	}
+	if x == 2 {
+		if z == 6 {
+			return 7
+		}
+		return 4
+	}
+	if z == 0 {
+		return 5 // ret cc
+	}
+	if z == 1 {
+		if q == 0 || p == 0 {
+			return 10 // pop, ret
+		}
+		if p == 3 {
+			return 6 // ld sp, hl
+		}
+		return 4 // exx, jp (hl)
+	}
+	if z == 2 {
+		return 10 // jp cc, nn
+	}
+	if z == 3 {
+		if y == 0 {
+			return 10 // jp nn
+		}
+		if y == 2 || y == 3 {
+			return 11 // out (n), a; in a, (n)
+		}
+		if y == 4 {
+			return 19 // ex (sp), hl
+		}
+		return 4
+	}
+	if z == 4 {
+		return 10 // call cc, nn
+	}
+	if z == 5 {
+		if q == 0 {
+			return 11 // push
+		}
+		return 17 // call nn
+	}
+	if z == 6 {
+		return 7
+	}
+	return 11 // rst
+}
+
+// T-states for dd and fd prefixed instructions. The prefix adds four
+// T-states and the displacement for (ix+d) or (iy+d) another eight,
+// except for ld (ix+d), n where it overlaps with fetching the value.
+func timingIndex(op uint8, fn string) int {
+	t := timingMain(op, fn) + 4
+	if strings.Contains(fn, "IndIX") || strings.Contains(fn, "IndIY") {
+		if op == 0x36 {
+			return t + 5
+		}
+		return t + 8
+	}
+	return t
+}
+
+func timingCB(op uint8, fn string) int {
+	x := int(rcs.SliceBits(op, 6, 7))
+	z := int(rcs.SliceBits(op, 0, 2))
+	if z != 6 {
+		return 8
+	}
+	if x == 1 {
+		return 12 // bit n, (hl)
+	}
+	return 15
+}
+
+func timingED(op uint8, fn string) int {
+	x := int(rcs.SliceBits(op, 6, 7))
+	y := int(rcs.SliceBits(op, 3, 5))
+	z := int(rcs.SliceBits(op, 0, 2))
+	if x == 2 {
+		return 16 // block instructions
+	}
+	switch z {
+	case 0, 1:
+		return 12 // in r, (c); out (c), r
+	case 2:
+		return 15 // sbc hl, rp; adc hl, rp
+	case 3:
+		return 20 // ld (nn), rp; ld rp, (nn)
+	case 5:
+		return 14 // retn, reti
+	case 7:
+		if y == 4 || y == 5 {
+			return 18 // rrd, rld
+		}
+		return 9 // ld i, a; ld r, a; ld a, i; ld a, r
+	}
+	return 8 // neg, im
+}
+
+func timingXCB(op uint8, fn string) int {
+	x := int(rcs.SliceBits(op, 6, 7))
+	if x == 1 {
+		return 20 // bit n, (ix+d)
+	}
+	return 23
+}
+
+// processTiming writes the number of T-states for each instruction found
+// in the table.
+func processTiming(out *bytes.Buffer, getFn func(*regtab, uint8) string, tab *regtab, timing func(uint8, string) int) {
+	for i := 0; i < 0x100; i++ {
+		fn := getFn(tab, uint8(i))
+		if fn == "" {
+			continue
+		}
+		line := fmt.Sprintf("0x%02x: %v,\n", i, timing(uint8(i), fn))
+		out.WriteString(line)
+	}
+}
+
 func process(out *bytes.Buffer, getFn func(*regtab, uint8) string, tab *regtab) {
 	for i := 0; i < 0x100; i++ {
 		fn := getFn(tab, uint8(i))
@@ -785,6 +974,34 @@ package z80
 	process(&out, processXCB, fdcb)
 	out.WriteString("}\n")
 
+	out.WriteString("var tstates = [256]uint8{\n")
+	processTiming(&out, processMain, un, timingMain)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesCB = [256]uint8{\n")
+	processTiming(&out, processCB, un, timingCB)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesED = [256]uint8{\n")
+	processTiming(&out, processED, un, timingED)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesDD = [256]uint8{\n")
+	processTiming(&out, processMain, dd, timingIndex)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesFD = [256]uint8{\n")
+	processTiming(&out, processMain, fd, timingIndex)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesDDCB = [256]uint8{\n")
+	processTiming(&out, processXCB, ddcb, timingXCB)
+	out.WriteString("}\n")
+
+	out.WriteString("var tstatesFDCB = [256]uint8{\n")
+	processTiming(&out, processXCB, fdcb, timingXCB)
+	out.WriteString("}\n")
+
 	filename := filepath.Join(targetDir, "opcodes.go")
 	err := ioutil.WriteFile(filename, out.Bytes(), 0644)
 	if err != nil {
@@ -2,6 +2,8 @@ package mock
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/blackchip-org/retro-cs/rcs"
 )
@@ -13,14 +15,17 @@ type CPU struct {
 	B   uint8 // sample register
 	Q   bool  // sample flag
 	Z   bool  // sample flag
+	SP  uint16
 
 	// Offset to be added to the program counter to get the address of the
 	// next instruction.
 	OffsetPC int
+
+	calls rcs.CallStack
 }
 
 func NewCPU(mem *rcs.Memory) *CPU {
-	return &CPU{mem: mem}
+	return &CPU{mem: mem, SP: 0xff00}
 }
 
 func (c *CPU) PC() int {
@@ -39,13 +44,20 @@ func (c *CPU) Memory() *rcs.Memory {
 	return c.mem
 }
 
+const (
+	OpCall   = 0x2f // call the subroutine at the address in the arguments
+	OpReturn = 0x0f // return from subroutine
+)
+
 // Next reads the next byte at the program counter as the "opcode". The high
-// nibble is the number of "arguments" it will fetch (max two).
+// nibble is the number of "arguments" it will fetch (max two). The
+// opcodes OpCall and OpReturn use a stack that grows downward from SP.
 func (c *CPU) Next() {
 	if c.OffsetPC == 1 {
 		c.pc++
 	}
-	opcode := c.mem.Read(int(c.pc))
+	addr := int(c.pc)
+	opcode := c.mem.Fetch(int(c.pc), true)
 	if c.OffsetPC == 0 {
 		c.pc++
 	}
@@ -53,8 +65,63 @@ func (c *CPU) Next() {
 	if narg > 2 {
 		narg = 2
 	}
+	for i := 1; i <= narg; i++ {
+		c.mem.Fetch(addr+i, false)
+	}
 	c.pc += uint16(narg)
-	return
+
+	switch opcode {
+	case OpCall:
+		c.SP -= 2
+		c.mem.WriteLE(int(c.SP), addr+1+narg)
+		entry := c.mem.ReadLE(addr + 1)
+		c.pc = uint16(entry - c.OffsetPC)
+		if c.calls.Enabled {
+			c.calls.Call(rcs.Frame{Entry: entry, Return: addr + 1 + narg, SP: int(c.SP)})
+		}
+	case OpReturn:
+		c.pc = uint16(c.mem.ReadLE(int(c.SP)) - c.OffsetPC)
+		c.SP += 2
+		if c.calls.Enabled {
+			c.calls.Return(int(c.SP))
+		}
+	}
+}
+
+func (c *CPU) Flow(addr int) rcs.Flow {
+	switch c.mem.Peek(addr) {
+	case OpCall:
+		return rcs.FlowCall
+	case OpReturn:
+		return rcs.FlowReturn
+	}
+	return rcs.FlowNext
+}
+
+func (c *CPU) StackAddr() int {
+	return int(c.SP)
+}
+
+func (c *CPU) CallStack() *rcs.CallStack {
+	return &c.calls
+}
+
+// ScanStack finds return addresses between SP and $ff00 that follow an
+// OpCall.
+func (c *CPU) ScanStack() []rcs.Frame {
+	var frames []rcs.Frame
+	for sp := int(c.SP); sp < 0xff00; sp += 2 {
+		ret := c.mem.PeekLE(sp)
+		if c.mem.Peek(ret-3) != OpCall {
+			continue
+		}
+		frames = append([]rcs.Frame{{
+			Entry:  c.mem.PeekLE(ret - 2),
+			Return: ret,
+			SP:     sp,
+		}}, frames...)
+	}
+	return frames
 }
 
 func (c *CPU) String() string {
@@ -95,3 +162,74 @@ func formatter() rcs.CodeFormatter {
 func (c *CPU) NewDisassembler() *rcs.Disassembler {
 	return rcs.NewDisassembler(c.mem, reader, formatter())
 }
+
+// Assemble returns the code for an instruction in the form produced by the
+// disassembler, such as "i10 $44". The address is not used.
+func (c *CPU) Assemble(addr int, line string) ([]uint8, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || len(fields) > 2 || !strings.HasPrefix(fields[0], "i") {
+		return nil, fmt.Errorf("invalid instruction: %v", line)
+	}
+	opcode, err := strconv.ParseUint(fields[0][1:], 16, 8)
+	if err != nil || opcode>>4 > 2 {
+		return nil, fmt.Errorf("invalid instruction: %v", fields[0])
+	}
+	code := []uint8{uint8(opcode)}
+	argN := int(opcode >> 4)
+	if argN == 0 {
+		if len(fields) != 1 {
+			return nil, fmt.Errorf("unexpected operand: %v", fields[1])
+		}
+		return code, nil
+	}
+	if len(fields) != 2 || !strings.HasPrefix(fields[1], "$") {
+		return nil, fmt.Errorf("missing operand")
+	}
+	value, err := strconv.ParseUint(fields[1][1:], 16, 8*argN)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %v", fields[1])
+	}
+	for i := 0; i < argN; i++ {
+		code = append(code, uint8(value>>uint(8*i)))
+	}
+	return code, nil
+}
+
+// Syntax is a source code format for the mock processor. Opcodes from $30
+// and up are not valid instructions.
+var Syntax = &rcs.Syntax{
+	Name: "mock",
+	Decode: func(mem *rcs.Memory, addr int) (rcs.Instr, bool) {
+		dasm := rcs.NewDisassembler(mem, reader, formatter())
+		dasm.SetPC(addr)
+		s := dasm.NextStmt()
+		if s.Op[0] == '?' {
+			return rcs.Instr{}, false
+		}
+		in := rcs.Instr{Addr: addr, Op: s.Op, Bytes: s.Bytes, Next: true}
+		switch s.Bytes[0] {
+		case OpCall:
+			in.Calls = []int{int(s.Bytes[1]) | int(s.Bytes[2])<<8}
+		case OpReturn:
+			in.Next = false
+		}
+		return in, true
+	},
+	Format: func(in rcs.Instr, label func(int) (string, bool)) (string, bool) {
+		if in.Bytes[0] == OpCall {
+			if name, ok := label(in.Calls[0]); ok {
+				return fmt.Sprintf("i%02x %v", in.Bytes[0], name), true
+			}
+		}
+		return in.Op, true
+	},
+	Org:    "org $%04x",
+	Equate: "%v = $%04x",
+	Label:  "%v:",
+	Byte:   ".byte",
+	Word:   ".word",
+}
+
+func (c *CPU) Syntaxes() []*rcs.Syntax {
+	return []*rcs.Syntax{Syntax}
+}
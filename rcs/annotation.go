@@ -0,0 +1,172 @@
+package rcs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DataKind is the type of values found in a region of memory.
+type DataKind int
+
+const (
+	Code     DataKind = iota // instructions, the default
+	Bytes                    // 8-bit values
+	Words                    // 16-bit little-endian values
+	Text                     // characters
+	Pointers                 // 16-bit little-endian addresses
+)
+
+var dataKindNames = map[DataKind]string{
+	Code:     "code",
+	Bytes:    "bytes",
+	Words:    "words",
+	Text:     "text",
+	Pointers: "pointers",
+}
+
+func (k DataKind) String() string {
+	return dataKindNames[k]
+}
+
+// ParseDataKind returns the kind of data with the given name.
+func ParseDataKind(name string) (DataKind, bool) {
+	for k, v := range dataKindNames {
+		if v == name {
+			return k, true
+		}
+	}
+	return Code, false
+}
+
+// DataRegion is a range of memory, inclusive, that contains data instead
+// of instructions.
+type DataRegion struct {
+	Start int
+	End   int
+	Kind  DataKind
+}
+
+// Annotations are notes and data regions recorded for memory while
+// reverse engineering.
+type Annotations struct {
+	Notes   map[int]string
+	Regions []DataRegion // sorted by start address and never overlapping
+}
+
+func NewAnnotations() *Annotations {
+	return &Annotations{
+		Notes: make(map[int]string),
+	}
+}
+
+// Mark sets the kind of data found from start to end, inclusive. Marking
+// a range as Code removes it from any data region.
+func (a *Annotations) Mark(start int, end int, kind DataKind) {
+	regions := make([]DataRegion, 0, len(a.Regions)+2)
+	for _, r := range a.Regions {
+		if r.End < start || r.Start > end {
+			regions = append(regions, r)
+			continue
+		}
+		if r.Start < start {
+			regions = append(regions, DataRegion{r.Start, start - 1, r.Kind})
+		}
+		if r.End > end {
+			regions = append(regions, DataRegion{end + 1, r.End, r.Kind})
+		}
+	}
+	if kind != Code {
+		regions = append(regions, DataRegion{start, end, kind})
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return regions[i].Start < regions[j].Start
+	})
+	a.Regions = regions
+}
+
+// Note returns the notes for the n bytes starting at the address joined
+// with "; ". Notes found after the first byte, such as one on the operand
+// of an instruction, start with their address.
+func (a *Annotations) Note(addr int, n int) string {
+	return joinNotes(a.Notes, addr, n)
+}
+
+func joinNotes(notes map[int]string, addr int, n int) string {
+	var list []string
+	for i := 0; i < n; i++ {
+		note, ok := notes[addr+i]
+		if !ok {
+			continue
+		}
+		if i > 0 {
+			note = fmt.Sprintf("$%04x %v", addr+i, note)
+		}
+		list = append(list, note)
+	}
+	return strings.Join(list, "; ")
+}
+
+// Region returns the data region that contains the address.
+func (a *Annotations) Region(addr int) (DataRegion, bool) {
+	i := sort.Search(len(a.Regions), func(i int) bool {
+		return a.Regions[i].End >= addr
+	})
+	if i < len(a.Regions) && a.Regions[i].Start <= addr {
+		return a.Regions[i], true
+	}
+	return DataRegion{}, false
+}
+
+// readData reads values from a data region until the end of the region,
+// the next address with a note, or the limit of values for one statement.
+func (d *Disassembler) readData(e StmtEval, r DataRegion) {
+	s := e.Stmt
+	s.Addr = e.Ptr.Addr()
+	more := func(max int) bool {
+		addr := e.Ptr.Addr()
+		if len(s.Bytes) >= max || addr > r.End {
+			return false
+		}
+		_, note := d.Annotations.Notes[addr]
+		return len(s.Bytes) == 0 || !note
+	}
+	switch r.Kind {
+	case Words, Pointers:
+		if r.End > s.Addr {
+			v := e.Ptr.FetchLE()
+			s.Bytes = append(s.Bytes, uint8(v), uint8(v>>8))
+			op := ".word"
+			if r.Kind == Pointers {
+				op = ".addr"
+			}
+			s.Op = fmt.Sprintf("%v $%04x", op, v)
+			return
+		}
+		// an odd byte at the end of the region is shown as a byte
+	case Text:
+		decode := d.Decoder
+		if decode == nil {
+			decode = ASCIIDecoder
+		}
+		var text strings.Builder
+		for more(8) {
+			v := e.Ptr.Fetch()
+			s.Bytes = append(s.Bytes, v)
+			ch, printable := decode(v)
+			if !printable {
+				ch = '.'
+			}
+			text.WriteRune(ch)
+		}
+		s.Op = fmt.Sprintf(".text %q", text.String())
+		return
+	}
+	values := make([]string, 0, 4)
+	for more(4) {
+		v := e.Ptr.Fetch()
+		s.Bytes = append(s.Bytes, v)
+		values = append(values, X8(v))
+	}
+	s.Op = ".byte " + strings.Join(values, ",")
+}
@@ -0,0 +1,98 @@
+package rcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAnnotationsMark(t *testing.T) {
+	tests := []struct {
+		name  string
+		marks []DataRegion
+		want  []DataRegion
+	}{
+		{"one", []DataRegion{{0x10, 0x1f, Bytes}},
+			[]DataRegion{{0x10, 0x1f, Bytes}}},
+		{"sorted", []DataRegion{{0x20, 0x2f, Text}, {0x10, 0x1f, Bytes}},
+			[]DataRegion{{0x10, 0x1f, Bytes}, {0x20, 0x2f, Text}}},
+		{"split", []DataRegion{{0x10, 0x1f, Bytes}, {0x14, 0x17, Words}},
+			[]DataRegion{{0x10, 0x13, Bytes}, {0x14, 0x17, Words}, {0x18, 0x1f, Bytes}}},
+		{"overlap", []DataRegion{{0x10, 0x1f, Bytes}, {0x18, 0x27, Text}},
+			[]DataRegion{{0x10, 0x17, Bytes}, {0x18, 0x27, Text}}},
+		{"replace", []DataRegion{{0x10, 0x1f, Bytes}, {0x08, 0x2f, Pointers}},
+			[]DataRegion{{0x08, 0x2f, Pointers}}},
+		{"code", []DataRegion{{0x10, 0x1f, Bytes}, {0x12, 0x1d, Code}},
+			[]DataRegion{{0x10, 0x11, Bytes}, {0x1e, 0x1f, Bytes}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a := NewAnnotations()
+			for _, r := range test.marks {
+				a.Mark(r.Start, r.End, r.Kind)
+			}
+			if !reflect.DeepEqual(a.Regions, test.want) {
+				t.Errorf("\n have: %v \n want: %v", a.Regions, test.want)
+			}
+		})
+	}
+}
+
+func TestAnnotationsRegion(t *testing.T) {
+	a := NewAnnotations()
+	a.Mark(0x10, 0x1f, Bytes)
+	a.Mark(0x30, 0x3f, Text)
+	tests := []struct {
+		addr int
+		ok   bool
+		kind DataKind
+	}{
+		{0x0f, false, Code},
+		{0x10, true, Bytes},
+		{0x1f, true, Bytes},
+		{0x20, false, Code},
+		{0x35, true, Text},
+		{0x40, false, Code},
+	}
+	for _, test := range tests {
+		r, ok := a.Region(test.addr)
+		if ok != test.ok || r.Kind != test.kind {
+			t.Errorf("$%04x: have %v %v, want %v %v", test.addr, r.Kind, ok, test.kind, test.ok)
+		}
+	}
+}
+
+func TestAnnotationsNote(t *testing.T) {
+	a := NewAnnotations()
+	a.Notes[0x10] = "start"
+	a.Notes[0x12] = "operand"
+	a.Notes[0x13] = "high byte"
+	tests := []struct {
+		addr int
+		n    int
+		want string
+	}{
+		{0x10, 1, "start"},
+		{0x10, 3, "start; $0012 operand"},
+		{0x11, 2, "$0012 operand"},
+		{0x12, 2, "operand; $0013 high byte"},
+		{0x14, 3, ""},
+	}
+	for _, test := range tests {
+		have := a.Note(test.addr, test.n)
+		if have != test.want {
+			t.Errorf("$%04x %v: have %q, want %q", test.addr, test.n, have, test.want)
+		}
+	}
+}
+
+func TestParseDataKind(t *testing.T) {
+	for kind, name := range dataKindNames {
+		have, ok := ParseDataKind(name)
+		if !ok || have != kind {
+			t.Errorf("%v: have %v", name, have)
+		}
+	}
+	if _, ok := ParseDataKind("floats"); ok {
+		t.Errorf("expected invalid kind")
+	}
+}
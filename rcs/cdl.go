@@ -0,0 +1,124 @@
+package rcs
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// CDLFlag records how a byte in memory was used during emulation.
+type CDLFlag uint8
+
+const (
+	CDLOpcode  CDLFlag = 1 << iota // fetched as the first byte of an instruction
+	CDLOperand                     // fetched as another byte of an instruction
+	CDLRead                        // read as data
+	CDLWrite                       // written
+	CDLTarget                      // executed after a jump, branch, call or interrupt
+)
+
+/*
+CDL is a code/data log that records, for every address in memory, how the
+byte at that address was used while running. Set the CDL field of Memory
+to start logging:
+
+	mem.CDL = rcs.NewCDL(mem.MaxAddr + 1)
+
+Reads and writes are logged by Memory. CPUs log instructions by calling
+Memory.Fetch instead of Read. An address that is fetched as an opcode
+but does not follow the last byte fetched is logged as a jump target.
+
+Flags are logged by address and not by bank. The log is saved as one
+byte of flags per address, which is similar to the CDL files of other
+emulators, and logs from more than one run can be merged.
+*/
+type CDL struct {
+	Flags []CDLFlag
+	next  int // address that follows the last byte fetched
+}
+
+func NewCDL(size int) *CDL {
+	return &CDL{
+		Flags: make([]CDLFlag, size),
+		next:  -1,
+	}
+}
+
+func (c *CDL) fetch(addr int, opcode bool) {
+	if !opcode {
+		c.Flags[addr] |= CDLOperand
+	} else if addr != c.next {
+		c.Flags[addr] |= CDLOpcode | CDLTarget
+	} else {
+		c.Flags[addr] |= CDLOpcode
+	}
+	c.next = addr + 1
+}
+
+// Merge adds the flags found in another log of the same size.
+func (c *CDL) Merge(o *CDL) error {
+	if len(o.Flags) != len(c.Flags) {
+		return fmt.Errorf("expected size %v but was %v", len(c.Flags), len(o.Flags))
+	}
+	for i, f := range o.Flags {
+		c.Flags[i] |= f
+	}
+	return nil
+}
+
+// Clear removes all flags.
+func (c *CDL) Clear() {
+	for i := range c.Flags {
+		c.Flags[i] = 0
+	}
+	c.next = -1
+}
+
+// Usage counts the bytes from start to end, inclusive, that were executed
+// as code, only used as data, or not used at all.
+func (c *CDL) Usage(start int, end int) (code int, data int, unused int) {
+	for addr := start; addr <= end; addr++ {
+		f := c.Flags[addr]
+		switch {
+		case f&(CDLOpcode|CDLOperand) != 0:
+			code++
+		case f != 0:
+			data++
+		default:
+			unused++
+		}
+	}
+	return
+}
+
+// Targets returns the addresses of all jump targets in order.
+func (c *CDL) Targets() []int {
+	var addrs []int
+	for addr, f := range c.Flags {
+		if f&CDLTarget != 0 {
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs
+}
+
+// Save writes the log to a file.
+func (c *CDL) Save(filename string) error {
+	data := make([]uint8, len(c.Flags))
+	for i, f := range c.Flags {
+		data[i] = uint8(f)
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+// LoadCDL reads a log from a file.
+func LoadCDL(filename string) (*CDL, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCDL(len(data))
+	for i, v := range data {
+		c.Flags[i] = CDLFlag(v)
+	}
+	return c, nil
+}
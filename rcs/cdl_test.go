@@ -0,0 +1,88 @@
+package rcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCDL(t *testing.T) {
+	mem := NewMemory(1, 0x10)
+	mem.MapRAM(0, make([]uint8, 0x10))
+	mem.CDL = NewCDL(0x10)
+	mem.Fetch(0x0, true)  // first instruction
+	mem.Fetch(0x1, false) // operand
+	mem.Fetch(0x2, true)  // next instruction
+	mem.Fetch(0x8, true)  // jump target
+	mem.Fetch(0x9, false)
+	mem.Read(0x9)
+	mem.Read(0xa)
+	mem.Write(0xb, 1)
+	mem.Peek(0xc)
+
+	want := []CDLFlag{
+		CDLOpcode | CDLTarget,
+		CDLOperand,
+		CDLOpcode,
+		0, 0, 0, 0, 0,
+		CDLOpcode | CDLTarget,
+		CDLOperand | CDLRead,
+		CDLRead,
+		CDLWrite,
+		0, 0, 0, 0,
+	}
+	if have := mem.CDL.Flags; !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %v \n want: %v", have, want)
+	}
+	if have, want := mem.CDL.Targets(), []int{0x0, 0x8}; !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %v \n want: %v", have, want)
+	}
+	code, data, unused := mem.CDL.Usage(0, 0xf)
+	if code != 5 || data != 2 || unused != 9 {
+		t.Errorf("usage: code %v, data %v, unused %v", code, data, unused)
+	}
+}
+
+func TestCDLMerge(t *testing.T) {
+	c1 := NewCDL(4)
+	c1.Flags[0] = CDLOpcode
+	c1.Flags[1] = CDLRead
+	c2 := NewCDL(4)
+	c2.Flags[1] = CDLWrite
+	c2.Flags[3] = CDLRead
+	if err := c1.Merge(c2); err != nil {
+		t.Fatal(err)
+	}
+	want := []CDLFlag{CDLOpcode, CDLRead | CDLWrite, 0, CDLRead}
+	if !reflect.DeepEqual(c1.Flags, want) {
+		t.Errorf("\n have: %v \n want: %v", c1.Flags, want)
+	}
+	if err := c1.Merge(NewCDL(8)); err == nil {
+		t.Errorf("expected error on size mismatch")
+	}
+}
+
+func TestCDLSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rcs-cdl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "test.cdl")
+
+	c := NewCDL(4)
+	c.Flags[0] = CDLOpcode | CDLTarget
+	c.Flags[2] = CDLWrite
+	if err := c.Save(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadCDL(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Flags, c.Flags) {
+		t.Errorf("\n have: %v \n want: %v", loaded.Flags, c.Flags)
+	}
+}
@@ -0,0 +1,346 @@
+package rcs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Cheat is a value that is written to memory after each frame. Cheats
+// are used to freeze values, such as the number of lives remaining.
+type Cheat struct {
+	CPU     string // name of the CPU that sees the memory
+	Addr    int
+	Value   uint8
+	Bank    int // bank to write to, or -1 for the selected bank
+	Compare int // only write when memory has this value, or -1 to always write
+	Enabled bool
+	Desc    string
+}
+
+// Apply writes the value to memory if the cheat is enabled and the
+// value in memory matches the compare value, if there is one. The write is
+// not recorded in the code/data log.
+func (c *Cheat) Apply(mem *Memory) {
+	if !c.Enabled {
+		return
+	}
+	if c.Bank >= 0 && c.Bank != mem.Bank() {
+		prev := mem.Bank()
+		mem.SetBank(c.Bank)
+		defer mem.SetBank(prev)
+	}
+	if c.Compare >= 0 && int(mem.Peek(c.Addr)) != c.Compare {
+		return
+	}
+	mem.Poke(c.Addr, c.Value)
+}
+
+func (c *Cheat) String() string {
+	state := "off"
+	if c.Enabled {
+		state = "on"
+	}
+	bank, compare := "-", "-"
+	if c.Bank >= 0 {
+		bank = strconv.Itoa(c.Bank)
+	}
+	if c.Compare >= 0 {
+		compare = X8(uint8(c.Compare))
+	}
+	return strings.TrimSpace(fmt.Sprintf("%v %v %v %v %v %v %v", state, c.CPU,
+		X16(uint16(c.Addr)), X8(c.Value), bank, compare, c.Desc))
+}
+
+// SaveCheats writes cheats to a file, one per line, in the format returned
+// by String.
+func SaveCheats(filename string, cheats []*Cheat) error {
+	var buf bytes.Buffer
+	for _, c := range cheats {
+		buf.WriteString(c.String())
+		buf.WriteString("\n")
+	}
+	return ioutil.WriteFile(filename, buf.Bytes(), 0644)
+}
+
+// LoadCheats reads the cheats written with SaveCheats. There are no
+// cheats, and no error, if the file does not exist.
+func LoadCheats(filename string) ([]*Cheat, error) {
+	data, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cheats []*Cheat
+	for i, line := range strings.Split(string(data), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 0 {
+			continue
+		}
+		c, err := parseCheat(f)
+		if err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", filename, i+1, err)
+		}
+		cheats = append(cheats, c)
+	}
+	return cheats, nil
+}
+
+func parseCheat(f []string) (*Cheat, error) {
+	if len(f) < 6 {
+		return nil, fmt.Errorf("invalid cheat")
+	}
+	c := &Cheat{CPU: f[1], Bank: -1, Compare: -1}
+	switch f[0] {
+	case "on":
+		c.Enabled = true
+	case "off":
+	default:
+		return nil, fmt.Errorf("invalid state: %v", f[0])
+	}
+	addr, err := strconv.ParseUint(strings.TrimPrefix(f[2], "$"), 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address: %v", f[2])
+	}
+	c.Addr = int(addr)
+	value, err := strconv.ParseUint(strings.TrimPrefix(f[3], "$"), 16, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value: %v", f[3])
+	}
+	c.Value = uint8(value)
+	if f[4] != "-" {
+		if c.Bank, err = strconv.Atoi(f[4]); err != nil {
+			return nil, fmt.Errorf("invalid bank: %v", f[4])
+		}
+	}
+	if f[5] != "-" {
+		compare, err := strconv.ParseUint(strings.TrimPrefix(f[5], "$"), 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compare value: %v", f[5])
+		}
+		c.Compare = int(compare)
+	}
+	c.Desc = strings.Join(f[6:], " ")
+	return c, nil
+}
+
+// ImportCheats reads cheats from a MAME cheat file. Both the XML format
+// and the older colon separated format used by cheat.dat and .cht files
+// are supported. Only cheats that write a constant value are imported and
+// the number of other cheats that were skipped is returned. In the older
+// format, only the lines for the named game are used. CPUs are given in
+// the order that MAME numbers them. The imported cheats are not enabled.
+func ImportCheats(r io.Reader, game string, cpus []string) ([]*Cheat, int, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	var cheats []*Cheat
+	var skipped int
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		cheats, skipped, err = importCheatXML(data, cpus)
+	} else {
+		cheats, skipped, err = importCheatDat(data, game, cpus)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	if len(cheats) == 0 {
+		return nil, skipped, fmt.Errorf("no cheats found")
+	}
+	return cheats, skipped, nil
+}
+
+type mameCheatFile struct {
+	Cheats []struct {
+		Desc    string     `xml:"desc,attr"`
+		Params  []struct{} `xml:"parameter"`
+		Scripts []struct {
+			State   string `xml:"state,attr"`
+			Actions []struct {
+				Condition string `xml:"condition,attr"`
+				Text      string `xml:",chardata"`
+			} `xml:"action"`
+		} `xml:"script"`
+	} `xml:"cheat"`
+}
+
+var (
+	// maincpu.pb@4E14=03
+	mameWriteRegex = regexp.MustCompile(`^:?([A-Za-z0-9_]+)\.[pmor]([bw])@(?:0x|\$)?([0-9A-Fa-f]+)=(?:0x|\$)?([0-9A-Fa-f]+)$`)
+	// maincpu.pb@4E14==03
+	mameCompareRegex = regexp.MustCompile(`^:?([A-Za-z0-9_]+)\.[pmor]b@(?:0x|\$)?([0-9A-Fa-f]+)==(?:0x|\$)?([0-9A-Fa-f]+)$`)
+)
+
+func importCheatXML(data []byte, cpus []string) ([]*Cheat, int, error) {
+	var file mameCheatFile
+	if err := xml.Unmarshal(data, &file); err != nil {
+		return nil, 0, err
+	}
+	var cheats []*Cheat
+	skipped := 0
+	for _, mc := range file.Cheats {
+		var imported []*Cheat
+		ok := len(mc.Params) == 0
+		for _, script := range mc.Scripts {
+			if !ok {
+				break
+			}
+			if script.State != "on" && script.State != "run" {
+				continue
+			}
+			for _, action := range script.Actions {
+				text := strings.Join(strings.Fields(action.Text), "")
+				cond := strings.Join(strings.Fields(action.Condition), "")
+				for _, stmt := range strings.Split(text, ",") {
+					cs, valid := mameWrite(stmt, cond, mc.Desc, cpus)
+					if !valid {
+						ok = false
+						break
+					}
+					imported = append(imported, cs...)
+				}
+			}
+		}
+		if !ok || len(imported) == 0 {
+			skipped++
+			continue
+		}
+		cheats = append(cheats, imported...)
+	}
+	return cheats, skipped, nil
+}
+
+// mameWrite converts a MAME action that writes a constant to memory.
+func mameWrite(stmt string, cond string, desc string, cpus []string) ([]*Cheat, bool) {
+	m := mameWriteRegex.FindStringSubmatch(stmt)
+	if m == nil {
+		return nil, false
+	}
+	cpu, ok := mameCPU(m[1], cpus)
+	if !ok {
+		return nil, false
+	}
+	addr, _ := strconv.ParseUint(m[3], 16, 32)
+	value, _ := strconv.ParseUint(m[4], 16, 32)
+	compare := -1
+	if cond != "" {
+		c := mameCompareRegex.FindStringSubmatch(cond)
+		if c == nil || c[1] != m[1] || !strings.EqualFold(c[2], m[3]) || m[2] != "b" {
+			return nil, false
+		}
+		v, _ := strconv.ParseUint(c[3], 16, 8)
+		compare = int(v)
+	}
+	newCheat := func(addr int, value uint8) *Cheat {
+		return &Cheat{CPU: cpu, Addr: addr, Value: value, Bank: -1,
+			Compare: compare, Desc: desc}
+	}
+	if m[2] == "b" {
+		if value > 0xff {
+			return nil, false
+		}
+		return []*Cheat{newCheat(int(addr), uint8(value))}, true
+	}
+	if value > 0xffff {
+		return nil, false
+	}
+	return []*Cheat{
+		newCheat(int(addr), uint8(value)),
+		newCheat(int(addr)+1, uint8(value>>8)),
+	}, true
+}
+
+// mameCPU returns the name of the CPU for a MAME device tag. The tag can
+// be the name of the CPU itself.
+func mameCPU(tag string, cpus []string) (string, bool) {
+	for _, name := range cpus {
+		if name == tag {
+			return name, true
+		}
+	}
+	index := map[string]int{"maincpu": 0, "sub": 1, "sub2": 2}
+	if i, ok := index[tag]; ok && i < len(cpus) {
+		return cpus[i], true
+	}
+	return "", false
+}
+
+// importCheatDat reads cheats in either of these formats:
+//
+//	pacman:0:4e14:03:000:Infinite Lives
+//	:pacman:00000000:4E14:00000003:000000FF:Infinite Lives
+//
+// The first is game, CPU, address, value, type, and description. The
+// second is game, type, address, value, mask, description, and an
+// optional comment. Only the type that writes a value each frame is
+// imported.
+func importCheatDat(data []byte, game string, cpus []string) ([]*Cheat, int, error) {
+	var cheats []*Cheat
+	skipped := 0
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' || line[0] == '[' {
+			continue
+		}
+		f := strings.Split(strings.TrimPrefix(line, ":"), ":")
+		if len(f) < 6 || f[0] != game {
+			continue
+		}
+		c, ok := datCheat(f, line[0] == ':', cpus)
+		if !ok {
+			skipped++
+			continue
+		}
+		cheats = append(cheats, c)
+	}
+	return cheats, skipped, s.Err()
+}
+
+func datCheat(f []string, newFormat bool, cpus []string) (*Cheat, bool) {
+	hex := func(s string) (int, bool) {
+		v, err := strconv.ParseUint(s, 16, 32)
+		return int(v), err == nil
+	}
+	var cpu, addr, value, kind int
+	var ok [4]bool
+	if newFormat {
+		kind, ok[0] = hex(f[1])
+		addr, ok[1] = hex(f[2])
+		value, ok[2] = hex(f[3])
+		var mask int
+		mask, ok[3] = hex(f[4])
+		if mask != 0xff {
+			return nil, false
+		}
+	} else {
+		cpu, ok[0] = hex(f[1])
+		addr, ok[1] = hex(f[2])
+		value, ok[2] = hex(f[3])
+		kind, ok[3] = hex(f[4])
+	}
+	if !ok[0] || !ok[1] || !ok[2] || !ok[3] {
+		return nil, false
+	}
+	if kind != 0 || cpu >= len(cpus) || value > 0xff {
+		return nil, false
+	}
+	return &Cheat{
+		CPU:     cpus[cpu],
+		Addr:    addr,
+		Value:   uint8(value),
+		Bank:    -1,
+		Compare: -1,
+		Desc:    f[5],
+	}, true
+}
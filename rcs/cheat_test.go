@@ -0,0 +1,199 @@
+package rcs
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheatApply(t *testing.T) {
+	mem := NewMemory(2, 0x100)
+	ram0 := make([]uint8, 0x100)
+	ram1 := make([]uint8, 0x100)
+	mem.SetBank(1)
+	mem.MapRAM(0, ram1)
+	mem.SetBank(0)
+	mem.MapRAM(0, ram0)
+
+	tests := []struct {
+		name  string
+		cheat Cheat
+		ram   []uint8
+		want  uint8
+	}{
+		{"write", Cheat{Addr: 0x10, Value: 5, Bank: -1, Compare: -1, Enabled: true}, ram0, 5},
+		{"disabled", Cheat{Addr: 0x10, Value: 5, Bank: -1, Compare: -1}, ram0, 1},
+		{"compare", Cheat{Addr: 0x10, Value: 5, Bank: -1, Compare: 1, Enabled: true}, ram0, 5},
+		{"compare no match", Cheat{Addr: 0x10, Value: 5, Bank: -1, Compare: 2, Enabled: true}, ram0, 1},
+		{"bank", Cheat{Addr: 0x10, Value: 5, Bank: 1, Compare: -1, Enabled: true}, ram1, 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ram0[0x10], ram1[0x10] = 1, 1
+			test.cheat.Apply(mem)
+			if test.ram[0x10] != test.want {
+				t.Errorf("\n have: %v \n want: %v", test.ram[0x10], test.want)
+			}
+			if mem.Bank() != 0 {
+				t.Errorf("bank not restored: %v", mem.Bank())
+			}
+		})
+	}
+}
+
+type cheatCPU struct {
+	mem *Memory
+}
+
+func (c *cheatCPU) Next()           {}
+func (c *cheatCPU) PC() int         { return 0 }
+func (c *cheatCPU) SetPC(int)       {}
+func (c *cheatCPU) Offset() int     { return 0 }
+func (c *cheatCPU) Memory() *Memory { return c.mem }
+
+func TestCheatApplyCDL(t *testing.T) {
+	mem := NewMemory(1, 0x100)
+	ram := make([]uint8, 0x100)
+	mem.MapRAM(0, ram)
+	mem.CDL = NewCDL(0x100)
+	m := &Mach{
+		Comps: []Component{
+			NewComponent("cpu", "cpu", "mem", &cheatCPU{mem: mem}),
+		},
+		Cheats: []*Cheat{
+			{CPU: "cpu", Addr: 0x10, Value: 5, Bank: -1, Compare: -1, Enabled: true},
+		},
+	}
+	m.Init()
+	m.applyCheats()
+	if ram[0x10] != 5 {
+		t.Errorf("cheat not applied: %v", ram[0x10])
+	}
+	if flags := mem.CDL.Flags[0x10]; flags != 0 {
+		t.Errorf("have flags %02x, want 00", flags)
+	}
+}
+
+func TestCheatSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "retro-cs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "cheats")
+
+	want := []*Cheat{
+		{CPU: "cpu", Addr: 0x4e14, Value: 5, Bank: -1, Compare: -1, Enabled: true, Desc: "Infinite Lives"},
+		{CPU: "cpu2", Addr: 0x10, Value: 0xff, Bank: 1, Compare: 3},
+	}
+	if err := SaveCheats(filename, want); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filename)
+	wantText := "on cpu $4e14 $05 - - Infinite Lives\noff cpu2 $0010 $ff 1 $03\n"
+	if string(data) != wantText {
+		t.Errorf("\n have: %q \n want: %q", data, wantText)
+	}
+	have, err := LoadCheats(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %v \n want: %v", have, want)
+	}
+
+	none, err := LoadCheats(filepath.Join(dir, "none"))
+	if none != nil || err != nil {
+		t.Errorf("unexpected result: %v %v", none, err)
+	}
+}
+
+func TestImportCheats(t *testing.T) {
+	cpus := []string{"cpu1", "cpu2", "cpu3"}
+	tests := []struct {
+		name    string
+		in      string
+		want    []string
+		skipped int
+	}{
+		{"xml", `<?xml version="1.0"?>
+<mamecheat version="1">
+  <cheat desc="Infinite Lives">
+    <script state="run">
+      <action>maincpu.pb@4E14=03</action>
+    </script>
+  </cheat>
+  <cheat desc="Sub Score">
+    <script state="on">
+      <action>sub.pw@8000=1234</action>
+    </script>
+  </cheat>
+  <cheat desc="Compare">
+    <script state="run">
+      <action condition="maincpu.pb@4E15 == 01">maincpu.pb@4E15=02</action>
+    </script>
+  </cheat>
+  <cheat desc="Select Level">
+    <parameter min="1" max="20" step="1"/>
+    <script state="run">
+      <action>maincpu.pb@4E13=param</action>
+    </script>
+  </cheat>
+  <cheat desc="Temp">
+    <script state="run">
+      <action>temp0=maincpu.pb@4E13</action>
+    </script>
+  </cheat>
+</mamecheat>
+`, []string{
+			"off cpu1 $4e14 $03 - - Infinite Lives",
+			"off cpu2 $8000 $34 - - Sub Score",
+			"off cpu2 $8001 $12 - - Sub Score",
+			"off cpu1 $4e15 $02 - $01 Compare",
+		}, 2},
+		{"dat", `
+; comment
+galaga:0:8AE0:05:000:Other Game
+pacman:0:4E14:03:000:Infinite Lives
+pacman:1:4E15:03:000:Second CPU
+pacman:0:4E16:03:001:Unknown Type
+`, []string{
+			"off cpu1 $4e14 $03 - - Infinite Lives",
+			"off cpu2 $4e15 $03 - - Second CPU",
+		}, 1},
+		{"dat new", `
+:pacman:00000000:4E14:00000003:000000FF:Infinite Lives:Comment
+:pacman:00000000:4E15:00000003:0000000F:Partial Mask
+`, []string{
+			"off cpu1 $4e14 $03 - - Infinite Lives",
+		}, 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cheats, skipped, err := ImportCheats(strings.NewReader(test.in), "pacman", cpus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var have []string
+			for _, c := range cheats {
+				have = append(have, c.String())
+			}
+			if !reflect.DeepEqual(have, test.want) {
+				t.Errorf("\n have: \n%v \n want: \n%v", strings.Join(have, "\n"), strings.Join(test.want, "\n"))
+			}
+			if skipped != test.skipped {
+				t.Errorf("skipped %v, want %v", skipped, test.skipped)
+			}
+		})
+	}
+}
+
+func TestImportCheatsNone(t *testing.T) {
+	_, _, err := ImportCheats(strings.NewReader("galaga:0:8AE0:05:000:Other\n"), "pacman", []string{"cpu"})
+	if err == nil || err.Error() != "no cheats found" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -33,6 +33,108 @@ type CPUDisassembler interface {
 	NewDisassembler() *Disassembler
 }
 
+// CPUAssembler assembles a single instruction for CPUs that support this
+// method. The address is where the instruction will be stored.
+type CPUAssembler interface {
+	Assemble(addr int, line string) ([]uint8, error)
+}
+
+// CPUCycles is implemented by CPUs that count the clock cycles used as
+// instructions are executed.
+type CPUCycles interface {
+	Cycles() uint64
+}
+
+// Flow is the effect an instruction has on the call stack.
+type Flow int
+
+const (
+	FlowNext   Flow = iota // does not call or return
+	FlowCall               // calls a subroutine: JSR, CALL, RST
+	FlowReturn             // returns from a subroutine or interrupt: RTS, RTI, RET
+)
+
+// CPUFlow is implemented by CPUs that can classify instructions by their
+// effect on the call stack. This is used by the monitor to step over
+// subroutine calls and to run until the current subroutine returns.
+//
+// Flow classifies the instruction at addr by its opcode only. Conditional
+// calls and returns are classified the same as unconditional ones even
+// if the branch would not be taken. Memory should be read with Peek.
+//
+// StackAddr is the address that the stack pointer refers to. The stack is
+// expected to grow downward.
+type CPUFlow interface {
+	Flow(addr int) Flow
+	StackAddr() int
+}
+
+// Frame is an entry in a call stack.
+type Frame struct {
+	Entry     int  // address of the subroutine or interrupt handler
+	Return    int  // address where execution continues after the return
+	SP        int  // stack address after the return address was pushed
+	Interrupt bool // entered by an interrupt instead of a call
+}
+
+// maxFrames is the maximum number of frames kept in a CallStack. Once
+// exceeded, the outermost frames are discarded.
+const maxFrames = 256
+
+// CallStack tracks subroutine calls and interrupts as they are executed.
+// CPUs should only record calls and returns when Enabled is set.
+//
+// Frames are matched to returns by the stack pointer instead of by
+// counting. A frame is discarded when the stack pointer moves above it,
+// or when a new call pushes a return address at or above it. This keeps
+// the call stack correct when a program discards a return address by
+// manipulating the stack directly.
+type CallStack struct {
+	Enabled bool
+	Frames  []Frame // outermost frame first
+}
+
+// Call records a call or interrupt.
+func (c *CallStack) Call(f Frame) {
+	c.unwind(f.SP + 1)
+	if len(c.Frames) >= maxFrames {
+		c.Frames = append(c.Frames[:0], c.Frames[1:]...)
+	}
+	c.Frames = append(c.Frames, f)
+}
+
+// Return records a return from a subroutine or interrupt. The value of sp
+// is the stack address after the return address has been pulled.
+func (c *CallStack) Return(sp int) {
+	c.unwind(sp)
+}
+
+// Reset removes all frames.
+func (c *CallStack) Reset() {
+	c.Frames = c.Frames[:0]
+}
+
+// unwind removes all frames that are below the stack address sp.
+func (c *CallStack) unwind(sp int) {
+	n := len(c.Frames)
+	for n > 0 && c.Frames[n-1].SP < sp {
+		n--
+	}
+	c.Frames = c.Frames[:n]
+}
+
+// CPUCallStack is implemented by CPUs that can provide a backtrace.
+//
+// CallStack returns the frames recorded during execution. ScanStack is a
+// fallback for when tracking was not enabled. It reads memory upward from
+// the stack pointer and returns a frame for each value that looks like a
+// return address from a call instruction. The results are a guess and
+// interrupt frames are not found.
+type CPUCallStack interface {
+	CallStack() *CallStack
+	ScanStack() []Frame
+}
+
 // Stmt represents a single statement in a disassembly.
 type Stmt struct {
 	Addr    int     // Address of the instruction
@@ -51,10 +153,12 @@ type CodeReader func(StmtEval)
 type CodeFormatter func(Stmt) string
 
 type Disassembler struct {
-	mem    *Memory
-	ptr    *Pointer
-	read   CodeReader
-	format CodeFormatter
+	Annotations *Annotations // notes and data regions, if any
+	Decoder     CharDecoder  // for text regions, ASCII if nil
+	mem         *Memory
+	ptr         *Pointer
+	read        CodeReader
+	format      CodeFormatter
 }
 
 type StmtEval struct {
@@ -62,10 +166,15 @@ type StmtEval struct {
 	Stmt *Stmt
 }
 
+// NewDisassembler creates a disassembler for the code found in mem. Memory
+// is accessed with Peek so that disassembling does not cause any side
+// effects.
 func NewDisassembler(mem *Memory, r CodeReader, f CodeFormatter) *Disassembler {
+	ptr := NewPointer(mem)
+	ptr.Peeking = true
 	return &Disassembler{
 		mem:    mem,
-		ptr:    NewPointer(mem),
+		ptr:    ptr,
 		read:   r,
 		format: f,
 	}
@@ -78,7 +187,16 @@ func (d *Disassembler) NextStmt() Stmt {
 			Bytes: make([]byte, 0, 0),
 		},
 	}
-	d.read(eval)
+	if d.Annotations == nil {
+		d.read(eval)
+		return *eval.Stmt
+	}
+	if r, ok := d.Annotations.Region(d.ptr.Addr()); ok {
+		d.readData(eval, r)
+	} else {
+		d.read(eval)
+	}
+	eval.Stmt.Comment = d.Annotations.Note(eval.Stmt.Addr, len(eval.Stmt.Bytes))
 	return *eval.Stmt
 }
 
@@ -108,5 +226,9 @@ func FormatStmt(s Stmt, options FormatOptions) string {
 		format = "%v"
 	}
 	sbytes := fmt.Sprintf(format, strings.Join(bytes, " "))
-	return fmt.Sprintf("$%04x:  %s  %s", s.Addr, sbytes, s.Op)
+	text := fmt.Sprintf("$%04x:  %s  %s", s.Addr, sbytes, s.Op)
+	if s.Comment != "" {
+		text += "  ; " + s.Comment
+	}
+	return text
 }
@@ -0,0 +1,56 @@
+package rcs
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCallStack(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(*CallStack)
+		want []Frame
+	}{
+		{"call", func(c *CallStack) {
+			c.Call(Frame{Entry: 0x1000, SP: 0x1fd})
+			c.Call(Frame{Entry: 0x2000, SP: 0x1fb})
+		}, []Frame{{Entry: 0x1000, SP: 0x1fd}, {Entry: 0x2000, SP: 0x1fb}}},
+		{"return", func(c *CallStack) {
+			c.Call(Frame{Entry: 0x1000, SP: 0x1fd})
+			c.Call(Frame{Entry: 0x2000, SP: 0x1fb})
+			c.Return(0x1fd)
+		}, []Frame{{Entry: 0x1000, SP: 0x1fd}}},
+		{"return all", func(c *CallStack) {
+			c.Call(Frame{Entry: 0x1000, SP: 0x1fd})
+			c.Call(Frame{Entry: 0x2000, SP: 0x1fb})
+			c.Return(0x1ff)
+		}, []Frame{}},
+		{"discarded return address", func(c *CallStack) {
+			c.Call(Frame{Entry: 0x1000, SP: 0x1fd})
+			c.Call(Frame{Entry: 0x2000, SP: 0x1fb})
+			c.Call(Frame{Entry: 0x3000, SP: 0x1fb})
+		}, []Frame{{Entry: 0x1000, SP: 0x1fd}, {Entry: 0x3000, SP: 0x1fb}}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := &CallStack{Frames: []Frame{}}
+			test.fn(c)
+			if !reflect.DeepEqual(c.Frames, test.want) {
+				t.Errorf("\n have: %+v \n want: %+v", c.Frames, test.want)
+			}
+		})
+	}
+}
+
+func TestCallStackMax(t *testing.T) {
+	c := &CallStack{}
+	for i := 0; i < maxFrames+1; i++ {
+		c.Call(Frame{Entry: i, SP: 0xffff - i})
+	}
+	if len(c.Frames) != maxFrames {
+		t.Fatalf("have %v frames, want %v", len(c.Frames), maxFrames)
+	}
+	if c.Frames[0].Entry != 1 {
+		t.Errorf("outermost frame: have %v, want 1", c.Frames[0].Entry)
+	}
+}
@@ -54,12 +54,12 @@ func (c *CPU) loadIndirectX() uint8 {
 }
 
 func (c *CPU) loadIndirectY() uint8 {
-	c.addrLoad = c.mem.ReadLE(int(c.fetch()))
-	indexed := c.addrLoad + int(c.Y)
-	if c.addrLoad&0xff00 != indexed&0xff00 {
+	base := c.mem.ReadLE(int(c.fetch()))
+	c.addrLoad = base + int(c.Y)
+	if base&0xff00 != c.addrLoad&0xff00 {
 		c.pageCross = true
 	}
-	return c.mem.Read(indexed)
+	return c.mem.Read(c.addrLoad)
 }
 
 func (c *CPU) loadZeroPage() uint8 {
@@ -0,0 +1,168 @@
+package m6502
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// asmTable maps an instruction and addressing mode back to its opcode. It
+// is built from the disassembly table so that the assembler accepts
+// exactly what the disassembler produces.
+var (
+	asmTable = make(map[string]map[mode]uint8)
+	asmOnce  sync.Once
+)
+
+func initAsmTable() {
+	for opcode := 0; opcode <= 0xff; opcode++ {
+		op, ok := dasmTable[uint8(opcode)]
+		if !ok {
+			continue
+		}
+		modes, ok := asmTable[op.inst]
+		if !ok {
+			modes = make(map[mode]uint8)
+			asmTable[op.inst] = modes
+		}
+		// the lowest opcode is used when more than one matches
+		if _, exists := modes[op.mode]; !exists {
+			modes[op.mode] = uint8(opcode)
+		}
+	}
+}
+
+// Assemble returns the machine code for one instruction written in the
+// syntax used by the disassembler, such as "lda #$40" or "bne $c010".
+// The address is where the instruction will be stored and is used to
+// compute the offset for branches. Values are hexadecimal with a "$"
+// prefix or decimal. Values with three or four hexadecimal digits use
+// absolute addressing even when they would fit in the zero page.
+func Assemble(addr int, line string) ([]uint8, error) {
+	asmOnce.Do(initAsmTable)
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no instruction")
+	}
+	inst := fields[0]
+	operand := strings.Join(fields[1:], "")
+	modes, ok := asmTable[inst]
+	if !ok {
+		return nil, fmt.Errorf("invalid instruction: %v", inst)
+	}
+	if _, ok := modes[relative]; ok {
+		target, _, err := parseOperand(operand)
+		if err != nil {
+			return nil, err
+		}
+		offset := target - (addr + 2)
+		if offset < -128 || offset > 127 {
+			return nil, fmt.Errorf("branch out of range: %v", operand)
+		}
+		return []uint8{modes[relative], uint8(offset)}, nil
+	}
+
+	mode, value, err := parseMode(operand)
+	if err != nil {
+		return nil, err
+	}
+	// promote zero page modes when the instruction does not have them
+	if _, ok := modes[mode]; !ok {
+		switch mode {
+		case zeroPage:
+			mode = absolute
+		case zeroPageX:
+			mode = absoluteX
+		case zeroPageY:
+			mode = absoluteY
+		case implied:
+			mode = accumulator
+		}
+	}
+	opcode, ok := modes[mode]
+	if !ok {
+		return nil, fmt.Errorf("invalid addressing mode: %v", strings.TrimSpace(line))
+	}
+	switch operandLengths[mode] {
+	case 1:
+		if value > 0xff {
+			return nil, fmt.Errorf("invalid value: %v", operand)
+		}
+		return []uint8{opcode, uint8(value)}, nil
+	case 2:
+		if value > 0xffff {
+			return nil, fmt.Errorf("invalid value: %v", operand)
+		}
+		return []uint8{opcode, uint8(value), uint8(value >> 8)}, nil
+	}
+	return []uint8{opcode}, nil
+}
+
+// parseMode returns the addressing mode and the value of an operand with
+// the spaces removed.
+func parseMode(operand string) (mode, int, error) {
+	if operand == "" {
+		return implied, 0, nil
+	}
+	if operand == "a" {
+		return accumulator, 0, nil
+	}
+	if strings.HasPrefix(operand, "#") {
+		v, _, err := parseOperand(operand[1:])
+		return immediate, v, err
+	}
+	if strings.HasPrefix(operand, "(") {
+		switch {
+		case strings.HasSuffix(operand, ",x)"):
+			v, _, err := parseOperand(operand[1 : len(operand)-3])
+			return indirectX, v, err
+		case strings.HasSuffix(operand, "),y"):
+			v, _, err := parseOperand(operand[1 : len(operand)-3])
+			return indirectY, v, err
+		case strings.HasSuffix(operand, ")"):
+			v, _, err := parseOperand(operand[1 : len(operand)-1])
+			return indirect, v, err
+		}
+		return 0, 0, fmt.Errorf("invalid operand: %v", operand)
+	}
+	zp, abs := zeroPage, absolute
+	switch {
+	case strings.HasSuffix(operand, ",x"):
+		zp, abs = zeroPageX, absoluteX
+		operand = operand[:len(operand)-2]
+	case strings.HasSuffix(operand, ",y"):
+		zp, abs = zeroPageY, absoluteY
+		operand = operand[:len(operand)-2]
+	}
+	v, wide, err := parseOperand(operand)
+	if err != nil {
+		return 0, 0, err
+	}
+	if wide || v > 0xff {
+		return abs, v, nil
+	}
+	return zp, v, nil
+}
+
+// parseOperand returns the value of a number. Wide is true when a
+// hexadecimal value is written with more than two digits.
+func parseOperand(s string) (int, bool, error) {
+	base, digits := 10, s
+	if strings.HasPrefix(s, "$") {
+		base, digits = 16, s[1:]
+	}
+	v, err := strconv.ParseUint(digits, base, 16)
+	if err != nil || digits == "" {
+		return 0, false, fmt.Errorf("invalid value: %v", s)
+	}
+	return int(v), base == 16 && len(digits) > 2, nil
+}
+
+// Assemble returns the machine code for a 6502 instruction using only the
+// documented opcodes. Each instruction and addressing mode has one opcode
+// so the result is always one to three bytes. See the Assemble function in
+// this package for the syntax.
+func (c *CPU) Assemble(addr int, line string) ([]uint8, error) {
+	return Assemble(addr, line)
+}
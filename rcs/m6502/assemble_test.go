@@ -0,0 +1,92 @@
+package m6502
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/mock"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Each opcode is disassembled and then assembled again to check that the
+// same bytes are produced.
+func TestAssembleRoundTrip(t *testing.T) {
+	mock.ResetMemory()
+	mem := mock.TestMemory
+	dasm := rcs.NewDisassembler(mem, Reader, Formatter())
+	for opcode := 0; opcode <= 0xff; opcode++ {
+		if _, ok := dasmTable[uint8(opcode)]; !ok {
+			continue
+		}
+		for _, operand := range []int{0x00, 0x12, 0x7f, 0x80, 0xff} {
+			mem.WriteN(0x1234, uint8(opcode), uint8(operand), 0x56)
+			dasm.SetPC(0x1234)
+			stmt := dasm.NextStmt()
+			have, err := Assemble(0x1234, stmt.Op)
+			if err != nil {
+				t.Errorf("%02x %q: %v", opcode, stmt.Op, err)
+				continue
+			}
+			if !reflect.DeepEqual(have, stmt.Bytes) {
+				t.Errorf("%02x %q: have % x, want % x", opcode, stmt.Op, have, stmt.Bytes)
+			}
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		line string
+		want []uint8
+	}{
+		{"LDA #$40", []uint8{0xa9, 0x40}},
+		{"lda #64", []uint8{0xa9, 0x40}},
+		{"lda $12", []uint8{0xa5, 0x12}},
+		{"lda $0012", []uint8{0xad, 0x12, 0x00}},
+		{"lda ($12), y", []uint8{0xb1, 0x12}},
+		{"ldx $12,y", []uint8{0xb6, 0x12}},
+		{"lda $12,y", []uint8{0xb9, 0x12, 0x00}},
+		{"jmp $12", []uint8{0x4c, 0x12, 0x00}},
+		{"asl", []uint8{0x0a}},
+		{"asl a", []uint8{0x0a}},
+		{"bne $1234", []uint8{0xd0, 0xfe}},
+		{"bne $12b5", []uint8{0xd0, 0x7f}},
+		{"bne $11b6", []uint8{0xd0, 0x80}},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			have, err := Assemble(0x1234, test.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, test.want) {
+				t.Errorf("have % x, want % x", have, test.want)
+			}
+		})
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{"", "no instruction"},
+		{"foo", "invalid instruction: foo"},
+		{"lda", "invalid addressing mode: lda"},
+		{"jmp #$12", "invalid addressing mode: jmp #$12"},
+		{"lda #$123", "invalid value: #$123"},
+		{"lda $x", "invalid value: $x"},
+		{"lda ($12", "invalid operand: ($12"},
+		{"bne $12b6", "branch out of range: $12b6"},
+		{"bne $11b5", "branch out of range: $11b5"},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			_, err := Assemble(0x1234, test.line)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
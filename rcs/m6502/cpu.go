@@ -11,7 +11,9 @@ import (
 
 const (
 	addrStack = 0x0100 // starting address of the stack
+	addrNMI   = 0xfffa // non-maskable interrupt vector
 	addrReset = 0xfffc // reset vector
+	addrIRQ   = 0xfffe // interrupt request and break vector
 )
 
 // CPU is the MOS Technology 6502 series processor.
@@ -24,7 +26,10 @@ type CPU struct {
 	SP   uint8  // stack pointer
 	SR   uint8  // status register
 
-	IRQ bool // interrupt request
+	IRQ   bool // interrupt request
+	NMI   bool // non-maskable interrupt, triggered when it changes to true
+	RESET bool // reset, cleared when handled
+	SO    bool // set overflow, cleared when handled
 
 	BreakFunc  func()
 	WatchIRQ   bool
@@ -33,8 +38,13 @@ type CPU struct {
 
 	mem       *rcs.Memory          // CPU's view into memory
 	ops       map[uint8]func(*CPU) // opcode table
+	calls     rcs.CallStack        // call tracking for backtraces
 	addrLoad  int                  // memory address where the last value was loaded from
 	pageCross bool                 // if set, add a one cycle penalty for crossing a page boundary
+	cycles    uint64               // number of cycles executed
+	nmiLine   bool                 // value of NMI when last checked
+	nmiEdge   bool                 // set when NMI changes to true until handled
+	jammed    bool                 // stopped by a jam instruction until reset
 }
 
 const (
@@ -75,19 +85,45 @@ func New(mem *rcs.Memory) *CPU {
 
 // Next executes the next instruction.
 func (c *CPU) Next() {
+	if c.jammed {
+		if c.RESET {
+			c.RESET = false
+			c.resetAck()
+		}
+		return
+	}
 	here := uint16(c.PC() + 1)
 	c.pageCross = false
-	opcode := c.fetch()
+	opcode := c.fetchOpcode()
 	execute, ok := c.ops[opcode]
 	if !ok {
 		log.Printf("(!) %v: illegal instruction %v, pc %v", c.Name, rcs.X8(opcode), rcs.X16(here))
 		return
 	}
 	execute(c)
+	c.cycles += uint64(cycles[opcode])
+	if c.pageCross && pagePenalty[opcode] {
+		c.cycles++
+	}
 	c.SR |= Flag5
 	c.SR &^= FlagB
+	if c.jammed {
+		return
+	}
 
-	if c.IRQ {
+	if c.RESET {
+		c.RESET = false
+		c.resetAck()
+		return
+	}
+	if c.SO {
+		c.SO = false
+		c.SR |= FlagV
+	}
+	c.checkNMI()
+	if c.nmiEdge {
+		c.irqAck(false)
+	} else if c.IRQ {
 		c.IRQ = false
 		if c.SR&FlagI == 0 {
 			c.irqAck(false)
@@ -95,21 +131,49 @@ func (c *CPU) Next() {
 	}
 }
 
-// interrupt handler
+// checkNMI looks for a change in the NMI line from false to true. Once
+// seen, the interrupt stays pending until handled even if the line is
+// released.
+func (c *CPU) checkNMI() {
+	if c.NMI && !c.nmiLine {
+		c.nmiEdge = true
+	}
+	c.nmiLine = c.NMI
+}
+
+// interrupt handler for irq, nmi, and brk. If there is a pending nmi when
+// the vector is fetched, it hijacks the interrupt and the nmi vector is
+// used instead. The status register pushed to the stack still has the
+// break flag set when hijacking a brk.
+//
+// https://www.pagetable.com/?p=410
 func (c *CPU) irqAck(brk bool) {
 	here := uint16(c.pc)
 	// http://www.6502.org/tutorials/6502opcodes.html#RTI
 	// Note that unlike RTS, the return address on the stack is the
 	// actual address rather than the address-1.
 	ret := c.pc + 1
-	vector := uint16(c.mem.ReadLE(0xfffe))
+	c.checkNMI()
+	nmi := c.nmiEdge
+	c.nmiEdge = false
+	vector := uint16(c.mem.ReadLE(addrIRQ))
+	if nmi {
+		vector = uint16(c.mem.ReadLE(addrNMI))
+	}
 	if !brk && c.WatchIRQ {
-		log.Printf("%v: irq, vector %v, return %v", c.Name, rcs.X16(vector), rcs.X16(ret))
+		name := "irq"
+		if nmi {
+			name = "nmi"
+		}
+		log.Printf("%v: %v, vector %v, return %v", c.Name, name, rcs.X16(vector), rcs.X16(ret))
 	}
 	if brk && c.WatchBRK {
 		log.Printf("%v: brk, vector %v, pc %v", c.Name, rcs.X16(vector), rcs.X16(here))
 	}
 
+	if !brk {
+		c.cycles += 7
+	}
 	c.push2(ret)
 	sr := c.SR | Flag5
 	if brk {
@@ -118,6 +182,34 @@ func (c *CPU) irqAck(brk bool) {
 	c.push(sr)
 	c.SR |= FlagI
 	c.pc = vector - 1
+	if c.calls.Enabled {
+		c.calls.Call(rcs.Frame{
+			Entry:     int(vector),
+			Return:    int(ret),
+			SP:        c.StackAddr(),
+			Interrupt: true,
+		})
+	}
+}
+
+// resetAck runs the interrupt sequence with the writes to the stack
+// turned into reads. The stack pointer is decremented by three, interrupts
+// are disabled, and execution continues at the reset vector. The other
+// registers are left as is.
+func (c *CPU) resetAck() {
+	c.SP -= 3
+	c.SR |= FlagI
+	c.pc = uint16(c.mem.ReadLE(addrReset) - 1)
+	c.cycles += 7
+	c.nmiEdge = false
+	c.jammed = false
+	c.calls.Reset()
+}
+
+// Cycles returns the number of clock cycles used by the instructions and
+// interrupts executed so far.
+func (c *CPU) Cycles() uint64 {
+	return c.cycles
 }
 
 // PC returns the value of the program counter.
@@ -142,6 +234,49 @@ func (c *CPU) Memory() *rcs.Memory {
 	return c.mem
 }
 
+// Flow classifies the instruction at addr by its effect on the call stack.
+func (c *CPU) Flow(addr int) rcs.Flow {
+	switch c.mem.Peek(addr) {
+	case 0x20: // jsr
+		return rcs.FlowCall
+	case 0x40, 0x60: // rti, rts
+		return rcs.FlowReturn
+	}
+	return rcs.FlowNext
+}
+
+// StackAddr is the address in page one that the stack pointer refers to.
+func (c *CPU) StackAddr() int {
+	return 0x100 + int(c.SP)
+}
+
+// CallStack returns the calls tracked during execution. Tracking is off
+// until enabled.
+func (c *CPU) CallStack() *rcs.CallStack {
+	return &c.calls
+}
+
+// ScanStack finds return addresses on the stack that follow a JSR
+// instruction.
+func (c *CPU) ScanStack() []rcs.Frame {
+	var frames []rcs.Frame
+	for sp := int(c.SP) + 1; sp < 0xff; sp++ {
+		// jsr pushes the address of its last byte
+		last := c.mem.PeekLE(addrStack + sp)
+		jsrAddr := last - 2
+		if jsrAddr < 0 || c.mem.Peek(jsrAddr) != 0x20 {
+			continue
+		}
+		frames = append([]rcs.Frame{{
+			Entry:  c.mem.PeekLE(jsrAddr + 1),
+			Return: last + 1,
+			SP:     addrStack + sp - 1,
+		}}, frames...)
+		sp++
+	}
+	return frames
+}
+
 // NewDisassembler creates a disassembler that can handle 6502 machine
 // code.
 func (c *CPU) NewDisassembler() *rcs.Disassembler {
@@ -183,7 +318,13 @@ func (c *CPU) String() string {
 // program counter.
 func (c *CPU) fetch() uint8 {
 	c.pc++
-	return c.mem.Read(int(c.pc))
+	return c.mem.Fetch(int(c.pc), false)
+}
+
+// Like fetch, but for the first byte of an instruction.
+func (c *CPU) fetchOpcode() uint8 {
+	c.pc++
+	return c.mem.Fetch(int(c.pc), true)
 }
 
 // Like fetch, but return the next 16-bit value.
@@ -1,9 +1,11 @@
 package m6502
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/blackchip-org/retro-cs/mock"
+	"github.com/blackchip-org/retro-cs/rcs"
 )
 
 func newTestCPU() *CPU {
@@ -95,3 +97,295 @@ func TestCPUString(t *testing.T) {
 		}
 	}
 }
+
+func TestFlow(t *testing.T) {
+	var tests = []struct {
+		op   uint8
+		want rcs.Flow
+	}{
+		{0x20, rcs.FlowCall},   // jsr
+		{0x60, rcs.FlowReturn}, // rts
+		{0x40, rcs.FlowReturn}, // rti
+		{0x4c, rcs.FlowNext},   // jmp
+		{0x00, rcs.FlowNext},   // brk
+	}
+	for _, test := range tests {
+		cpu := newTestCPU()
+		cpu.mem.Write(0x0200, test.op)
+		have := cpu.Flow(0x0200)
+		if have != test.want {
+			t.Errorf("$%02x: have %v, want %v", test.op, have, test.want)
+		}
+	}
+}
+
+func TestCallStack(t *testing.T) {
+	cpu := newTestCPU()
+	cpu.mem.WriteN(0x0200, 0x20, 0x00, 0x03) // jsr $0300
+	cpu.mem.WriteN(0x0300, 0x20, 0x00, 0x04) // jsr $0400
+	cpu.mem.WriteN(0x0400, 0x60)             // rts
+	cpu.CallStack().Enabled = true
+	cpu.Next()
+	cpu.Next()
+	want := []rcs.Frame{
+		{Entry: 0x0300, Return: 0x0203, SP: 0x01fd},
+		{Entry: 0x0400, Return: 0x0303, SP: 0x01fb},
+	}
+	if have := cpu.CallStack().Frames; !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %+v \n want: %+v", have, want)
+	}
+	if have := cpu.ScanStack(); !reflect.DeepEqual(have, want) {
+		t.Errorf("scan\n have: %+v \n want: %+v", have, want)
+	}
+	cpu.Next()
+	want = want[:1]
+	if have := cpu.CallStack().Frames; !reflect.DeepEqual(have, want) {
+		t.Errorf("return\n have: %+v \n want: %+v", have, want)
+	}
+}
+
+func TestCallStackIRQ(t *testing.T) {
+	cpu := newTestCPU()
+	cpu.mem.WriteLE(0xfffe, 0x0400)
+	cpu.mem.WriteN(0x0200, 0xea) // nop
+	cpu.mem.WriteN(0x0400, 0x40) // rti
+	cpu.CallStack().Enabled = true
+	cpu.IRQ = true
+	cpu.Next()
+	want := []rcs.Frame{
+		{Entry: 0x0400, Return: 0x0201, SP: 0x01fc, Interrupt: true},
+	}
+	if have := cpu.CallStack().Frames; !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %+v \n want: %+v", have, want)
+	}
+	cpu.IRQ = false
+	cpu.Next()
+	if have := cpu.CallStack().Frames; len(have) != 0 {
+		t.Errorf("frames remaining after rti: %+v", have)
+	}
+}
+
+func TestInterrupts(t *testing.T) {
+	var tests = []struct {
+		name   string
+		ops    []uint8
+		setup  func(*CPU)
+		n      int
+		pc     int   // address of the next instruction
+		sp     uint8 // stack pointer
+		sr     uint8 // flags expected to be set
+		pushed uint8 // status register on the stack, if any
+	}{
+		{"nmi", []uint8{0xea}, func(c *CPU) {
+			c.NMI = true
+		}, 1, 0x0500, 0xfc, FlagI, Flag5},
+		{"nmi when disabled", []uint8{0xea}, func(c *CPU) {
+			c.SR |= FlagI
+			c.NMI = true
+		}, 1, 0x0500, 0xfc, FlagI, Flag5 | FlagI},
+		{"nmi on edge only", []uint8{0xea}, func(c *CPU) {
+			c.NMI = true
+		}, 2, 0x0501, 0xfc, FlagI, Flag5},
+		{"nmi hijacks irq", []uint8{0xea}, func(c *CPU) {
+			c.IRQ, c.NMI = true, true
+		}, 1, 0x0500, 0xfc, FlagI, Flag5},
+		{"nmi hijacks brk", []uint8{0x00}, func(c *CPU) {
+			c.NMI = true
+		}, 1, 0x0500, 0xfc, FlagI, Flag5 | FlagB},
+		{"reset", []uint8{0xea}, func(c *CPU) {
+			c.RESET = true
+		}, 1, 0x0600, 0xfc, FlagI, 0},
+		{"so", []uint8{0xea}, func(c *CPU) {
+			c.SO = true
+		}, 1, 0x0201, 0xff, FlagV, 0},
+		{"jam ignores nmi", []uint8{0x02}, func(c *CPU) {
+			c.NMI = true
+		}, 2, 0x0200, 0xff, 0, 0},
+		{"jam until reset", []uint8{0x02}, func(c *CPU) {
+			c.RESET = true
+		}, 2, 0x0600, 0xfc, FlagI, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU()
+			cpu.mem.WriteLE(0xfffa, 0x0500)
+			cpu.mem.WriteLE(0xfffc, 0x0600)
+			cpu.mem.WriteLE(0xfffe, 0x0400)
+			cpu.mem.WriteN(0x0200, test.ops...)
+			cpu.mem.WriteN(0x0500, 0xea) // nop
+			test.setup(cpu)
+			for i := 0; i < test.n; i++ {
+				cpu.Next()
+			}
+			if have := cpu.PC() + cpu.Offset(); have != test.pc {
+				t.Errorf("pc: have %04x, want %04x", have, test.pc)
+			}
+			if cpu.SP != test.sp {
+				t.Errorf("sp: have %02x, want %02x", cpu.SP, test.sp)
+			}
+			if cpu.SR&test.sr != test.sr {
+				t.Errorf("sr: have %02x, want %02x set", cpu.SR, test.sr)
+			}
+			if test.pushed != 0 {
+				if have := cpu.mem.Read(0x01fd); have != test.pushed {
+					t.Errorf("pushed: have %02x, want %02x", have, test.pushed)
+				}
+			}
+		})
+	}
+}
+
+func TestCDL(t *testing.T) {
+	cpu := newTestCPU()
+	cpu.mem.WriteN(0x0200, 0xad, 0x00, 0x03) // lda $0300
+	cpu.mem.WriteN(0x0203, 0x8d, 0x01, 0x03) // sta $0301
+	cpu.mem.WriteN(0x0206, 0x4c, 0x10, 0x02) // jmp $0210
+	cpu.mem.WriteN(0x0210, 0xea)             // nop
+	cpu.mem.CDL = rcs.NewCDL(0x10000)
+	defer func() { cpu.mem.CDL = nil }()
+	for i := 0; i < 4; i++ {
+		cpu.Next()
+	}
+	want := map[int]rcs.CDLFlag{
+		0x0200: rcs.CDLOpcode | rcs.CDLTarget,
+		0x0201: rcs.CDLOperand,
+		0x0202: rcs.CDLOperand,
+		0x0203: rcs.CDLOpcode,
+		0x0206: rcs.CDLOpcode,
+		0x0208: rcs.CDLOperand,
+		0x0210: rcs.CDLOpcode | rcs.CDLTarget,
+		0x0211: 0,
+		0x0300: rcs.CDLRead,
+		0x0301: rcs.CDLWrite,
+	}
+	for addr, flags := range want {
+		if have := cpu.mem.CDL.Flags[addr]; have != flags {
+			t.Errorf("$%04x: have %05b want %05b", addr, have, flags)
+		}
+	}
+}
+
+// Timing for each instruction as published in the NMOS 6502 tables,
+// described by addressing mode with the exceptions listed.
+// Cycles for each NMOS opcode, including the undocumented ones, without
+// page crossings or branches taken, as listed in "NMOS 6510 Unintended
+// Opcodes" (No More Secrets) and the MOS programming manual. The JAM
+// opcodes never finish an instruction and are zero.
+var nmosCycles = [256]int{
+	// 0  1  2  3  4  5  6  7  8  9  a  b  c  d  e  f
+	7, 6, 0, 8, 3, 3, 5, 5, 3, 2, 2, 2, 4, 4, 6, 6, // 0
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // 1
+	6, 6, 0, 8, 3, 3, 5, 5, 4, 2, 2, 2, 4, 4, 6, 6, // 2
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // 3
+	6, 6, 0, 8, 3, 3, 5, 5, 3, 2, 2, 2, 3, 4, 6, 6, // 4
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // 5
+	6, 6, 0, 8, 3, 3, 5, 5, 4, 2, 2, 2, 5, 4, 6, 6, // 6
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // 7
+	2, 6, 2, 6, 3, 3, 3, 3, 2, 2, 2, 2, 4, 4, 4, 4, // 8
+	2, 6, 0, 6, 4, 4, 4, 4, 2, 5, 2, 5, 5, 5, 5, 5, // 9
+	2, 6, 2, 6, 3, 3, 3, 3, 2, 2, 2, 2, 4, 4, 4, 4, // a
+	2, 5, 0, 5, 4, 4, 4, 4, 2, 4, 2, 4, 4, 4, 4, 4, // b
+	2, 6, 2, 8, 3, 3, 5, 5, 2, 2, 2, 2, 4, 4, 6, 6, // c
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // d
+	2, 6, 2, 8, 3, 3, 5, 5, 2, 2, 2, 2, 4, 4, 6, 6, // e
+	2, 5, 0, 8, 4, 4, 6, 6, 2, 4, 2, 7, 4, 4, 7, 7, // f
+}
+
+// TestCycles runs every opcode once and checks the cycles counted by the
+// processor. The index registers are zero so that no page is crossed and
+// the flags are set so that no branch is taken.
+func TestCycles(t *testing.T) {
+	notTaken := map[uint8]uint8{
+		0x10: FlagN, // bpl
+		0x30: 0,     // bmi
+		0x50: FlagV, // bvc
+		0x70: 0,     // bvs
+		0x90: FlagC, // bcc
+		0xb0: 0,     // bcs
+		0xd0: FlagZ, // bne
+		0xf0: 0,     // beq
+	}
+	for opcode := 0; opcode <= 0xff; opcode++ {
+		cpu := newTestCPU()
+		cpu.SR = notTaken[uint8(opcode)]
+		cpu.mem.WriteN(0x0200, uint8(opcode), 0x10, 0x00)
+		cpu.Next()
+		if have := int(cpu.Cycles()); have != nmosCycles[opcode] {
+			t.Errorf("%02x: have %v want %v", opcode, have, nmosCycles[opcode])
+		}
+	}
+}
+
+func TestCyclesPenalty(t *testing.T) {
+	var tests = []struct {
+		name  string
+		setup func(*CPU)
+		want  uint64
+	}{
+		{"lda abs,x", func(c *CPU) {
+			c.X = 0x01
+			c.mem.WriteN(0x0200, 0xbd, 0x00, 0x03)
+		}, 4},
+		{"lda abs,x, page cross", func(c *CPU) {
+			c.X = 0x01
+			c.mem.WriteN(0x0200, 0xbd, 0xff, 0x03)
+		}, 5},
+		{"lda (zp),y, page cross", func(c *CPU) {
+			c.Y = 0x01
+			c.mem.WriteLE(0x0010, 0x03ff)
+			c.mem.WriteN(0x0200, 0xb1, 0x10)
+		}, 6},
+		{"lax (zp),y, page cross", func(c *CPU) {
+			c.Y = 0x01
+			c.mem.WriteLE(0x0010, 0x03ff)
+			c.mem.WriteN(0x0200, 0xb3, 0x10)
+		}, 6},
+		{"nop abs,x, page cross", func(c *CPU) {
+			c.X = 0x01
+			c.mem.WriteN(0x0200, 0x1c, 0xff, 0x03)
+		}, 5},
+		{"dcp (zp),y, page cross", func(c *CPU) {
+			c.Y = 0x01
+			c.mem.WriteLE(0x0010, 0x03ff)
+			c.mem.WriteN(0x0200, 0xd3, 0x10)
+		}, 8},
+		{"sta abs,x, page cross", func(c *CPU) {
+			c.X = 0x01
+			c.mem.WriteN(0x0200, 0x9d, 0xff, 0x03)
+		}, 5},
+		{"inc abs,x, page cross", func(c *CPU) {
+			c.X = 0x01
+			c.mem.WriteN(0x0200, 0xfe, 0xff, 0x03)
+		}, 7},
+		{"beq, not taken", func(c *CPU) {
+			c.mem.WriteN(0x0200, 0xf0, 0x10)
+		}, 2},
+		{"beq, taken", func(c *CPU) {
+			c.SR |= FlagZ
+			c.mem.WriteN(0x0200, 0xf0, 0x10)
+		}, 3},
+		{"beq, taken, page cross", func(c *CPU) {
+			c.SR |= FlagZ
+			c.mem.WriteN(0x0200, 0xf0, 0xf0)
+		}, 4},
+		{"irq", func(c *CPU) {
+			c.IRQ = true
+			c.mem.WriteN(0x0200, 0xea)
+		}, 9},
+		{"irq, disabled", func(c *CPU) {
+			c.IRQ = true
+			c.SR |= FlagI
+			c.mem.WriteN(0x0200, 0xea)
+		}, 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cpu := newTestCPU()
+			test.setup(cpu)
+			cpu.Next()
+			if have := cpu.Cycles(); have != test.want {
+				t.Errorf("have %v want %v", have, test.want)
+			}
+		})
+	}
+}
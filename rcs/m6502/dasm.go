@@ -222,3 +222,139 @@ var dasmTable = map[uint8]op{
 	0xfd: op{"sbc", absoluteX},
 	0xfe: op{"inc", absoluteX},
 }
+
+// Opcodes not documented by MOS Technology. These are decoded by the
+// disassembler but are not accepted by the assembler.
+//
+// http://www.zimmers.net/anonftp/pub/cbm/documents/chipdata/64doc
+var dasmUndocumented = map[uint8]op{
+	0x02: op{"jam", implied},
+	0x03: op{"slo", indirectX},
+	0x04: op{"nop", zeroPage},
+	0x07: op{"slo", zeroPage},
+	0x0b: op{"anc", immediate},
+	0x0c: op{"nop", absolute},
+	0x0f: op{"slo", absolute},
+
+	0x12: op{"jam", implied},
+	0x13: op{"slo", indirectY},
+	0x14: op{"nop", zeroPageX},
+	0x17: op{"slo", zeroPageX},
+	0x1a: op{"nop", implied},
+	0x1b: op{"slo", absoluteY},
+	0x1c: op{"nop", absoluteX},
+	0x1f: op{"slo", absoluteX},
+
+	0x22: op{"jam", implied},
+	0x23: op{"rla", indirectX},
+	0x27: op{"rla", zeroPage},
+	0x2b: op{"anc", immediate},
+	0x2f: op{"rla", absolute},
+
+	0x32: op{"jam", implied},
+	0x33: op{"rla", indirectY},
+	0x34: op{"nop", zeroPageX},
+	0x37: op{"rla", zeroPageX},
+	0x3a: op{"nop", implied},
+	0x3b: op{"rla", absoluteY},
+	0x3c: op{"nop", absoluteX},
+	0x3f: op{"rla", absoluteX},
+
+	0x42: op{"jam", implied},
+	0x43: op{"sre", indirectX},
+	0x44: op{"nop", zeroPage},
+	0x47: op{"sre", zeroPage},
+	0x4b: op{"alr", immediate},
+	0x4f: op{"sre", absolute},
+
+	0x52: op{"jam", implied},
+	0x53: op{"sre", indirectY},
+	0x54: op{"nop", zeroPageX},
+	0x57: op{"sre", zeroPageX},
+	0x5a: op{"nop", implied},
+	0x5b: op{"sre", absoluteY},
+	0x5c: op{"nop", absoluteX},
+	0x5f: op{"sre", absoluteX},
+
+	0x62: op{"jam", implied},
+	0x63: op{"rra", indirectX},
+	0x64: op{"nop", zeroPage},
+	0x67: op{"rra", zeroPage},
+	0x6b: op{"arr", immediate},
+	0x6f: op{"rra", absolute},
+
+	0x72: op{"jam", implied},
+	0x73: op{"rra", indirectY},
+	0x74: op{"nop", zeroPageX},
+	0x77: op{"rra", zeroPageX},
+	0x7a: op{"nop", implied},
+	0x7b: op{"rra", absoluteY},
+	0x7c: op{"nop", absoluteX},
+	0x7f: op{"rra", absoluteX},
+
+	0x80: op{"nop", immediate},
+	0x82: op{"nop", immediate},
+	0x83: op{"sax", indirectX},
+	0x87: op{"sax", zeroPage},
+	0x89: op{"nop", immediate},
+	0x8b: op{"ane", immediate},
+	0x8f: op{"sax", absolute},
+
+	0x92: op{"jam", implied},
+	0x93: op{"sha", indirectY},
+	0x97: op{"sax", zeroPageY},
+	0x9b: op{"tas", absoluteY},
+	0x9c: op{"shy", absoluteX},
+	0x9e: op{"shx", absoluteY},
+	0x9f: op{"sha", absoluteY},
+
+	0xa3: op{"lax", indirectX},
+	0xa7: op{"lax", zeroPage},
+	0xab: op{"lxa", immediate},
+	0xaf: op{"lax", absolute},
+
+	0xb2: op{"jam", implied},
+	0xb3: op{"lax", indirectY},
+	0xb7: op{"lax", zeroPageY},
+	0xbb: op{"las", absoluteY},
+	0xbf: op{"lax", absoluteY},
+
+	0xc2: op{"nop", immediate},
+	0xc3: op{"dcp", indirectX},
+	0xc7: op{"dcp", zeroPage},
+	0xcb: op{"sbx", immediate},
+	0xcf: op{"dcp", absolute},
+
+	0xd2: op{"jam", implied},
+	0xd3: op{"dcp", indirectY},
+	0xd4: op{"nop", zeroPageX},
+	0xd7: op{"dcp", zeroPageX},
+	0xda: op{"nop", implied},
+	0xdb: op{"dcp", absoluteY},
+	0xdc: op{"nop", absoluteX},
+	0xdf: op{"dcp", absoluteX},
+
+	0xe2: op{"nop", immediate},
+	0xe3: op{"isc", indirectX},
+	0xe7: op{"isc", zeroPage},
+	0xeb: op{"sbc", immediate},
+	0xef: op{"isc", absolute},
+
+	0xf2: op{"jam", implied},
+	0xf3: op{"isc", indirectY},
+	0xf4: op{"nop", zeroPageX},
+	0xf7: op{"isc", zeroPageX},
+	0xfa: op{"nop", implied},
+	0xfb: op{"isc", absoluteY},
+	0xfc: op{"nop", absoluteX},
+	0xff: op{"isc", absoluteX},
+}
+
+// lookup returns the operation for the opcode, documented or not.
+func lookup(opcode uint8) (op, bool) {
+	if o, ok := dasmTable[opcode]; ok {
+		return o, true
+	}
+	o, ok := dasmUndocumented[opcode]
+	return o, ok
+}
@@ -21,7 +21,6 @@ func TestDisassembler(t *testing.T) {
 		bytes []uint8
 		want  string
 	}{
-		{b(0x02, 0x00, 0x00), "$1234:  02        ?02"},
 
 		{b(0x69, 0x56, 0x00), "$1234:  69 56     adc #$56"},
 		{b(0x65, 0x56, 0x00), "$1234:  65 56     adc $56"},
@@ -58,6 +57,7 @@ func TestDisassembler(t *testing.T) {
 		{b(0x90, 0x0a, 0x00), "$1234:  90 0a     bcc $1240"},
 		{b(0xb0, 0x0a, 0x00), "$1234:  b0 0a     bcs $1240"},
 		{b(0xd0, 0x0a, 0x00), "$1234:  d0 0a     bne $1240"},
+		{b(0xd0, 0x80, 0x00), "$1234:  d0 80     bne $11b6"},
 		{b(0xf0, 0x0a, 0x00), "$1234:  f0 0a     beq $1240"},
 
 		{b(0x00, 0x00, 0x00), "$1234:  00        brk"},
@@ -205,6 +205,35 @@ func TestDisassembler(t *testing.T) {
 		{b(0x84, 0x56, 0x00), "$1234:  84 56     sty $56"},
 		{b(0x94, 0x56, 0x00), "$1234:  94 56     sty $56,x"},
 		{b(0x8c, 0x78, 0x56), "$1234:  8c 78 56  sty $5678"},
+
+		// undocumented
+		{b(0x4b, 0x56, 0x00), "$1234:  4b 56     alr #$56"},
+		{b(0x0b, 0x56, 0x00), "$1234:  0b 56     anc #$56"},
+		{b(0x8b, 0x56, 0x00), "$1234:  8b 56     ane #$56"},
+		{b(0x6b, 0x56, 0x00), "$1234:  6b 56     arr #$56"},
+		{b(0xc3, 0x56, 0x00), "$1234:  c3 56     dcp ($56,x)"},
+		{b(0xff, 0x78, 0x56), "$1234:  ff 78 56  isc $5678,x"},
+		{b(0x02, 0x00, 0x00), "$1234:  02        jam"},
+		{b(0xbb, 0x78, 0x56), "$1234:  bb 78 56  las $5678,y"},
+		{b(0xb7, 0x56, 0x00), "$1234:  b7 56     lax $56,y"},
+		{b(0xab, 0x56, 0x00), "$1234:  ab 56     lxa #$56"},
+		{b(0x1a, 0x00, 0x00), "$1234:  1a        nop"},
+		{b(0x80, 0x56, 0x00), "$1234:  80 56     nop #$56"},
+		{b(0x04, 0x56, 0x00), "$1234:  04 56     nop $56"},
+		{b(0x14, 0x56, 0x00), "$1234:  14 56     nop $56,x"},
+		{b(0x0c, 0x78, 0x56), "$1234:  0c 78 56  nop $5678"},
+		{b(0x1c, 0x78, 0x56), "$1234:  1c 78 56  nop $5678,x"},
+		{b(0x33, 0x56, 0x00), "$1234:  33 56     rla ($56),y"},
+		{b(0x7b, 0x78, 0x56), "$1234:  7b 78 56  rra $5678,y"},
+		{b(0x97, 0x56, 0x00), "$1234:  97 56     sax $56,y"},
+		{b(0xeb, 0x56, 0x00), "$1234:  eb 56     sbc #$56"},
+		{b(0xcb, 0x56, 0x00), "$1234:  cb 56     sbx #$56"},
+		{b(0x93, 0x56, 0x00), "$1234:  93 56     sha ($56),y"},
+		{b(0x9e, 0x78, 0x56), "$1234:  9e 78 56  shx $5678,y"},
+		{b(0x9c, 0x78, 0x56), "$1234:  9c 78 56  shy $5678,x"},
+		{b(0x07, 0x56, 0x00), "$1234:  07 56     slo $56"},
+		{b(0x4f, 0x78, 0x56), "$1234:  4f 78 56  sre $5678"},
+		{b(0x9b, 0x78, 0x56), "$1234:  9b 78 56  tas $5678,y"},
 	}
 
 	for _, test := range disassemblerTests {
@@ -1,9 +1,15 @@
 package m6502
 
 import (
+	"log"
+
 	"github.com/blackchip-org/retro-cs/rcs"
 )
 
+// unstableMagic is the value used for the constant in ane and lxa. It
+// varies between chips but this is the value seen most often.
+const unstableMagic = 0xee
+
 // add with carry
 func adc(cpu *CPU, load rcs.Load8) {
 	if cpu.SR&FlagD != 0 {
@@ -53,6 +59,21 @@ func adcd(c *CPU, load rcs.Load8) {
 	c.A = out
 }
 
+// and, then logical shift right of the accumulator (undocumented)
+func alr(c *CPU, load rcs.Load8) {
+	and(c, load)
+	lsr(c, c.storeA, c.loadA)
+}
+
+// and, then copy bit 7 to carry (undocumented)
+func anc(c *CPU, load rcs.Load8) {
+	and(c, load)
+	c.SR &^= FlagC
+	if c.A&(1<<7) != 0 {
+		c.SR |= FlagC
+	}
+}
+
 // logical and
 func and(c *CPU, load rcs.Load8) {
 	out := c.A & load()
@@ -66,6 +87,62 @@ func and(c *CPU, load rcs.Load8) {
 	c.A = out
 }
 
+// and of the accumulator, x, and an immediate value after the
+// accumulator is combined with a constant (undocumented, unstable). The
+// result depends on the chip and temperature. Here it is as if
+// the constant was always unstableMagic.
+func ane(c *CPU, load rcs.Load8) {
+	ld(c, c.storeA, func() uint8 { return (c.A | unstableMagic) & c.X & load() })
+}
+
+// and, then rotate right of the accumulator (undocumented). The flags
+// come from the adder instead of the rotate: C is bit 6 and V is bit 6
+// exclusive or bit 5. In decimal mode, the result is then adjusted
+// using the value before the rotate.
+//
+// http://www.zimmers.net/anonftp/pub/cbm/documents/chipdata/64doc
+func arr(c *CPU, load rcs.Load8) {
+	in := c.A & load()
+	out := in >> 1
+	if c.SR&FlagC != 0 {
+		out |= 1 << 7
+	}
+	carryIn := c.SR&FlagC != 0
+
+	c.SR &^= FlagN | FlagV | FlagZ | FlagC
+	if out == 0 {
+		c.SR |= FlagZ
+	}
+	if c.SR&FlagD == 0 {
+		if out&(1<<7) != 0 {
+			c.SR |= FlagN
+		}
+		if out&(1<<6) != 0 {
+			c.SR |= FlagC
+		}
+		if (out>>6)&1 != (out>>5)&1 {
+			c.SR |= FlagV
+		}
+		c.A = out
+		return
+	}
+
+	if carryIn {
+		c.SR |= FlagN
+	}
+	if (in^out)&(1<<6) != 0 {
+		c.SR |= FlagV
+	}
+	if in&0x0f+in&0x01 > 5 {
+		out = out&0xf0 | (out+6)&0x0f
+	}
+	if uint16(in&0xf0)+uint16(in&0x10) > 0x50 {
+		out += 0x60
+		c.SR |= FlagC
+	}
+	c.A = out
+}
+
 // arithmetic shift left
 func asl(c *CPU, store rcs.Store8, load rcs.Load8) {
 	in := load()
@@ -108,15 +185,21 @@ func bit(c *CPU, load rcs.Load8) {
 	}
 }
 
-// branch instructions
+// branch instructions. A branch taken uses one more cycle and another if
+// the target is on a different page than the next instruction.
 func branch(c *CPU, do bool) {
 	displacement := int8(c.fetch())
 	if do {
+		next := c.PC() + 1
 		if displacement >= 0 {
 			c.SetPC(c.PC() + int(displacement))
 		} else {
 			c.SetPC(c.PC() - int(displacement*-1))
 		}
+		c.cycles++
+		if (c.PC()+1)&0xff00 != next&0xff00 {
+			c.cycles++
+		}
 	}
 }
 
@@ -147,6 +230,13 @@ func cmp(c *CPU, load0 rcs.Load8, load1 rcs.Load8) {
 	}
 }
 
+// decrement, then compare (undocumented)
+func dcp(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	dec(c, func(v uint8) { out = v; store(v) }, load)
+	cmp(c, c.loadA, func() uint8 { return out })
+}
+
 // decrement
 func dec(c *CPU, store rcs.Store8, load rcs.Load8) {
 	out := load() - 1
@@ -186,6 +276,21 @@ func inc(c *CPU, store rcs.Store8, load rcs.Load8) {
 	store(out)
 }
 
+// increment, then subtract with carry (undocumented)
+func isc(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	inc(c, func(v uint8) { out = v; store(v) }, load)
+	sbc(c, func() uint8 { return out })
+}
+
+// jam the processor (undocumented). No more instructions are executed and
+// interrupts are ignored until a reset.
+func jam(c *CPU) {
+	c.pc--
+	c.jammed = true
+	log.Printf("(!) %v: jammed, pc %v", c.Name, rcs.X16(c.pc+1))
+}
+
 // jump
 func jmp(c *CPU) {
 	c.pc = uint16(c.fetch2() - 1)
@@ -200,9 +305,27 @@ func jmpIndirect(c *CPU) {
 func jsr(c *CPU) {
 	addr := uint16(c.fetch2())
 	c.push2(c.pc)
+	if c.calls.Enabled {
+		c.calls.Call(rcs.Frame{
+			Entry:  int(addr),
+			Return: int(c.pc) + 1,
+			SP:     c.StackAddr(),
+		})
+	}
 	c.pc = addr - 1
 }
 
+// and of memory with the stack pointer into the accumulator, x, and the
+// stack pointer (undocumented)
+func las(c *CPU, load rcs.Load8) {
+	ld(c, func(v uint8) { c.A, c.X, c.SP = v, v, v }, func() uint8 { return load() & c.SP })
+}
+
+// load accumulator and x (undocumented)
+func lax(c *CPU, load rcs.Load8) {
+	ld(c, func(v uint8) { c.A, c.X = v, v }, load)
+}
+
 // load
 func ld(c *CPU, store rcs.Store8, load rcs.Load8) {
 	out := load()
@@ -235,6 +358,12 @@ func lsr(c *CPU, store rcs.Store8, load rcs.Load8) {
 	store(out)
 }
 
+// load accumulator and x with an immediate value after the accumulator
+// is combined with a constant (undocumented, unstable). See ane.
+func lxa(c *CPU, load rcs.Load8) {
+	lax(c, func() uint8 { return (c.A | unstableMagic) & load() })
+}
+
 // logical or
 func ora(c *CPU, load rcs.Load8) {
 	out := c.A | load()
@@ -275,6 +404,13 @@ func plp(c *CPU) {
 	c.SR = sr
 }
 
+// rotate left, then and (undocumented)
+func rla(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	rol(c, func(v uint8) { out = v; store(v) }, load)
+	and(c, func() uint8 { return out })
+}
+
 // rotate left
 func rol(c *CPU, store rcs.Store8, load rcs.Load8) {
 	in := load()
@@ -319,6 +455,13 @@ func ror(c *CPU, store rcs.Store8, load rcs.Load8) {
 	store(out)
 }
 
+// rotate right, then add with carry (undocumented)
+func rra(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	ror(c, func(v uint8) { out = v; store(v) }, load)
+	adc(c, func() uint8 { return out })
+}
+
 // return from interrupt
 func rti(c *CPU) {
 	// http://www.6502.org/tutorials/6502opcodes.html#RTI
@@ -326,6 +469,22 @@ func rti(c *CPU) {
 	// actual address rather than the address-1.
 	c.SR = c.pull()
 	c.pc = c.pull2() - 1
+	if c.calls.Enabled {
+		c.calls.Return(c.StackAddr())
+	}
+}
+
+// return from subroutine
+func rts(c *CPU) {
+	c.pc = c.pull2()
+	if c.calls.Enabled {
+		c.calls.Return(c.StackAddr())
+	}
+}
+
+// store accumulator and x (undocumented)
+func sax(c *CPU, store rcs.Store8) {
+	store(c.A & c.X)
 }
 
 // subtract with carry
@@ -381,7 +540,50 @@ func sbcd(c *CPU, load rcs.Load8) {
 	c.A = out
 }
 
+// subtract from the accumulator and x into x, setting the flags like a
+// compare (undocumented)
+func sbx(c *CPU, load rcs.Load8) {
+	in0 := c.A & c.X
+	in1 := load()
+	cmp(c, func() uint8 { return in0 }, func() uint8 { return in1 })
+	c.X = in0 - in1
+}
+
+// store of a value and'ed with the high byte of the base address plus one
+// (undocumented, unstable). When the index crosses a page boundary, the
+// value stored also replaces the high byte of the address. Used by sha,
+// shx, shy, and tas.
+func sh(c *CPU, base int, index uint8, v uint8) {
+	addr := base + int(index)
+	v &= uint8(base>>8) + 1
+	if addr&0xff00 != base&0xff00 {
+		addr = int(v)<<8 | addr&0xff
+	}
+	c.mem.Write(addr, v)
+}
+
+// arithmetic shift left, then logical or (undocumented)
+func slo(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	asl(c, func(v uint8) { out = v; store(v) }, load)
+	ora(c, func() uint8 { return out })
+}
+
+// logical shift right, then exclusive or (undocumented)
+func sre(c *CPU, store rcs.Store8, load rcs.Load8) {
+	var out uint8
+	lsr(c, func(v uint8) { out = v; store(v) }, load)
+	eor(c, func() uint8 { return out })
+}
+
 // store
 func st(c *CPU, store rcs.Store8, load rcs.Load8) {
 	store(load())
 }
+
+// transfer accumulator and x to the stack pointer, then store like sha
+// (undocumented, unstable)
+func tas(c *CPU, base int, index uint8) {
+	c.SP = c.A & c.X
+	sh(c, base, index, c.SP)
+}
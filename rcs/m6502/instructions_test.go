@@ -3079,3 +3079,115 @@ func TestTyaSigned(t *testing.T) {
 		t.Errorf("\n want: %02x \n have: %02x \n", want, have)
 	}
 }
+
+// ----------------------------------------------------------------------------
+// undocumented
+// ----------------------------------------------------------------------------
+func TestUndocumented(t *testing.T) {
+	tests := []struct {
+		name  string
+		ops   []uint8
+		setup func(*CPU)
+		a     uint8
+		x     uint8
+		sr    uint8
+		addr  int // memory to check, if not zero
+		value uint8
+	}{
+		{"alr", []uint8{0x4b, 0x03}, func(c *CPU) {
+			c.A = 0xff
+		}, 0x01, 0x00, FlagC, 0, 0},
+		{"anc", []uint8{0x0b, 0x80}, func(c *CPU) {
+			c.A = 0xff
+		}, 0x80, 0x00, FlagN | FlagC, 0, 0},
+		{"ane", []uint8{0x8b, 0xff}, func(c *CPU) {
+			c.X = 0xff
+		}, 0xee, 0xff, FlagN, 0, 0},
+		{"arr", []uint8{0x6b, 0xc0}, func(c *CPU) {
+			c.A = 0xff
+			c.SR |= FlagC
+		}, 0xe0, 0x00, FlagN | FlagC, 0, 0},
+		{"arr overflow", []uint8{0x6b, 0x40}, func(c *CPU) {
+			c.A = 0xff
+		}, 0x20, 0x00, FlagV, 0, 0},
+		{"dcp", []uint8{0xc7, 0x10}, func(c *CPU) {
+			c.A = 0x05
+			c.mem.Write(0x10, 0x06)
+		}, 0x05, 0x00, FlagZ | FlagC, 0x10, 0x05},
+		{"isc", []uint8{0xe7, 0x10}, func(c *CPU) {
+			c.A = 0x10
+			c.SR |= FlagC
+			c.mem.Write(0x10, 0x0f)
+		}, 0x00, 0x00, FlagZ | FlagC, 0x10, 0x10},
+		{"las", []uint8{0xbb, 0x10, 0x03}, func(c *CPU) {
+			c.mem.Write(0x0310, 0x3c)
+		}, 0x3c, 0x3c, 0, 0, 0},
+		{"lax", []uint8{0xa7, 0x10}, func(c *CPU) {
+			c.mem.Write(0x10, 0x80)
+		}, 0x80, 0x80, FlagN, 0, 0},
+		{"lxa", []uint8{0xab, 0x0f}, func(c *CPU) {}, 0x0e, 0x0e, 0, 0, 0},
+		{"nop abs,x", []uint8{0x1c, 0x00, 0x03}, func(c *CPU) {
+			c.A = 0x12
+		}, 0x12, 0x00, 0, 0, 0},
+		{"rla", []uint8{0x27, 0x10}, func(c *CPU) {
+			c.A = 0xff
+			c.SR |= FlagC
+			c.mem.Write(0x10, 0x80)
+		}, 0x01, 0x00, FlagC, 0x10, 0x01},
+		{"rra", []uint8{0x67, 0x10}, func(c *CPU) {
+			c.A = 0x01
+			c.mem.Write(0x10, 0x03)
+		}, 0x03, 0x00, 0, 0x10, 0x01},
+		{"sax", []uint8{0x87, 0x10}, func(c *CPU) {
+			c.A, c.X = 0xf0, 0x3c
+		}, 0xf0, 0x3c, 0, 0x10, 0x30},
+		{"sbc", []uint8{0xeb, 0x01}, func(c *CPU) {
+			c.A = 0x10
+			c.SR |= FlagC
+		}, 0x0f, 0x00, FlagC, 0, 0},
+		{"sbx", []uint8{0xcb, 0x05}, func(c *CPU) {
+			c.A, c.X = 0xff, 0x0f
+		}, 0xff, 0x0a, FlagC, 0, 0},
+		{"sha", []uint8{0x9f, 0x00, 0x03}, func(c *CPU) {
+			c.A, c.X, c.Y = 0xff, 0x0f, 0x10
+		}, 0xff, 0x0f, 0, 0x0310, 0x04},
+		{"sha page cross", []uint8{0x9f, 0xf0, 0x02}, func(c *CPU) {
+			c.A, c.X, c.Y = 0xff, 0x01, 0x20
+		}, 0xff, 0x01, 0, 0x0110, 0x01},
+		{"slo", []uint8{0x07, 0x10}, func(c *CPU) {
+			c.A = 0x01
+			c.mem.Write(0x10, 0x81)
+		}, 0x03, 0x00, FlagC, 0x10, 0x02},
+		{"slo (zp),y", []uint8{0x13, 0x10}, func(c *CPU) {
+			c.Y = 0x04
+			c.mem.WriteLE(0x10, 0x0300)
+			c.mem.Write(0x0304, 0x40)
+		}, 0x80, 0x00, FlagN, 0x0304, 0x80},
+		{"sre", []uint8{0x47, 0x10}, func(c *CPU) {
+			c.A = 0xff
+			c.mem.Write(0x10, 0x03)
+		}, 0xfe, 0x00, FlagN | FlagC, 0x10, 0x01},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newTestCPU()
+			c.mem.WriteN(0x0200, test.ops...)
+			test.setup(c)
+			testRunCPU(t, c)
+			if c.A != test.a {
+				t.Errorf("a: want %02x have %02x", test.a, c.A)
+			}
+			if c.X != test.x {
+				t.Errorf("x: want %02x have %02x", test.x, c.X)
+			}
+			if want := test.sr | Flag5; c.SR != want {
+				flagError(t, want, c.SR)
+			}
+			if test.addr != 0 {
+				if have := c.mem.Read(test.addr); have != test.value {
+					t.Errorf("$%04x: want %02x have %02x", test.addr, test.value, have)
+				}
+			}
+		})
+	}
+}
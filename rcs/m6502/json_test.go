@@ -0,0 +1,152 @@
+package m6502
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/config"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Per-instruction tests in the JSON format of the SingleStepTests
+// ProcessorTests repository. Each file is named after the opcode and
+// contains the state before and after executing that single instruction
+// and a list of the bus cycles used.
+var jsonDir = filepath.Join(config.ResourceDir(), "ext", "m6502", "json")
+
+// Set to an opcode, such as "a9", to run only the tests in that file
+var jsonSingle = ""
+
+// Stop reporting the failures in a file after this many tests
+const jsonMaxFailures = 10
+
+type jsonState struct {
+	PC  int     `json:"pc"`
+	S   uint8   `json:"s"`
+	A   uint8   `json:"a"`
+	X   uint8   `json:"x"`
+	Y   uint8   `json:"y"`
+	P   uint8   `json:"p"`
+	RAM [][]int `json:"ram"`
+}
+
+type jsonTest struct {
+	Name    string          `json:"name"`
+	Initial jsonState       `json:"initial"`
+	Final   jsonState       `json:"final"`
+	Cycles  [][]interface{} `json:"cycles"`
+}
+
+var jsonFlags = []struct {
+	name string
+	flag uint8
+}{
+	{"c", FlagC},
+	{"z", FlagZ},
+	{"i", FlagI},
+	{"d", FlagD},
+	{"b", FlagB},
+	{"5", Flag5},
+	{"v", FlagV},
+	{"n", FlagN},
+}
+
+func TestJSON(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join(jsonDir, "*.json"))
+	if len(files) == 0 {
+		t.Skipf("no tests found in %v", jsonDir)
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if jsonSingle != "" && name != jsonSingle {
+			continue
+		}
+		opcode, err := strconv.ParseUint(name, 16, 8)
+		if err != nil {
+			continue
+		}
+		if _, ok := opcodes[uint8(opcode)]; !ok {
+			continue
+		}
+		// a jammed processor stays that way until reset
+		if op, _ := lookup(uint8(opcode)); op.inst == "jam" {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tests []jsonTest
+			if err := json.Unmarshal(data, &tests); err != nil {
+				t.Fatalf("unable to decode %v: %v", file, err)
+			}
+			runJSONTests(t, tests)
+		})
+	}
+}
+
+func runJSONTests(t *testing.T, tests []jsonTest) {
+	ram := make([]uint8, 0x10000)
+	mem := rcs.NewMemory(1, 0x10000)
+	mem.MapRAM(0, ram)
+	cpu := New(mem)
+
+	failures := 0
+	for _, test := range tests {
+		in := test.Initial
+		for _, av := range in.RAM {
+			ram[av[0]] = uint8(av[1])
+		}
+		cpu.SetPC(in.PC - cpu.Offset())
+		cpu.SP, cpu.A, cpu.X, cpu.Y, cpu.SR = in.S, in.A, in.X, in.Y, in.P
+
+		start := cpu.Cycles()
+		cpu.Next()
+
+		diffs := compareJSON(cpu, ram, test.Final)
+		if have, want := int(cpu.Cycles()-start), len(test.Cycles); have != want {
+			diffs = append(diffs, fmt.Sprintf("  %-5v have %v want %v", "cycles", have, want))
+		}
+		if len(diffs) > 0 {
+			failures++
+			if failures <= jsonMaxFailures {
+				t.Errorf("%v:\n%v", test.Name, strings.Join(diffs, "\n"))
+			}
+		}
+		for _, av := range append(in.RAM, test.Final.RAM...) {
+			ram[av[0]] = 0
+		}
+	}
+	if failures > jsonMaxFailures {
+		t.Errorf("%v of %v tests failed", failures, len(tests))
+	}
+}
+
+// compareJSON returns a line for each register, flag, and memory cell that
+// is not in the expected state.
+func compareJSON(cpu *CPU, ram []uint8, want jsonState) []string {
+	var diffs []string
+	diff := func(name string, have int, want int) {
+		if have != want {
+			diffs = append(diffs, fmt.Sprintf("  %-5v have %v want %v", name, rcs.X(have), rcs.X(want)))
+		}
+	}
+	diff("pc", cpu.PC()+cpu.Offset(), want.PC)
+	diff("s", int(cpu.SP), int(want.S))
+	diff("a", int(cpu.A), int(want.A))
+	diff("x", int(cpu.X), int(want.X))
+	diff("y", int(cpu.Y), int(want.Y))
+	for _, f := range jsonFlags {
+		diff("f."+f.name, int(cpu.SR&f.flag)/int(f.flag), int(want.P&f.flag)/int(f.flag))
+	}
+	for _, av := range want.RAM {
+		diff(rcs.X16(uint16(av[0])), int(ram[av[0]]), av[1])
+	}
+	return diffs
+}
@@ -11,7 +11,7 @@ func Reader(e rcs.StmtEval) {
 	e.Stmt.Addr = e.Ptr.Addr()
 	opcode := e.Ptr.Fetch()
 	e.Stmt.Bytes = append(e.Stmt.Bytes, opcode)
-	op, ok := dasmTable[opcode]
+	op, ok := lookup(opcode)
 	if !ok {
 		e.Stmt.Op = fmt.Sprintf("?%02x", opcode)
 		return
@@ -49,12 +49,7 @@ func formatOp(op op, operand int, addr int) string {
 		// the instruction
 		value := operand
 		if op.mode == relative {
-			value8 := int8(value)
-			if value8 >= 0 {
-				value = addr + int(value8) + 2
-			} else {
-				value = addr - int(value8*-1) + 2
-			}
+			value = addr + int(int8(value)) + 2
 		}
 		// If the format does not contain a formatting directive, just use as is.
 		// For example: "asl a"
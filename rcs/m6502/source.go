@@ -0,0 +1,121 @@
+package m6502
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// SyntaxCA65 is the source code format of the ca65 assembler.
+var SyntaxCA65 = &rcs.Syntax{
+	Name:   "ca65",
+	Decode: decode,
+	Format: formatter(func(inst string, operand string) string {
+		return inst + " a:" + operand
+	}),
+	Org:     ".org $%04x",
+	Equate:  "%v = $%04x",
+	Label:   "%v:",
+	Byte:    ".byte",
+	Word:    ".word",
+	Vectors: []int{0xfffa, 0xfffc, 0xfffe},
+}
+
+// SyntaxACME is the source code format of the ACME assembler.
+var SyntaxACME = &rcs.Syntax{
+	Name:   "acme",
+	Decode: decode,
+	Format: formatter(func(inst string, operand string) string {
+		return inst + "+2 " + operand
+	}),
+	Org:     "* = $%04x",
+	Equate:  "%v = $%04x",
+	Label:   "%v",
+	Byte:    "!byte",
+	Word:    "!word",
+	Vectors: []int{0xfffa, 0xfffc, 0xfffe},
+}
+
+// Syntaxes returns the assemblers that source code can be generated for.
+func (c *CPU) Syntaxes() []*rcs.Syntax {
+	return []*rcs.Syntax{SyntaxCA65, SyntaxACME}
+}
+
+func decode(mem *rcs.Memory, addr int) (rcs.Instr, bool) {
+	opcode := mem.Peek(addr)
+	op, ok := lookup(opcode)
+	if !ok {
+		return rcs.Instr{}, false
+	}
+	in := rcs.Instr{
+		Addr:  addr,
+		Bytes: []uint8{opcode},
+		Next:  true,
+	}
+	value := 0
+	switch operandLengths[op.mode] {
+	case 1:
+		value = int(mem.Peek(addr + 1))
+		in.Bytes = append(in.Bytes, uint8(value))
+	case 2:
+		value = mem.PeekLE(addr + 1)
+		in.Bytes = append(in.Bytes, uint8(value), uint8(value>>8))
+	}
+	in.Op = op.inst + formatOp(op, value, addr)
+
+	switch {
+	case op.mode == relative:
+		in.Jumps = []int{addr + int(int8(value)) + 2}
+	case op.inst == "jmp" && op.mode == absolute:
+		in.Jumps = []int{value}
+		in.Next = false
+	case op.inst == "jmp":
+		in.Refs = []int{value}
+		in.Next = false
+	case op.inst == "jsr":
+		in.Calls = []int{value}
+	case op.inst == "brk", op.inst == "rti", op.inst == "rts", op.inst == "jam":
+		in.Next = false
+	case operandLengths[op.mode] == 2:
+		in.Refs = []int{value}
+	}
+	return in, true
+}
+
+// formatter returns a function that formats instructions for an
+// assembler. Absolute addressing with a value that fits in the zero page
+// is formatted with force so that the assembler does not use zero page
+// addressing instead.
+func formatter(force func(inst string, operand string) string) func(rcs.Instr, func(int) (string, bool)) (string, bool) {
+	return func(in rcs.Instr, label func(int) (string, bool)) (string, bool) {
+		// undocumented opcodes are written as data
+		op, ok := dasmTable[in.Bytes[0]]
+		if !ok {
+			return "", false
+		}
+		format := operandFormats[op.mode]
+		switch op.mode {
+		case implied, accumulator:
+			return op.inst, true
+		case relative, absolute, absoluteX, absoluteY, indirect:
+		default:
+			return op.inst + " " + fmt.Sprintf(format, in.Bytes[1]), true
+		}
+		value := 0
+		if op.mode == relative {
+			value = in.Addr + int(int8(in.Bytes[1])) + 2
+		} else {
+			value = int(in.Bytes[1]) | int(in.Bytes[2])<<8
+		}
+		name, ok := label(value)
+		if !ok {
+			name = fmt.Sprintf("$%04x", value)
+		}
+		operand := strings.Replace(format, "$%04x", name, 1)
+		if value < 0x100 && op.mode != relative && op.mode != indirect {
+			return force(op.inst, operand), true
+		}
+		return op.inst + " " + operand, true
+	}
+}
@@ -0,0 +1,123 @@
+package m6502
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/mock"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+var sourceProgram = []uint8{
+	0xa2, 0x00, // $c000: ldx #$00
+	0xbd, 0x13, 0xc0, // $c002: lda $c013,x
+	0xf0, 0x06, // $c005: beq $c00d
+	0x20, 0xd2, 0xff, // $c007: jsr $ffd2
+	0xe8,       // $c00a: inx
+	0xd0, 0xf5, // $c00b: bne $c002
+	0xad, 0x12, 0x00, // $c00d: lda $0012
+	0x6c, 0x16, 0xc0, // $c010: jmp ($c016)
+	0x48, 0x49, 0x00, // $c013: "HI", 0
+	0x00, 0xc0, // $c016: .word $c000
+}
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		syntax *rcs.Syntax
+		want   string
+	}{
+		{SyntaxCA65, `
+; $c000-$c017, ca65
+
+CHROUT = $ffd2
+
+	.org $c000
+Lc000:
+	ldx #$00
+Lc002:
+	lda Lc013,x
+	beq Lc00d
+	jsr CHROUT
+	inx
+	bne Lc002
+Lc00d:
+	lda a:$0012
+	jmp (Lc016)
+Lc013:
+	.byte $48,$49,$00               ; message
+Lc016:
+	.word Lc000
+`},
+		{SyntaxACME, `
+; $c000-$c017, acme
+
+CHROUT = $ffd2
+
+	* = $c000
+Lc000
+	ldx #$00
+Lc002
+	lda Lc013,x
+	beq Lc00d
+	jsr CHROUT
+	inx
+	bne Lc002
+Lc00d
+	lda+2 $0012
+	jmp (Lc016)
+Lc013
+	!byte $48,$49,$00               ; message
+Lc016
+	!word Lc000
+`},
+	}
+	for _, test := range tests {
+		t.Run(test.syntax.Name, func(t *testing.T) {
+			mock.ResetMemory()
+			mem := mock.TestMemory
+			mem.WriteN(0xc000, sourceProgram...)
+			names := map[int]string{0xffd2: "CHROUT"}
+			a := rcs.NewAnnotations()
+			a.Mark(0xc016, 0xc017, rcs.Pointers)
+			a.Notes[0xc013] = "message"
+			an := rcs.Analyze(mem, test.syntax, 0xc000, 0xc017, []int{0xc000}, names, a)
+			var buf bytes.Buffer
+			if err := an.WriteSource(&buf); err != nil {
+				t.Fatal(err)
+			}
+			have := strings.TrimSpace(buf.String())
+			want := strings.TrimSpace(test.want)
+			if have != want {
+				t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+			}
+		})
+	}
+}
+
+// Undocumented opcodes are followed but written as data since the
+// assemblers need options to accept them.
+func TestSourceUndocumented(t *testing.T) {
+	mock.ResetMemory()
+	mem := mock.TestMemory
+	mem.WriteN(0xc000,
+		0xa7, 0x10, // $c000: lax $10
+		0x60, // $c002: rts
+	)
+	an := rcs.Analyze(mem, SyntaxCA65, 0xc000, 0xc002, []int{0xc000}, nil, rcs.NewAnnotations())
+	var buf bytes.Buffer
+	if err := an.WriteSource(&buf); err != nil {
+		t.Fatal(err)
+	}
+	have := strings.TrimSpace(buf.String())
+	want := strings.TrimSpace(`
+; $c000-$c002, ca65
+
+	.org $c000
+	.byte $a7,$10                   ; lax $10
+	rts
+`)
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+}
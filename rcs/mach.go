@@ -1,6 +1,7 @@
 package rcs
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -46,14 +47,25 @@ const (
 	MachStart
 	MachTrace
 	MachTraceAll
+	MachUntil
 	MachQuit
+	MachDo
 )
 
 type message struct {
-	Cmd  MachCmd
-	Args []interface{}
+	Cmd   MachCmd
+	Args  []interface{}
+	reply chan reply
 }
 
+type reply struct {
+	v   interface{}
+	err error
+}
+
+// ErrNotRunning is returned by Call when the machine has stopped running.
+var ErrNotRunning = errors.New("machine is not running")
+
 type MachEvent int
 
 const (
@@ -82,13 +94,18 @@ type Mach struct {
 	Breakpoints map[string]map[int]struct{}
 	Executing   string // name of the CPU that is executing
 	At          int    // address of the executing instruction
+	Frames      int    // number of frames (vertical blanks) while running
+	Cheats      []*Cheat
 
 	stuck     map[string]bool
 	tracing   map[string]bool
+	untilCPU  string
+	until     func() bool
 	scanLines *sdl.Texture
 	init      bool
 	quit      bool
 	cmd       chan message
+	done      chan struct{}
 	snapT     *sdl.Texture
 	snapS     *sdl.Surface
 }
@@ -119,6 +136,7 @@ func (m *Mach) Init() error {
 		m.DefaultEncoding = "ascii"
 	}
 	m.cmd = make(chan message, 10)
+	m.done = make(chan struct{})
 
 	m.Breakpoints = make(map[string]map[int]struct{})
 	for name := range m.CPU {
@@ -168,6 +186,7 @@ func (m *Mach) Run() error {
 	ticker := time.NewTicker(vblank)
 	panicked := true
 	defer func() {
+		close(m.done)
 		if panicked {
 			m.reportCrash()
 		}
@@ -187,10 +206,42 @@ func (m *Mach) Run() error {
 	return nil
 }
 
+// Command sends a command to the machine without waiting for it to be
+// handled. If the command fails, an ErrorEvent is sent to the Callback.
 func (m *Mach) Command(cmd MachCmd, args ...interface{}) {
 	m.cmd <- message{Cmd: cmd, Args: args}
 }
 
+// Call sends a command to the machine and waits for it to be handled. The
+// result of the command is returned and errors are returned instead of
+// being sent as an ErrorEvent.
+//
+// Use the MachDo command to safely read or change the state of the machine
+// from another goroutine. The argument is a function with the signature
+// func() error that is called between instructions. Call must not be used
+// from the goroutine running the machine, such as in a Callback or within a
+// MachDo function, as it will never return.
+func (m *Mach) Call(cmd MachCmd, args ...interface{}) (interface{}, error) {
+	r := make(chan reply, 1)
+	select {
+	case m.cmd <- message{Cmd: cmd, Args: args, reply: r}:
+	case <-m.done:
+		return nil, ErrNotRunning
+	}
+	select {
+	case rep := <-r:
+		return rep.v, rep.err
+	case <-m.done:
+	}
+	// the reply may have been sent just before the machine stopped
+	select {
+	case rep := <-r:
+		return rep.v, rep.err
+	default:
+		return nil, ErrNotRunning
+	}
+}
+
 func (m *Mach) jiffy() {
 	if m.Status == Run {
 		m.execute()
@@ -207,7 +258,19 @@ func (m *Mach) jiffy() {
 	}
 	m.sdl()
 	if m.Status == Run {
+		m.Frames++
 		m.VBlankFunc()
+		m.applyCheats()
+	}
+}
+
+// applyCheats writes the cheats to memory after the vertical blank so
+// that the values are in place before the next frame.
+func (m *Mach) applyCheats() {
+	for _, c := range m.Cheats {
+		if cpu, ok := m.CPU[c.CPU]; ok {
+			c.Apply(cpu.Memory())
+		}
 	}
 }
 
@@ -232,6 +295,10 @@ func (m *Mach) execute() {
 				m.setStatus(Break)
 				return
 			}
+			if m.until != nil && m.untilCPU == name && m.until() {
+				m.setStatus(Break)
+				return
+			}
 		}
 		m.Executing = ""
 		m.At = 0
@@ -298,73 +365,86 @@ func (m *Mach) sdl() {
 }
 
 func (m *Mach) handleCommand(msg message) {
+	v, err := m.command(msg)
+	if msg.reply != nil {
+		msg.reply <- reply{v: v, err: err}
+		return
+	}
+	if err != nil {
+		m.event(ErrorEvent, err)
+	}
+}
+
+func (m *Mach) command(msg message) (interface{}, error) {
 	switch msg.Cmd {
+	case MachDo:
+		return nil, msg.Args[0].(func() error)()
 	case MachExport:
-		m.cmdExport(msg.Args...)
+		return nil, m.cmdExport(msg.Args...)
 	case MachImport:
-		m.cmdImport(msg.Args...)
+		return nil, m.cmdImport(msg.Args...)
 	case MachPause:
 		m.setStatus(Pause)
 	case MachSnapshot:
-		m.cmdSnapshot(msg.Args...)
+		return nil, m.cmdSnapshot(msg.Args...)
 	case MachStart:
 		m.setStatus(Run)
 	case MachTrace:
-		m.cmdTrace(msg.Args...)
+		return nil, m.cmdTrace(msg.Args...)
 	case MachTraceAll:
-		m.cmdTraceAll(msg.Args...)
+		return nil, m.cmdTraceAll(msg.Args...)
+	case MachUntil:
+		return nil, m.cmdUntil(msg.Args...)
 	case MachQuit:
 		m.quit = true
 	default:
-		m.event(ErrorEvent, fmt.Errorf("unknown command: %v", msg.Cmd))
+		return nil, fmt.Errorf("unknown command: %v", msg.Cmd)
 	}
+	return nil, nil
 }
 
-func (m *Mach) cmdExport(args ...interface{}) {
+func (m *Mach) cmdExport(args ...interface{}) error {
 	sys, ok := m.Sys.(Saver)
 	if !ok {
-		m.event(ErrorEvent, "exporting is not supported")
-		return
+		return errors.New("exporting is not supported")
 	}
 	filename := args[0].(string)
 	out, err := os.Create(filename)
 	if err != nil {
-		m.event(ErrorEvent, fmt.Sprintf("unable toe xport: %v", err))
-		return
+		return fmt.Errorf("unable to export: %v", err)
 	}
+	defer out.Close()
 	enc := NewEncoder(out)
 	sys.Save(enc)
 	if enc.Err != nil {
-		m.event(ErrorEvent, fmt.Sprintf("unable to export: %v", enc.Err))
-		return
+		return fmt.Errorf("unable to export: %v", enc.Err)
 	}
+	return nil
 }
 
-func (m *Mach) cmdImport(args ...interface{}) {
+func (m *Mach) cmdImport(args ...interface{}) error {
 	sys, ok := m.Sys.(Loader)
 	if !ok {
-		m.event(ErrorEvent, "importing is not supported")
-		return
+		return errors.New("importing is not supported")
 	}
 	filename := args[0].(string)
 	in, err := os.Open(filename)
 	if err != nil {
-		m.event(ErrorEvent, fmt.Sprintf("unable to import: %v", err))
-		return
+		return fmt.Errorf("unable to import: %v", err)
 	}
+	defer in.Close()
 	dec := NewDecoder(in)
 	sys.Load(dec)
 	if dec.Err != nil {
-		m.event(ErrorEvent, fmt.Sprintf("unable to import: %v", dec.Err))
-		return
+		return fmt.Errorf("unable to import: %v", dec.Err)
 	}
+	return nil
 }
 
-func (m *Mach) cmdSnapshot(args ...interface{}) {
+func (m *Mach) cmdSnapshot(args ...interface{}) error {
 	filename := args[0].(string)
 	if m.Screen.Texture == nil {
-		m.event(ErrorEvent, "no screen to snapshot")
-		return
+		return errors.New("no screen to snapshot")
 	}
 
 	r := m.Ctx.Renderer
@@ -373,13 +453,11 @@ func (m *Mach) cmdSnapshot(args ...interface{}) {
 	if m.snapT == nil {
 		tex, err := r.CreateTexture(sdl.PIXELFORMAT_RGBA8888, sdl.TEXTUREACCESS_TARGET, w, h)
 		if err != nil {
-			m.event(ErrorEvent, err.Error())
-			return
+			return err
 		}
 		surf, err := sdl.CreateRGBSurface(0, w, h, 32, 0, 0, 0, 0)
 		if err != nil {
-			m.event(ErrorEvent, err.Error())
-			return
+			return err
 		}
 		m.snapT = tex
 		m.snapS = surf
@@ -393,35 +471,65 @@ func (m *Mach) cmdSnapshot(args ...interface{}) {
 	r.ReadPixels(nil, m.snapS.Format.Format, ptr, int(m.snapS.Pitch))
 
 	if err := img.SavePNG(m.snapS, filename); err != nil {
-		m.event(ErrorEvent, fmt.Sprintf("unable to save snapshot: %v", err))
-		return
+		return fmt.Errorf("unable to save snapshot: %v", err)
 	}
+	return nil
 }
 
-func (m *Mach) cmdTrace(args ...interface{}) {
+func (m *Mach) cmdTrace(args ...interface{}) error {
 	name := args[0].(string)
 	if len(args) == 1 {
 		m.tracing[name] = !m.tracing[name]
-		return
+		return nil
 	}
 	v, ok := args[1].(bool)
 	if !ok {
-		m.event(ErrorEvent, fmt.Sprintf("invalid trace mode: %v", args[0]))
-		return
+		return fmt.Errorf("invalid trace mode: %v", args[0])
 	}
 	m.tracing[name] = v
+	return nil
 }
 
-func (m *Mach) cmdTraceAll(args ...interface{}) {
+func (m *Mach) cmdTraceAll(args ...interface{}) error {
 	v, ok := args[0].(bool)
 	if !ok {
-		m.event(ErrorEvent, fmt.Sprintf("invalid trace mode: %v", args[0]))
-		return
+		return fmt.Errorf("invalid trace mode: %v", args[0])
 	}
 	fmt.Printf("trace all: %v", v)
 	for name := range m.tracing {
 		m.tracing[name] = v
 	}
+	return nil
+}
+
+// cmdUntil starts the machine and runs until the stop function returns
+// true. The stop function is called after each instruction executed by the
+// named CPU; Mach.At is the address of that instruction. The stop function
+// acts as a temporary breakpoint and is removed once the machine stops for
+// any reason.
+func (m *Mach) cmdUntil(args ...interface{}) error {
+	name := args[0].(string)
+	if _, ok := m.CPU[name]; !ok {
+		return fmt.Errorf("no such cpu: %v", name)
+	}
+	m.untilCPU = name
+	m.until = args[1].(func() bool)
+	m.setStatus(Run)
+	return nil
+}
+
+// Cycles returns the number of clock cycles executed by the named CPU. If
+// the CPU does not exist or does not count cycles, false is returned.
+func (m *Mach) Cycles(name string) (uint64, bool) {
+	cpu, ok := m.CPU[name]
+	if !ok {
+		return 0, false
+	}
+	c, ok := cpu.(CPUCycles)
+	if !ok {
+		return 0, false
+	}
+	return c.Cycles(), true
 }
 
 func (m *Mach) event(evt MachEvent, args ...interface{}) {
@@ -433,6 +541,9 @@ func (m *Mach) event(evt MachEvent, args ...interface{}) {
 
 func (m *Mach) setStatus(s Status) {
 	m.Status = s
+	if s != Run {
+		m.until = nil
+	}
 	m.event(StatusEvent, s)
 }
 
@@ -0,0 +1,29 @@
+package rcs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMachCall(t *testing.T) {
+	m := &Mach{}
+	m.Init()
+	go m.Run()
+
+	errDo := errors.New("do")
+	_, err := m.Call(MachDo, func() error { return errDo })
+	if err != errDo {
+		t.Errorf("have %v, want %v", err, errDo)
+	}
+	_, err = m.Call(MachTrace, "cpu", "x")
+	if err == nil {
+		t.Errorf("expected error")
+	}
+	if _, err := m.Call(MachQuit); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = m.Call(MachDo, func() error { return nil })
+	if err != ErrNotRunning {
+		t.Errorf("have %v, want %v", err, ErrNotRunning)
+	}
+}
@@ -11,6 +11,19 @@ import (
 	"strings"
 )
 
+// UnmappedPolicy determines what happens when an address without a mapping
+// is read or written.
+type UnmappedPolicy int
+
+const (
+	// UnmappedWarn logs a warning. Reads return zero.
+	UnmappedWarn UnmappedPolicy = iota
+	// UnmappedIgnore silently ignores the access. Reads return zero.
+	UnmappedIgnore
+	// UnmappedPanic panics. Useful in tests to find gaps in a memory map.
+	UnmappedPanic
+)
+
 type MemoryEvent struct {
 	Read  bool
 	Bank  int
@@ -28,7 +41,8 @@ To create a memory space with a 16 line address bus and a single bank, use:
 
 This struct is just a container for the address space and has no actual
 memory mapped to it yet. Reads or writes to an unmapped address emit a
-warning through the standard logger.
+warning through the standard logger. This can be changed by setting the
+Unmapped policy.
 
 Single values are mapped for read/write access using the MapRW method. This is
 useful for mapping ports or registers of a device into the address space. In
@@ -52,6 +66,18 @@ A range of addresses can map to the same value with:
 		mem.MapWO(i, &watchdogReset)
 	}
 
+Address maps that follow hardware documentation are easier to declare as
+a table of regions with MapRegions. Each region covers a range of addresses
+and can be repeated with a mirror mask when address lines are not decoded.
+In this example from Pac-Man, address line A15 is not connected so video
+memory also appears at 0xc000 and port IN0 appears at every address from
+0x5000 to 0x503f:
+
+	mem.MapRegions([]rcs.Region{
+		{Start: 0x4000, End: 0x43ff, Mirror: 0x8000, RAM: tileMemory},
+		{Start: 0x5000, End: 0x5000, Mirror: 0x003f, RO: &portIN0},
+	})
+
 Large blocks can be mapped by passing in a uint8 slice using MapRAM
 for read/write access and MapROM for read-only access. The following example
 maps a 16KB block of ROM to 0x0000 - 0x3fff and a 48KB block of RAM
@@ -89,17 +115,30 @@ in bank 0 and full access to the RAM in bank 1:
 
 	mem.SetBank(1)
 	mem.MapRAM(0x0000, ram)
+
+Reading a device register may change the state of the device. Tools that
+inspect memory, like the monitor, should use Peek instead of Read. Values
+mapped with MapRAM, MapROM, and MapRO can always be peeked. Devices mapped
+with MapLoad should also provide a side-effect free function with MapPeek:
+
+	mem.MapLoad(0xd601, vdc.ReadData)
+	mem.MapPeek(0xd601, vdc.PeekData)
 */
 type Memory struct {
 	Name     string
 	MaxAddr  int               // maximum valid address
 	Callback func(MemoryEvent) // function called on watch events
 	NBank    int               // number of banks
+	Unmapped UnmappedPolicy    // action on access to an unmapped address
+	CDL      *CDL              // code/data log, if logging
 
 	// read and write functions for each bank
 	reads  [][]Load8
 	writes [][]Store8
 
+	// side-effect free read functions for each bank
+	peeks [][]Load8
+
 	// previous read and write functions are stored here during watches
 	preads  [][]Load8
 	pwrites [][]Store8
@@ -110,6 +149,7 @@ type Memory struct {
 	// read and write functions for the selected bank
 	read  []Load8
 	write []Store8
+	peek  []Load8
 }
 
 // NewMemory creates a memory space of uint8 values that are addressable
@@ -126,42 +166,103 @@ func NewMemory(banks int, size int) *Memory {
 		NBank:   banks,
 		reads:   make([][]Load8, banks, banks),
 		writes:  make([][]Store8, banks, banks),
+		peeks:   make([][]Load8, banks, banks),
 		preads:  make([][]Load8, banks, banks),
 		pwrites: make([][]Store8, banks, banks),
 	}
 	for b := 0; b < banks; b++ {
 		mem.reads[b] = make([]Load8, size, size)
 		mem.writes[b] = make([]Store8, size, size)
+		mem.peeks[b] = make([]Load8, size, size)
 		mem.preads[b] = make([]Load8, size, size)
 		mem.pwrites[b] = make([]Store8, size, size)
 	}
 	mem.read = mem.reads[0]
 	mem.write = mem.writes[0]
+	mem.peek = mem.peeks[0]
 	mem.Callback = func(MemoryEvent) {}
 	return mem
 }
 
 // Read returns the 8-bit value at the given address.
 func (m *Memory) Read(addr int) uint8 {
+	if m.CDL != nil {
+		m.CDL.Flags[addr] |= CDLRead
+	}
+	return m.load(addr)
+}
+
+// Fetch returns the 8-bit value at the given address as part of an
+// instruction. It is the same as Read except that the code/data log
+// records the access as an opcode or operand instead of as data.
+func (m *Memory) Fetch(addr int, opcode bool) uint8 {
+	if m.CDL != nil {
+		m.CDL.fetch(addr, opcode)
+	}
+	return m.load(addr)
+}
+
+func (m *Memory) load(addr int) uint8 {
 	if m.read[addr] == nil {
-		log.Printf("(!) %v: unmapped read, bank %v, addr %v", m.Name,
-			X(m.bank), X(addr))
+		m.unmapped(fmt.Sprintf("unmapped read, bank %v, addr %v",
+			X(m.bank), X(addr)))
 		return 0
 	}
 	v := m.read[addr]()
 	return v
 }
 
+// Peek returns the 8-bit value at the given address without causing any
+// side effects. This is the read path used by debugging tools such as
+// memory dumps, disassemblers, and tracers. Watches are not triggered and
+// devices are not notified of the access. If the address is mapped with
+// MapLoad and no side-effect free function was provided with MapPeek, zero
+// is returned. Unmapped addresses also return zero without a warning.
+func (m *Memory) Peek(addr int) uint8 {
+	if m.peek[addr] == nil {
+		return 0
+	}
+	return m.peek[addr]()
+}
+
+// PeekLE returns the 16-bit value at addr and addr+1 stored in little endian
+// byte order without causing any side effects.
+func (m *Memory) PeekLE(addr int) int {
+	lo := int(m.Peek(addr))
+	hi := int(m.Peek(addr + 1))
+	return hi<<8 + lo
+}
+
 // Write sets the 8-bit value at the given address.
 func (m *Memory) Write(addr int, val uint8) {
+	if m.CDL != nil {
+		m.CDL.Flags[addr] |= CDLWrite
+	}
+	m.Poke(addr, val)
+}
+
+// Poke sets the 8-bit value at the given address like Write but is not
+// recorded in the code/data log. Use this for values that are put into
+// memory from outside of the running program, such as cheats, so that the
+// log only shows what the program itself does.
+func (m *Memory) Poke(addr int, val uint8) {
 	if m.write[addr] == nil {
-		log.Printf("(!) %v: unmapped write, bank %v, addr %v, val %v",
-			m.Name, X(m.bank), X(addr), X8(val))
+		m.unmapped(fmt.Sprintf("unmapped write, bank %v, addr %v, val %v",
+			X(m.bank), X(addr), X8(val)))
 		return
 	}
 	m.write[addr](val)
 }
 
+func (m *Memory) unmapped(msg string) {
+	switch m.Unmapped {
+	case UnmappedWarn:
+		log.Printf("(!) %v: %v", m.Name, msg)
+	case UnmappedPanic:
+		panic(fmt.Sprintf("%v: %v", m.Name, msg))
+	}
+}
+
 // WriteN sets multiple 8-bit values starting with the given address.
 func (m *Memory) WriteN(addr int, values ...uint8) {
 	for i, val := range values {
@@ -193,6 +294,7 @@ func (m *Memory) MapRAM(addr int, ram []uint8) {
 		j := i
 		m.read[addr+i] = func() uint8 { return ram[j] }
 		m.write[addr+i] = func(v uint8) { ram[j] = v }
+		m.peek[addr+i] = m.read[addr+i]
 	}
 }
 
@@ -206,6 +308,7 @@ func (m *Memory) MapROM(addr int, rom []uint8) {
 	for i := 0; i < len(rom); i++ {
 		j := i
 		m.read[addr+i] = func() uint8 { return rom[j] }
+		m.peek[addr+i] = m.read[addr+i]
 	}
 }
 
@@ -220,6 +323,7 @@ func (m *Memory) MapRW(addr int, b *uint8) {
 // already a read mapping, it is replaced. Write mappings are not altered.
 func (m *Memory) MapRO(addr int, b *uint8) {
 	m.read[addr] = func() uint8 { return *b }
+	m.peek[addr] = m.read[addr]
 }
 
 // MapWO adds a write mapping to the given 8-bit value at addr. If there is
@@ -232,8 +336,21 @@ func (m *Memory) MapWO(addr int, b *uint8) {
 // read from, the function is invoked to get the value. If there is already a
 // read mapping for this address, it is replaced. Write mappings are not
 // altered.
+//
+// Since the function may have side effects, it is not used when the
+// address is accessed with Peek. Use MapPeek to provide a function that
+// can be used instead.
 func (m *Memory) MapLoad(addr int, load Load8) {
 	m.read[addr] = load
+	m.peek[addr] = nil
+}
+
+// MapPeek adds a side-effect free read mapping to the given function. This
+// function is invoked when the address is accessed with Peek and should
+// return the value that a read would return without changing the state of
+// the device. Read and write mappings are not altered.
+func (m *Memory) MapPeek(addr int, peek Load8) {
+	m.peek[addr] = peek
 }
 
 // MapStore adds a write mapping to the given function. When this address is
@@ -253,6 +370,7 @@ func (m *Memory) Map(startAddr int, m1 *Memory) {
 	for i, addr := 0, startAddr; addr < endAddr; i, addr = i+1, addr+1 {
 		m.read[addr] = m1.read[i]
 		m.write[addr] = m1.write[i]
+		m.peek[addr] = m1.peek[i]
 	}
 }
 
@@ -260,12 +378,126 @@ func (m *Memory) Map(startAddr int, m1 *Memory) {
 func (m *Memory) Unmap(addr int) {
 	m.read[addr] = nil
 	m.write[addr] = nil
+	m.peek[addr] = nil
 }
 
 // MapNil creates an empty read and write mapping at the address.
 func (m *Memory) MapNil(addr int) {
 	m.read[addr] = func() uint8 { return 0 }
 	m.write[addr] = func(uint8) {}
+	m.peek[addr] = m.read[addr]
+}
+
+// Region describes how a range of addresses on the bus is decoded. A table
+// of regions is mapped into memory with MapRegions.
+//
+// Each address from Start to End, inclusive, is mapped. Mirror is a mask of
+// address lines that are not decoded; the region is repeated at every
+// address that can be formed by setting any combination of those bits.
+// Start and End should have the mirror bits clear.
+//
+// Handlers receive the decoded offset of the address. The offset is the
+// distance from Start with Mask applied, if Mask is not zero. Mirrored
+// addresses decode to the same offset. A device with four registers that
+// repeat across a 256 byte range would use a Mask of 0x03.
+//
+// The remaining fields work the same as the Map method with the similar
+// name and are applied in the order listed. Any number can be used
+// together. For RAM, ROM, and Mem, addresses with an offset beyond the end
+// of the slice or memory are not changed. A nil ROM does not change any
+// mappings, just like MapROM.
+type Region struct {
+	Name   string // for documentation only
+	Start  int
+	End    int
+	Mirror int
+	Mask   int
+
+	Unmap bool    // remove existing mappings
+	Nil   bool    // ignore writes, reads return zero
+	RAM   []uint8 // read/write access to slice at offset
+	ROM   []uint8 // read access to slice at offset
+	Mem   *Memory // copy bindings from this memory at offset
+	RW    *uint8
+	RO    *uint8
+	WO    *uint8
+	Load  LoadAt // may have side effects, not used by Peek
+	Peek  LoadAt // side-effect free version of Load
+	Store StoreAt
+}
+
+// MapRegions maps each region in the order given. Later regions replace
+// mappings made by earlier ones. A region that reaches outside of memory,
+// including through a mirror, is a mistake in the memory map and causes a
+// panic that names the region.
+func (m *Memory) MapRegions(regions []Region) {
+	for _, r := range regions {
+		if r.Start < 0 || r.End > m.MaxAddr || r.Start > r.End {
+			panic(fmt.Sprintf("%v: region %q: invalid range %v-%v",
+				m.Name, r.Name, X(r.Start), X(r.End)))
+		}
+		for addr := r.Start; addr <= r.End; addr++ {
+			offset := addr - r.Start
+			if r.Mask != 0 {
+				offset &= r.Mask
+			}
+			// Iterate over every subset of the mirror bits, including
+			// the empty set.
+			mirror := r.Mirror
+			for {
+				if addr|mirror > m.MaxAddr {
+					panic(fmt.Sprintf("%v: region %q: mirror %v of %v is beyond %v",
+						m.Name, r.Name, X(r.Mirror), X(addr), X(m.MaxAddr)))
+				}
+				m.mapRegion(addr|mirror, offset, r)
+				if mirror == 0 {
+					break
+				}
+				mirror = (mirror - 1) & r.Mirror
+			}
+		}
+	}
+}
+
+func (m *Memory) mapRegion(addr int, offset int, r Region) {
+	if r.Unmap {
+		m.Unmap(addr)
+	}
+	if r.Nil {
+		m.MapNil(addr)
+	}
+	if r.RAM != nil && offset < len(r.RAM) {
+		m.MapRAM(addr, r.RAM[offset:offset+1])
+	}
+	if r.ROM != nil && offset < len(r.ROM) {
+		m.MapROM(addr, r.ROM[offset:offset+1])
+	}
+	if r.Mem != nil && offset <= r.Mem.MaxAddr {
+		m.read[addr] = r.Mem.read[offset]
+		m.write[addr] = r.Mem.write[offset]
+		m.peek[addr] = r.Mem.peek[offset]
+	}
+	if r.RW != nil {
+		m.MapRW(addr, r.RW)
+	}
+	if r.RO != nil {
+		m.MapRO(addr, r.RO)
+	}
+	if r.WO != nil {
+		m.MapWO(addr, r.WO)
+	}
+	if r.Load != nil {
+		load := r.Load
+		m.MapLoad(addr, func() uint8 { return load(offset) })
+	}
+	if r.Peek != nil {
+		peek := r.Peek
+		m.MapPeek(addr, func() uint8 { return peek(offset) })
+	}
+	if r.Store != nil {
+		store := r.Store
+		m.MapStore(addr, func(v uint8) { store(offset, v) })
+	}
 }
 
 // WatchRO creates a read watch on the address. When a value is read to that
@@ -338,13 +570,15 @@ func (m *Memory) SetBank(bank int) {
 	m.bank = bank
 	m.read = m.reads[bank]
 	m.write = m.writes[bank]
+	m.peek = m.peeks[bank]
 }
 
 // Pointer points to a location in memory.
 type Pointer struct {
-	addr int     // Current position.
-	Mask int     // Address mask
-	Mem  *Memory // Memory view
+	addr    int     // Current position.
+	Mask    int     // Address mask
+	Mem     *Memory // Memory view
+	Peeking bool    // Read with Memory.Peek to avoid side effects
 }
 
 // NewPointer creates pointer at address zero on the provided memory.
@@ -363,7 +597,7 @@ func (p *Pointer) SetAddr(addr int) {
 // Fetch returns the byte at current position as an 8-bit value and advances
 // the pointer by one.
 func (p *Pointer) Fetch() uint8 {
-	value := p.Mem.Read(p.addr)
+	value := p.read()
 	p.addr = (p.addr + 1) & p.Mask
 	return value
 }
@@ -371,7 +605,7 @@ func (p *Pointer) Fetch() uint8 {
 // Peek returns the byte at the current position as an 8-bit value. The
 // pointer is not moved.
 func (p *Pointer) Peek() uint8 {
-	return p.Mem.Read(p.addr)
+	return p.read()
 }
 
 // FetchLE returns the next two bytes as a 16-bit value stored in little
@@ -388,6 +622,13 @@ func (p *Pointer) Put(value uint8) {
 	p.addr = (p.addr + 1) & p.Mask
 }
 
+func (p *Pointer) read() uint8 {
+	if p.Peeking {
+		return p.Mem.Peek(p.addr)
+	}
+	return p.Mem.Read(p.addr)
+}
+
 // PutN calls Put for each value.
 func (p *Pointer) PutN(values ...uint8) {
 	for _, value := range values {
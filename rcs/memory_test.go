@@ -217,6 +217,23 @@ func TestWriteN(t *testing.T) {
 	}
 }
 
+func TestMemoryPoke(t *testing.T) {
+	mem := NewMemory(1, 4)
+	ram := make([]uint8, 4, 4)
+	mem.MapRAM(0, ram)
+	mem.CDL = NewCDL(4)
+
+	mem.Poke(1, 10)
+	mem.Write(2, 11)
+	if ram[1] != 10 || ram[2] != 11 {
+		t.Errorf("have %v", ram)
+	}
+	want := []CDLFlag{0, 0, CDLWrite, 0}
+	if !reflect.DeepEqual(mem.CDL.Flags, want) {
+		t.Errorf("\n have: %v \n want: %v", mem.CDL.Flags, want)
+	}
+}
+
 func TestMemoryBank(t *testing.T) {
 	mem := NewMemory(2, 2)
 	ram0 := []uint8{10, 0}
@@ -255,6 +272,225 @@ func TestMemoryMirror(t *testing.T) {
 	}
 }
 
+func TestMemoryUnmappedIgnore(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetOutput(os.Stderr)
+	}()
+
+	mem := NewMemory(1, 0x10000)
+	mem.Unmapped = UnmappedIgnore
+	mem.Write(0x1234, 0xaa)
+	mem.Read(0x1234)
+	if buf.Len() != 0 {
+		t.Errorf("unexpected log output: %v", buf.String())
+	}
+}
+
+func TestMemoryUnmappedPanic(t *testing.T) {
+	mem := NewMemory(1, 0x10000)
+	mem.Unmapped = UnmappedPanic
+	defer func() {
+		have := recover()
+		want := "mem: unmapped read, bank $0, addr $1234"
+		if have != want {
+			t.Errorf("\n have: %v \n want: %v", have, want)
+		}
+	}()
+	mem.Read(0x1234)
+	t.Errorf("expected panic")
+}
+
+func TestMapRegionsMirror(t *testing.T) {
+	mem := NewMemory(1, 0x10000)
+	ram := make([]uint8, 0x400, 0x400)
+	var in0 uint8
+	mem.MapRegions([]Region{
+		{Start: 0x4000, End: 0x43ff, Mirror: 0x8000, RAM: ram},
+		{Start: 0x5000, End: 0x5000, Mirror: 0x0003, RO: &in0},
+	})
+
+	mem.Write(0xc010, 0x12)
+	if ram[0x10] != 0x12 {
+		t.Errorf("write to mirror: have %02x want 12", ram[0x10])
+	}
+	if have := mem.Read(0x4010); have != 0x12 {
+		t.Errorf("read base: have %02x want 12", have)
+	}
+
+	in0 = 0x34
+	for _, addr := range []int{0x5000, 0x5001, 0x5002, 0x5003} {
+		if have := mem.Read(addr); have != 0x34 {
+			t.Errorf("in0 at %04x: have %02x want 34", addr, have)
+		}
+	}
+	if mem.read[0x5004] != nil {
+		t.Errorf("mirror should not extend beyond mask")
+	}
+}
+
+func TestMapRegionsOutOfRange(t *testing.T) {
+	tests := []struct {
+		region Region
+		want   string
+	}{
+		{Region{Name: "io", Start: 0xd000, End: 0x10000, Nil: true},
+			`mem: region "io": invalid range $d000-$10000`},
+		{Region{Name: "io", Start: -1, End: 0x10, Nil: true},
+			`mem: region "io": invalid range $-1-$10`},
+		{Region{Name: "io", Start: 0x20, End: 0x10, Nil: true},
+			`mem: region "io": invalid range $20-$10`},
+		{Region{Name: "ram", Start: 0x4000, End: 0x43ff, Mirror: 0x18000, Nil: true},
+			`mem: region "ram": mirror $18000 of $4000 is beyond $ffff`},
+	}
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			mem := NewMemory(1, 0x10000)
+			mem.Name = "mem"
+			defer func() {
+				have := recover()
+				if have != test.want {
+					t.Errorf("\n have: %v \n want: %v", have, test.want)
+				}
+			}()
+			mem.MapRegions([]Region{test.region})
+		})
+	}
+}
+
+func TestMapRegionsMask(t *testing.T) {
+	mem := NewMemory(1, 0x10000)
+	var loads, stores []int
+	var peeks int
+	mem.MapRegions([]Region{{
+		Start: 0xd600,
+		End:   0xd6ff,
+		Mask:  0x03,
+		Load:  func(offset int) uint8 { loads = append(loads, offset); return 0 },
+		Peek:  func(offset int) uint8 { peeks++; return uint8(offset) },
+		Store: func(offset int, v uint8) { stores = append(stores, offset) },
+	}})
+
+	mem.Read(0xd600)
+	mem.Read(0xd605)
+	mem.Write(0xd6ff, 0)
+	if have := mem.Peek(0xd612); have != 2 {
+		t.Errorf("peek: have %v want 2", have)
+	}
+	if want := []int{0, 1}; !reflect.DeepEqual(loads, want) {
+		t.Errorf("loads: have %v want %v", loads, want)
+	}
+	if want := []int{3}; !reflect.DeepEqual(stores, want) {
+		t.Errorf("stores: have %v want %v", stores, want)
+	}
+	if peeks != 1 {
+		t.Errorf("peeks: have %v want 1", peeks)
+	}
+}
+
+func TestMapRegionsOrder(t *testing.T) {
+	mem := NewMemory(1, 0x10000)
+	ram := make([]uint8, 0x10000, 0x10000)
+	rom := []uint8{0xaa, 0xbb}
+	mem.MapRegions([]Region{
+		{Start: 0x0000, End: 0xffff, RAM: ram},
+		{Start: 0x1000, End: 0x1fff, ROM: rom},
+		{Start: 0x2000, End: 0x2fff, ROM: nil},
+		{Start: 0x3000, End: 0x3fff, Unmap: true},
+		{Start: 0x4000, End: 0x4fff, Unmap: true, Nil: true},
+	})
+
+	mem.Write(0x1000, 0x11)
+	if have := mem.Read(0x1000); have != 0xaa {
+		t.Errorf("rom overlay: have %02x want aa", have)
+	}
+	if ram[0x1000] != 0x11 {
+		t.Errorf("rom overlay should write to ram")
+	}
+	if have := mem.Read(0x1002); have != 0x00 {
+		t.Errorf("beyond rom: have %02x want ram value 00", have)
+	}
+	mem.Write(0x2000, 0x22)
+	if have := mem.Read(0x2000); have != 0x22 {
+		t.Errorf("nil rom: have %02x want 22", have)
+	}
+	if mem.read[0x3000] != nil || mem.write[0x3000] != nil {
+		t.Errorf("expected unmapped")
+	}
+	mem.Write(0x4000, 0x44)
+	if have := mem.Read(0x4000); have != 0 {
+		t.Errorf("nil mapping: have %02x want 00", have)
+	}
+}
+
+func TestMapRegionsMem(t *testing.T) {
+	io := NewMemory(1, 0x1000)
+	ioRAM := make([]uint8, 0x1000, 0x1000)
+	io.MapRAM(0, ioRAM)
+
+	mem := NewMemory(1, 0x10000)
+	mem.MapRegions([]Region{
+		{Start: 0xd000, End: 0xdfff, Mem: io},
+	})
+	mem.Write(0xd020, 0x0e)
+	if ioRAM[0x20] != 0x0e {
+		t.Errorf("have %02x want 0e", ioRAM[0x20])
+	}
+	if have := mem.Peek(0xd020); have != 0x0e {
+		t.Errorf("peek: have %02x want 0e", have)
+	}
+}
+
+func TestMemoryPeek(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetFlags(0)
+	log.SetOutput(&buf)
+	defer func() {
+		log.SetFlags(log.LstdFlags)
+		log.SetOutput(os.Stderr)
+	}()
+
+	reads := 0
+	value := uint8(33)
+	mem := NewMemory(1, 5)
+	mem.MapRAM(0, []uint8{11})
+	mem.MapRO(1, &value)
+	mem.MapLoad(2, func() uint8 { reads++; return 44 })
+	mem.MapPeek(2, func() uint8 { return 44 })
+	mem.MapLoad(3, func() uint8 { reads++; return 55 })
+
+	have := []uint8{mem.Peek(0), mem.Peek(1), mem.Peek(2), mem.Peek(3), mem.Peek(4)}
+	want := []uint8{11, 33, 44, 0, 0}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %v \n want: %v", have, want)
+	}
+	if reads != 0 {
+		t.Errorf("expected no reads, got %v", reads)
+	}
+	if buf.String() != "" {
+		t.Errorf("unexpected log output: %v", buf.String())
+	}
+}
+
+func TestMemoryPeekWatch(t *testing.T) {
+	events := 0
+	mem := NewMemory(1, 2)
+	mem.MapRAM(0, []uint8{0xcd, 0xab})
+	mem.Callback = func(MemoryEvent) { events++ }
+	mem.WatchRW(0)
+	mem.WatchRW(1)
+
+	have := mem.PeekLE(0)
+	want := 0xabcd
+	if have != want {
+		t.Errorf("\n have: %04x \n want: %04x", have, want)
+	}
+	if events != 0 {
+		t.Errorf("expected no watch events, got %v", events)
+	}
+}
+
 func benchmarkMemoryW(count int, b *testing.B) {
 	mem := NewMemory(1, count)
 	mem.MapRAM(0, make([]uint8, count, count))
@@ -334,6 +570,24 @@ func TestPeek(t *testing.T) {
 	}
 }
 
+func TestPointerPeeking(t *testing.T) {
+	reads := 0
+	mem := NewMemory(1, 2)
+	mem.MapLoad(0, func() uint8 { reads++; return 44 })
+	mem.MapPeek(0, func() uint8 { return 44 })
+
+	p := NewPointer(mem)
+	p.Peeking = true
+	have := p.Fetch()
+	want := uint8(44)
+	if have != want {
+		t.Errorf("\n have: %v \n want: %v", have, want)
+	}
+	if reads != 0 {
+		t.Errorf("expected no reads, got %v", reads)
+	}
+}
+
 func TestFetchLE(t *testing.T) {
 	mem := NewMemory(1, 10)
 	mem.MapRAM(0, make([]uint8, 10, 10))
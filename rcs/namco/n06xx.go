@@ -9,6 +9,7 @@ import (
 type N06XX struct {
 	DeviceR [4]rcs.Load8
 	DeviceW [4]rcs.Store8
+	DeviceP [4]rcs.Load8 // side-effect free reads, optional
 
 	ctrl    uint8
 	elapsed int
@@ -37,76 +38,106 @@ func NewN06XX() *N06XX {
 	return n
 }
 
-func (n *N06XX) WriteData(addr int) rcs.Store8 {
-	return func(v uint8) {
-		if n.ctrl&0x10 != 0 {
-			return
-		}
-		if n.WatchDataW {
-			log.Printf("n06xx data write($%04x) => $%02x\n", addr, v)
-		}
-		dev := n.ctrl & 0x03
-		switch dev {
-		case 1 << 0:
-			n.DeviceW[0](v)
-		case 1 << 1:
-			n.DeviceW[1](v)
-		case 1 << 2:
-			n.DeviceW[2](v)
-		case 1 << 3:
-			n.DeviceW[3](v)
-		}
+// WriteData writes a value to the selected device. The offset is relative
+// to the start of the data region.
+func (n *N06XX) WriteData(offset int, v uint8) {
+	if n.ctrl&0x10 != 0 {
+		return
+	}
+	if n.WatchDataW {
+		log.Printf("n06xx data write($%04x) => $%02x\n", offset, v)
+	}
+	dev := n.ctrl & 0x03
+	switch dev {
+	case 1 << 0:
+		n.DeviceW[0](v)
+	case 1 << 1:
+		n.DeviceW[1](v)
+	case 1 << 2:
+		n.DeviceW[2](v)
+	case 1 << 3:
+		n.DeviceW[3](v)
 	}
 }
 
-func (n *N06XX) ReadData(addr int) rcs.Load8 {
-	return func() uint8 {
-		if n.ctrl&0x10 != 0 {
-			return 0
-		}
-		dev := n.ctrl & 0x03
-		v := uint8(0xff)
-		switch dev {
-		case 1 << 0:
-			v = n.DeviceR[0]()
-		case 1 << 1:
-			v = n.DeviceR[1]()
-		case 1 << 2:
-			v = n.DeviceR[2]()
-		case 1 << 3:
-			v = n.DeviceR[3]()
-		}
-		if n.WatchDataR {
-			log.Printf("n06xx data $%02x <= read($%04x)\n", v, addr)
-		}
-		return v
+// ReadData reads a value from the selected device. The offset is relative
+// to the start of the data region.
+func (n *N06XX) ReadData(offset int) uint8 {
+	if n.ctrl&0x10 != 0 {
+		return 0
 	}
+	dev := n.ctrl & 0x03
+	v := uint8(0xff)
+	switch dev {
+	case 1 << 0:
+		v = n.DeviceR[0]()
+	case 1 << 1:
+		v = n.DeviceR[1]()
+	case 1 << 2:
+		v = n.DeviceR[2]()
+	case 1 << 3:
+		v = n.DeviceR[3]()
+	}
+	if n.WatchDataR {
+		log.Printf("n06xx data $%02x <= read($%04x)\n", v, offset)
+	}
+	return v
 }
 
-func (n *N06XX) WriteCtrl(addr int) rcs.Store8 {
-	return func(v uint8) {
-		if n.WatchCtrlW {
-			log.Printf("n06xx ctrl write($%04x) => $%02x\n", addr, v)
-		}
-		n.ctrl = v
-		if v&0x0f == 0 {
-			//n.timing = false
-		} else {
-			n.elapsed = 0
-			n.timing = true
-		}
+// PeekData returns the value that would be read from the selected device
+// without notifying the device. If the device does not have a function
+// in DeviceP, $ff is returned.
+func (n *N06XX) PeekData(offset int) uint8 {
+	if n.ctrl&0x10 != 0 {
+		return 0
+	}
+	var peek rcs.Load8
+	switch n.ctrl & 0x03 {
+	case 1 << 0:
+		peek = n.DeviceP[0]
+	case 1 << 1:
+		peek = n.DeviceP[1]
+	case 1 << 2:
+		peek = n.DeviceP[2]
+	case 1 << 3:
+		peek = n.DeviceP[3]
 	}
+	if peek == nil {
+		return 0xff
+	}
+	return peek()
 }
 
-func (n *N06XX) ReadCtrl(addr int) rcs.Load8 {
-	return func() uint8 {
-		if n.WatchCtrlR {
-			log.Printf("n06xx ctrl $%02x <= read(addr $%04x)\n", n.ctrl, addr)
-		}
-		return n.ctrl
+// WriteCtrl writes to the control register. The offset is relative to the
+// start of the control region.
+func (n *N06XX) WriteCtrl(offset int, v uint8) {
+	if n.WatchCtrlW {
+		log.Printf("n06xx ctrl write($%04x) => $%02x\n", offset, v)
+	}
+	n.ctrl = v
+	if v&0x0f == 0 {
+		//n.timing = false
+	} else {
+		n.elapsed = 0
+		n.timing = true
 	}
 }
 
+// ReadCtrl reads the control register. The offset is relative to the
+// start of the control region.
+func (n *N06XX) ReadCtrl(offset int) uint8 {
+	if n.WatchCtrlR {
+		log.Printf("n06xx ctrl $%02x <= read(addr $%04x)\n", n.ctrl, offset)
+	}
+	return n.ctrl
+}
+
+// PeekCtrl returns the value of the control register without any side
+// effects.
+func (n *N06XX) PeekCtrl(offset int) uint8 {
+	return n.ctrl
+}
+
 func (n *N06XX) Next() {
 	if n.timing {
 		n.elapsed++
@@ -23,3 +23,9 @@ func (n *N51XX) Read() uint8 {
 	}
 	return 0
 }
+
+// Peek returns the value that would be returned by Read without any side
+// effects.
+func (n *N51XX) Peek() uint8 {
+	return 0
+}
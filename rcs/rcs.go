@@ -61,6 +61,14 @@ type Load8 func() uint8
 // Store8 is a function which stores an unsiged 8-bit value
 type Store8 func(uint8)
 
+// LoadAt is a function which loads an unsigned 8-bit value found at
+// an offset within a device or region
+type LoadAt func(int) uint8
+
+// StoreAt is a function which stores an unsigned 8-bit value at an offset
+// within a device or region
+type StoreAt func(int, uint8)
+
 // Load is a function which loads an integer value
 type Load func() int
 
@@ -0,0 +1,290 @@
+package rcs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Instr is an instruction decoded for flow analysis.
+type Instr struct {
+	Addr  int
+	Op    string  // as shown by the disassembler
+	Bytes []uint8 // bytes that represent this instruction
+	Jumps []int   // where execution may continue other than the next instruction
+	Calls []int   // subroutines called
+	Refs  []int   // other addresses used as 16-bit operands
+	Next  bool    // execution may continue with the following instruction
+}
+
+// Syntax is the source code format of an assembler.
+//
+// Decode returns the instruction at the address or false if it is not a
+// valid instruction. Format returns the text of the instruction using
+// label to find the name of an address. It returns false when the
+// instruction cannot be written in a form that the assembler encodes to
+// the same bytes. Those instructions are written as data instead.
+type Syntax struct {
+	Name    string
+	Decode  func(mem *Memory, addr int) (Instr, bool)
+	Format  func(in Instr, label func(addr int) (string, bool)) (string, bool)
+	Org     string // format of the origin directive given the address
+	Equate  string // format of a constant given the name and address
+	Label   string // format of a label given the name
+	Byte    string // directive for 8-bit values
+	Word    string // directive for 16-bit little-endian values
+	Entries []int  // addresses that are always entry points
+	Vectors []int  // addresses of pointers to entry points
+}
+
+// CPUSource is implemented by CPUs that can generate source code for an
+// assembler. The first syntax is the default.
+type CPUSource interface {
+	Syntaxes() []*Syntax
+}
+
+// Analysis is the result of following the flow of execution through a
+// range of memory.
+type Analysis struct {
+	Start  int
+	End    int
+	Code   map[int]Instr  // instructions found, by address
+	Labels map[int]string // names of addresses referred to in the range
+
+	mem     *Memory
+	syntax  *Syntax
+	notes   map[int]string
+	data    []DataRegion
+	used    []bool       // bytes that are part of an instruction
+	words   map[int]bool // data that is a pointer
+	equates map[int]string
+}
+
+// Analyze finds the instructions between start and end, inclusive, by
+// following jumps, calls and branches from the entry points, the entry
+// points of the syntax, and the addresses found in its vectors. Everything
+// else is data.
+//
+// Names are used for labels instead of generated ones and names outside
+// of the range become constants. Regions marked as data in the annotations
+// are not followed and the values in regions of pointers are entry points.
+// Names and annotations may be nil.
+func Analyze(mem *Memory, syn *Syntax, start int, end int, entries []int, names map[int]string, a *Annotations) *Analysis {
+	an := &Analysis{
+		Start:   start,
+		End:     end,
+		Code:    make(map[int]Instr),
+		Labels:  make(map[int]string),
+		mem:     mem,
+		syntax:  syn,
+		used:    make([]bool, end-start+1),
+		words:   make(map[int]bool),
+		equates: make(map[int]string),
+	}
+	if a != nil {
+		an.notes = a.Notes
+		an.data = a.Regions
+	}
+
+	var all []int
+	all = append(all, entries...)
+	all = append(all, syn.Entries...)
+	addWord := func(addr int) {
+		if an.inRange(addr) && an.inRange(addr+1) {
+			an.words[addr] = true
+		}
+		all = append(all, mem.PeekLE(addr))
+	}
+	for _, addr := range syn.Vectors {
+		addWord(addr)
+	}
+	for _, r := range an.data {
+		if r.Kind == Pointers {
+			for addr := r.Start; addr < r.End; addr += 2 {
+				addWord(addr)
+			}
+		}
+	}
+	for _, addr := range all {
+		an.trace(addr)
+	}
+	an.label(names)
+	return an
+}
+
+func (an *Analysis) inRange(addr int) bool {
+	return addr >= an.Start && addr <= an.End
+}
+
+func (an *Analysis) isData(addr int) bool {
+	for _, r := range an.data {
+		if addr >= r.Start && addr <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// trace decodes instructions starting at the address until the flow of
+// execution leaves the range, stops, or reaches code already found.
+// Branches are followed before continuing with the next entry point.
+func (an *Analysis) trace(entry int) {
+	stack := []int{entry}
+	for len(stack) > 0 {
+		addr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !an.inRange(addr) || an.used[addr-an.Start] || an.isData(addr) {
+			continue
+		}
+		in, ok := an.syntax.Decode(an.mem, addr)
+		if !ok || !an.inRange(addr+len(in.Bytes)-1) {
+			continue
+		}
+		free := true
+		for i := range in.Bytes {
+			if an.used[addr+i-an.Start] || an.words[addr+i] || an.isData(addr+i) {
+				free = false
+			}
+		}
+		if !free {
+			continue
+		}
+		for i := range in.Bytes {
+			an.used[addr+i-an.Start] = true
+		}
+		an.Code[addr] = in
+		if in.Next {
+			stack = append(stack, addr+len(in.Bytes))
+		}
+		for i := len(in.Calls) - 1; i >= 0; i-- {
+			stack = append(stack, in.Calls[i])
+		}
+		for i := len(in.Jumps) - 1; i >= 0; i-- {
+			stack = append(stack, in.Jumps[i])
+		}
+	}
+}
+
+// label creates a label for each address that is referred to and starts
+// an instruction or is data.
+func (an *Analysis) label(names map[int]string) {
+	add := func(addr int) {
+		name, named := names[addr]
+		if !an.inRange(addr) {
+			if named {
+				an.equates[addr] = name
+			}
+			return
+		}
+		if _, code := an.Code[addr]; !code && an.used[addr-an.Start] {
+			return
+		}
+		if !named {
+			name = fmt.Sprintf("L%04x", addr)
+		}
+		an.Labels[addr] = name
+	}
+	for _, in := range an.Code {
+		for _, list := range [][]int{in.Jumps, in.Calls, in.Refs} {
+			for _, addr := range list {
+				add(addr)
+			}
+		}
+	}
+	for addr := range an.words {
+		add(an.mem.PeekLE(addr))
+	}
+	for addr := range names {
+		if an.inRange(addr) {
+			add(addr)
+		}
+	}
+}
+
+func (an *Analysis) lookup(addr int) (string, bool) {
+	if name, ok := an.Labels[addr]; ok {
+		return name, true
+	}
+	name, ok := an.equates[addr]
+	return name, ok
+}
+
+// WriteSource writes the source code for the range.
+func (an *Analysis) WriteSource(w io.Writer) error {
+	syn := an.syntax
+	out := bufio.NewWriter(w)
+	line := func(text string, comment string) {
+		if comment != "" {
+			text = fmt.Sprintf("%-32v ; %v", text, comment)
+		}
+		fmt.Fprintln(out, text)
+	}
+	line(fmt.Sprintf("; $%04x-$%04x, %v", an.Start, an.End, syn.Name), "")
+	if len(an.equates) > 0 {
+		line("", "")
+		for _, addr := range sortedAddrs(an.equates) {
+			line(fmt.Sprintf(syn.Equate, an.equates[addr], addr), "")
+		}
+	}
+	line("", "")
+	line("\t"+fmt.Sprintf(syn.Org, an.Start), "")
+
+	for addr := an.Start; addr <= an.End; {
+		if name, ok := an.Labels[addr]; ok {
+			line(fmt.Sprintf(syn.Label, name), "")
+		}
+		note := an.notes[addr]
+		if in, ok := an.Code[addr]; ok {
+			note = joinNotes(an.notes, addr, len(in.Bytes))
+			if text, ok := syn.Format(in, an.lookup); ok {
+				line("\t"+text, note)
+			} else {
+				if note == "" {
+					note = strings.Join(strings.Fields(in.Op), " ")
+				}
+				line("\t"+syn.Byte+" "+byteList(in.Bytes), note)
+			}
+			addr += len(in.Bytes)
+			continue
+		}
+		if an.words[addr] && !an.used[addr+1-an.Start] && an.Labels[addr+1] == "" {
+			value := an.mem.PeekLE(addr)
+			text, ok := an.lookup(value)
+			if !ok {
+				text = fmt.Sprintf("$%04x", value)
+			}
+			line("\t"+syn.Word+" "+text, note)
+			addr += 2
+			continue
+		}
+		values := []uint8{an.mem.Peek(addr)}
+		for next := addr + 1; next <= an.End && len(values) < 8; next++ {
+			if an.used[next-an.Start] || an.words[next] || an.Labels[next] != "" || an.notes[next] != "" {
+				break
+			}
+			values = append(values, an.mem.Peek(next))
+		}
+		line("\t"+syn.Byte+" "+byteList(values), note)
+		addr += len(values)
+	}
+	return out.Flush()
+}
+
+func byteList(values []uint8) string {
+	list := make([]string, len(values))
+	for i, v := range values {
+		list[i] = fmt.Sprintf("$%02x", v)
+	}
+	return strings.Join(list, ",")
+}
+
+func sortedAddrs(m map[int]string) []int {
+	addrs := make([]int, 0, len(m))
+	for addr := range m {
+		addrs = append(addrs, addr)
+	}
+	sort.Ints(addrs)
+	return addrs
+}
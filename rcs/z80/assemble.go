@@ -0,0 +1,332 @@
+package z80
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Operand patterns that take a value. All other patterns, such as "a",
+// "(hl)" or the "$38" of "rst $38", must be matched exactly.
+const (
+	argN     = "<n>"    // 8-bit value
+	argNN    = "<nn>"   // 16-bit value
+	argE     = "<e>"    // branch target, stored as an 8-bit displacement
+	argIndN  = "(<n>)"  // 8-bit port
+	argIndNN = "(<nn>)" // 16-bit address
+	argIX    = "(ix+<d>)"
+	argIY    = "(iy+<d>)"
+)
+
+// asmEntry is one instruction that can be assembled.
+type asmEntry struct {
+	prefix   []uint8
+	opcode   uint8
+	operands []string
+}
+
+// asmTable lists the encodings of each instruction in the order that they
+// are tried. It is built the first time that Assemble is called, instead of
+// when the package is loaded, since it takes a disassembly of every opcode.
+var (
+	asmTable = make(map[string][]asmEntry)
+	asmOnce  sync.Once
+)
+
+// asmPrefixes are searched in this order and the first entry that matches
+// an instruction is used. Instructions with more than one encoding, such
+// as "ld hl,($1234)", use the unprefixed or lowest opcode.
+var asmPrefixes = [][]uint8{
+	{},
+	{0xcb},
+	{0xed},
+	{0xdd},
+	{0xfd},
+	{0xdd, 0xcb},
+	{0xfd, 0xcb},
+}
+
+// initAsmTable fills in the table by running each opcode, in each prefix
+// table, through the disassembler with zero for every operand byte.
+// Operands that come out as zero are turned into patterns that take a
+// value, and the opcodes that only select another table are skipped. The
+// indexed bit instructions get a zero displacement byte between the prefix
+// and the opcode, as they are encoded.
+func initAsmTable() {
+	mem := rcs.NewMemory(1, 0x10)
+	mem.MapRAM(0, make([]uint8, 0x10))
+	dasm := NewDisassembler(mem)
+	for _, prefix := range asmPrefixes {
+		for opcode := 0; opcode <= 0xff; opcode++ {
+			if isPrefix(prefix, uint8(opcode)) {
+				continue
+			}
+			code := append([]uint8{}, prefix...)
+			if len(prefix) == 2 {
+				code = append(code, 0)
+			}
+			code = append(code, uint8(opcode), 0, 0, 0)
+			mem.WriteN(0, code...)
+			dasm.SetPC(0)
+			s := dasm.NextStmt()
+			if strings.HasPrefix(s.Op, "?") {
+				continue
+			}
+			inst, operands := splitInst(s.Op)
+			for i, operand := range operands {
+				operands[i] = operandPattern(inst, operand)
+			}
+			asmTable[inst] = append(asmTable[inst], asmEntry{
+				prefix:   prefix,
+				opcode:   uint8(opcode),
+				operands: operands,
+			})
+		}
+	}
+}
+
+// isPrefix returns true if the opcode selects another table instead of
+// being an instruction.
+func isPrefix(prefix []uint8, opcode uint8) bool {
+	switch opcode {
+	case 0xcb, 0xdd, 0xed, 0xfd:
+		return len(prefix) == 0 || (len(prefix) == 1 && prefix[0] != 0xcb)
+	}
+	return false
+}
+
+// operandPattern returns the pattern for an operand as written by the
+// disassembler with values of zero.
+func operandPattern(inst string, operand string) string {
+	switch {
+	case inst == "rst":
+		return operand
+	case inst == "jr" || inst == "djnz":
+		if strings.HasPrefix(operand, "$") {
+			return argE
+		}
+	case operand == "$00":
+		return argN
+	case strings.HasPrefix(operand, "$"):
+		return argNN
+	case operand == "($00)":
+		return argIndN
+	case operand == "($0000)":
+		return argIndNN
+	case operand == "(ix+$00)":
+		return argIX
+	case operand == "(iy+$00)":
+		return argIY
+	}
+	return operand
+}
+
+// splitInst returns the instruction and the list of operands with the
+// spaces removed.
+func splitInst(line string) (string, []string) {
+	fields := strings.Fields(strings.ToLower(line))
+	if len(fields) == 0 {
+		return "", nil
+	}
+	operands := strings.Join(fields[1:], "")
+	if operands == "" {
+		return fields[0], []string{}
+	}
+	return fields[0], strings.Split(operands, ",")
+}
+
+// Assemble returns the machine code, with any $cb, $dd, $ed or $fd prefix,
+// for one instruction written in the syntax used by the disassembler, such
+// as "ld a,($4e00)" or "set 7,(iy+$05)". The address is where the
+// instruction will be stored and is used to compute the displacement of
+// "jr" and "djnz". Values are hexadecimal with a "$" prefix or decimal.
+//
+// Indexed operands are written as "(ix+d)", "(ix-d)" or "(ix)" for a
+// displacement of zero. Parentheses around a value are an address, or a
+// port for "in" and "out", and not a grouping. When the same instruction
+// has more than one encoding, the shortest one is used: "ld hl,($1234)"
+// is $2a and not $ed $6b. The undocumented instructions that use the index
+// register halves, such as "ld ixh,$05", are accepted as well.
+func Assemble(addr int, line string) ([]uint8, error) {
+	asmOnce.Do(initAsmTable)
+	inst, operands := splitInst(line)
+	if inst == "" {
+		return nil, fmt.Errorf("no instruction")
+	}
+	entries, ok := asmTable[inst]
+	if !ok {
+		return nil, fmt.Errorf("invalid instruction: %v", inst)
+	}
+	var valueErr error
+	for _, entry := range entries {
+		code, err := entry.assemble(addr, operands)
+		if err != nil && valueErr == nil {
+			valueErr = err
+		}
+		if code != nil {
+			return code, err
+		}
+	}
+	if valueErr != nil {
+		return nil, valueErr
+	}
+	return nil, fmt.Errorf("invalid operands: %v", strings.TrimSpace(line))
+}
+
+// assemble returns the code for the instruction if the operands match the
+// entry. If the operands match but a value is out of range, the code is
+// nil and an error is returned.
+func (a asmEntry) assemble(addr int, operands []string) ([]uint8, error) {
+	if len(operands) != len(a.operands) {
+		return nil, nil
+	}
+	var disp []uint8
+	var args []uint8
+	target := -1
+	for i, pattern := range a.operands {
+		operand := operands[i]
+		switch pattern {
+		case argN:
+			v, ok := parseValue(operand)
+			if !ok {
+				return nil, nil
+			}
+			if v < -0x80 || v > 0xff {
+				return nil, fmt.Errorf("invalid value: %v", operand)
+			}
+			args = append(args, uint8(v))
+		case argNN, argE:
+			v, ok := parseValue(operand)
+			if !ok {
+				return nil, nil
+			}
+			if v < 0 || v > 0xffff {
+				return nil, fmt.Errorf("invalid value: %v", operand)
+			}
+			if pattern == argE {
+				target = v
+				args = append(args, 0)
+			} else {
+				args = append(args, uint8(v), uint8(v>>8))
+			}
+		case argIndN, argIndNN:
+			if !strings.HasPrefix(operand, "(") || !strings.HasSuffix(operand, ")") {
+				return nil, nil
+			}
+			v, ok := parseValue(operand[1 : len(operand)-1])
+			if !ok {
+				return nil, nil
+			}
+			if v < 0 || (pattern == argIndN && v > 0xff) || v > 0xffff {
+				return nil, fmt.Errorf("invalid value: %v", operand)
+			}
+			args = append(args, uint8(v))
+			if pattern == argIndNN {
+				args = append(args, uint8(v>>8))
+			}
+		case argIX, argIY:
+			d, ok, err := parseIndex(pattern[1:3], operand)
+			if !ok || err != nil {
+				return nil, err
+			}
+			disp = append(disp, d)
+		default:
+			if !matchLiteral(pattern, operand) {
+				return nil, nil
+			}
+		}
+	}
+
+	code := append([]uint8{}, a.prefix...)
+	if len(a.prefix) == 2 {
+		// indexed bit instructions have the displacement before the opcode
+		code = append(code, disp...)
+		code = append(code, a.opcode)
+		return code, nil
+	}
+	code = append(code, a.opcode)
+	code = append(code, disp...)
+	code = append(code, args...)
+	if target >= 0 {
+		offset := target - (addr + len(code))
+		if offset < -128 || offset > 127 {
+			return nil, fmt.Errorf("branch out of range: %v", operands[len(operands)-1])
+		}
+		code[len(code)-1] = uint8(offset)
+	}
+	return code, nil
+}
+
+// matchLiteral returns true if the operand is the same as the pattern.
+// Numbers, such as the restart address of "rst", are compared by value.
+func matchLiteral(pattern string, operand string) bool {
+	if pattern == operand {
+		return true
+	}
+	pv, ok := parseValue(pattern)
+	if !ok {
+		return false
+	}
+	ov, ok := parseValue(operand)
+	return ok && pv == ov
+}
+
+// parseIndex returns the displacement of an indexed operand such as
+// "(ix+$05)", "(iy-3)" or "(ix)". Ok is false if the operand does not use
+// the index register.
+func parseIndex(reg string, operand string) (uint8, bool, error) {
+	if operand == "("+reg+")" {
+		return 0, true, nil
+	}
+	if !strings.HasPrefix(operand, "("+reg) || !strings.HasSuffix(operand, ")") {
+		return 0, false, nil
+	}
+	expr := operand[len(reg)+1 : len(operand)-1]
+	if len(expr) < 2 || (expr[0] != '+' && expr[0] != '-') {
+		return 0, false, nil
+	}
+	v, ok := parseValue(expr[1:])
+	if !ok || v < 0 {
+		return 0, false, nil
+	}
+	if expr[0] == '-' {
+		v = -v
+	}
+	if v < -0x80 || v > 0xff {
+		return 0, true, fmt.Errorf("invalid value: %v", operand)
+	}
+	return uint8(v), true, nil
+}
+
+// parseValue returns the value of a number that is hexadecimal with a "$"
+// prefix or decimal, with an optional minus sign.
+func parseValue(s string) (int, bool) {
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	base, digits := 10, s
+	if strings.HasPrefix(s, "$") {
+		base, digits = 16, s[1:]
+	}
+	if digits == "" {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(digits, base, 32)
+	if err != nil {
+		return 0, false
+	}
+	if neg {
+		return -int(v), true
+	}
+	return int(v), true
+}
+
+// Assemble implements rcs.CPUAssembler so that the monitor can assemble
+// z80 code into memory.
+func (c *CPU) Assemble(addr int, line string) ([]uint8, error) {
+	return Assemble(addr, line)
+}
@@ -0,0 +1,141 @@
+package z80
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/mock"
+)
+
+// Each instruction in the Harston tables is assembled and the result
+// disassembled again. Instructions with more than one encoding assemble to
+// the preferred one, which must disassemble to the same text. Invalid
+// opcodes are skipped.
+func TestAssembleHarston(t *testing.T) {
+	for _, test := range harstonTests {
+		if strings.HasPrefix(test.Op, "?") {
+			continue
+		}
+		t.Run(test.Name, func(t *testing.T) {
+			have, err := Assemble(0x10, test.Op)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if reflect.DeepEqual(have, test.Bytes) {
+				return
+			}
+			mock.ResetMemory()
+			mock.TestMemory.WriteN(0x10, have...)
+			dasm := NewDisassembler(mock.TestMemory)
+			dasm.SetPC(0x10)
+			s := dasm.NextStmt()
+			if s.Op != test.Op || len(s.Bytes) != len(have) {
+				t.Errorf("have % x (%v), want % x", have, s.Op, test.Bytes)
+			}
+		})
+	}
+}
+
+// Every opcode in each table is disassembled and then assembled again to
+// check that an equivalent instruction is produced.
+func TestAssembleRoundTrip(t *testing.T) {
+	mem := mock.TestMemory
+	dasm := NewDisassembler(mem)
+	for _, prefix := range asmPrefixes {
+		for opcode := 0; opcode <= 0xff; opcode++ {
+			if isPrefix(prefix, uint8(opcode)) {
+				continue
+			}
+			for _, operand := range []uint8{0x00, 0x7f, 0x80, 0xff} {
+				mock.ResetMemory()
+				code := append([]uint8{}, prefix...)
+				if len(prefix) == 2 {
+					code = append(code, operand)
+				}
+				code = append(code, uint8(opcode), operand, operand, operand)
+				mem.WriteN(0x1234, code...)
+				dasm.SetPC(0x1234)
+				want := dasm.NextStmt()
+				if want.Op[0] == '?' {
+					continue
+				}
+				have, err := Assemble(0x1234, want.Op)
+				if err != nil {
+					t.Errorf("% x %q: %v", want.Bytes, want.Op, err)
+					continue
+				}
+				mem.WriteN(0x1234, have...)
+				dasm.SetPC(0x1234)
+				if s := dasm.NextStmt(); s.Op != want.Op {
+					t.Errorf("% x %q: have % x (%v)", want.Bytes, want.Op, have, s.Op)
+				}
+			}
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	tests := []struct {
+		line string
+		want []uint8
+	}{
+		{"LD A,($4E00)", []uint8{0x3a, 0x00, 0x4e}},
+		{"ld a, 64", []uint8{0x3e, 0x40}},
+		{"ld a,-1", []uint8{0x3e, 0xff}},
+		{"ld hl,($1234)", []uint8{0x2a, 0x34, 0x12}},
+		{"ld (ix+$05),$ff", []uint8{0xdd, 0x36, 0x05, 0xff}},
+		{"ld (iy-2),a", []uint8{0xfd, 0x77, 0xfe}},
+		{"ld b,(ix)", []uint8{0xdd, 0x46, 0x00}},
+		{"inc ixh", []uint8{0xdd, 0x24}},
+		{"ld iyl,$12", []uint8{0xfd, 0x2e, 0x12}},
+		{"bit 7,(iy+$10)", []uint8{0xfd, 0xcb, 0x10, 0x7e}},
+		{"set 1,e", []uint8{0xcb, 0xcb}},
+		{"rst $38", []uint8{0xff}},
+		{"rst 56", []uint8{0xff}},
+		{"im 2", []uint8{0xed, 0x5e}},
+		{"out ($10),a", []uint8{0xd3, 0x10}},
+		{"ex af,af'", []uint8{0x08}},
+		{"jp (ix)", []uint8{0xdd, 0xe9}},
+		{"jr $1234", []uint8{0x18, 0xfe}},
+		{"jr nz,$12b5", []uint8{0x20, 0x7f}},
+		{"djnz $11b6", []uint8{0x10, 0x80}},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			have, err := Assemble(0x1234, test.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(have, test.want) {
+				t.Errorf("have % x, want % x", have, test.want)
+			}
+		})
+	}
+}
+
+func TestAssembleErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		err  string
+	}{
+		{"", "no instruction"},
+		{"foo", "invalid instruction: foo"},
+		{"ld a", "invalid operands: ld a"},
+		{"ld a,q", "invalid operands: ld a,q"},
+		{"ld a,$123", "invalid value: $123"},
+		{"out ($123),a", "invalid value: ($123)"},
+		{"ld a,(ix+$100)", "invalid value: (ix+$100)"},
+		{"rst $39", "invalid operands: rst $39"},
+		{"jr $12b6", "branch out of range: $12b6"},
+		{"jr $11b5", "branch out of range: $11b5"},
+	}
+	for _, test := range tests {
+		t.Run(test.line, func(t *testing.T) {
+			_, err := Assemble(0x1234, test.line)
+			if err == nil || err.Error() != test.err {
+				t.Errorf("\n have: %v \n want: %v", err, test.err)
+			}
+		})
+	}
+}
@@ -43,14 +43,28 @@ type CPU struct {
 	IM   uint8 // Interrupt mode
 	Halt bool  // Halted by instruction
 
-	Ports   *rcs.Memory
-	IRQ     bool
+	Ports *rcs.Memory
+
+	// IRQ is the level-sensitive interrupt request line. It is checked after
+	// each instruction and stays asserted while interrupts are disabled.
+	// When the interrupt is acknowledged, IRQAck is called so the device
+	// can release the line. If IRQAck is nil, the line is released by the
+	// processor.
+	IRQ    bool
+	IRQAck func()
+
+	// IRQData is the value on the data bus when the interrupt is
+	// acknowledged. In mode 0, it is the instruction executed. If IRQBus is
+	// set, it is called for each byte of that instruction instead.
 	IRQData uint8
-	NMI     bool
-	RESET   bool
+	IRQBus  func() uint8
+
+	NMI   bool // Edge-triggered, cleared when acknowledged
+	RESET bool
 
 	WatchIRQ bool
 
+	calls       rcs.CallStack // call tracking for backtraces
 	opcodes     map[uint8]func(*CPU)
 	opcodesCB   map[uint8]func(*CPU)
 	opcodesED   map[uint8]func(*CPU)
@@ -59,8 +73,11 @@ type CPU struct {
 	opcodesDDCB map[uint8]func(*CPU)
 	opcodesFDCB map[uint8]func(*CPU)
 
-	mem   *rcs.Memory
-	delta uint8
+	mem    *rcs.Memory
+	cycles uint64       // number of T-states executed
+	bus    func() uint8 // instruction bytes in interrupt mode 0
+	eiWait bool         // no interrupts until after the instruction after ei
+	delta  uint8
 	// address used to load on the last (IX+d) or (IY+d) instruction
 	iaddr int
 }
@@ -113,12 +130,14 @@ func New(mem *rcs.Memory) *CPU {
 func (c *CPU) Next() {
 	if !c.Halt {
 		c.execute()
+	} else {
+		// executing nop while halted
+		c.cycles += 4
 	}
-	if c.IRQ {
-		c.IRQ = false
-		if c.IFF1 {
-			c.irqAck()
-		}
+	if c.eiWait {
+		c.eiWait = false
+	} else if c.IRQ && c.IFF1 {
+		c.irqAck()
 	}
 	if c.NMI {
 		c.NMI = false
@@ -132,40 +151,47 @@ func (c *CPU) Next() {
 
 func (c *CPU) execute() {
 	here := c.PC()
-	opcode := c.fetch()
+	opcode := c.fetchOpcode()
 	c.refreshR()
 
 	prefix := ""
 	var table map[uint8]func(*CPU)
+	timing := &tstates
 	switch opcode {
 	case 0xcb:
 		table = c.opcodesCB
+		timing = &tstatesCB
 		opcode = c.fetch()
 		c.refreshR()
 		prefix = "cb"
 	case 0xed:
 		table = c.opcodesED
+		timing = &tstatesED
 		opcode = c.fetch()
 		c.refreshR()
 		prefix = "ed"
 	case 0xdd:
 		table = c.opcodesDD
+		timing = &tstatesDD
 		opcode = c.fetch()
 		c.refreshR()
 		prefix = "dd"
 		if opcode == 0xcb {
 			table = c.opcodesDDCB
+			timing = &tstatesDDCB
 			c.fetchd()
 			opcode = c.fetch()
 			prefix = "ddcb"
 		}
 	case 0xfd:
 		table = c.opcodesFD
+		timing = &tstatesFD
 		opcode = c.fetch()
 		c.refreshR()
 		prefix = "fd"
 		if opcode == 0xcb {
 			table = c.opcodesFDCB
+			timing = &tstatesFDCB
 			c.fetchd()
 			opcode = c.fetch()
 			prefix = "fdcb"
@@ -179,18 +205,36 @@ func (c *CPU) execute() {
 		log.Printf("%04x: illegal instruction: %v%02x", here, prefix, opcode)
 		return
 	}
+	c.cycles += uint64(timing[opcode])
 	opFunc(c)
 }
 
 func (c *CPU) irqAck() {
-	if c.IM == 0 {
-		log.Printf("(!) %v: unsupported interrupt mode 0", c.Name)
-		return
+	if c.IRQAck != nil {
+		c.IRQAck()
+	} else {
+		c.IRQ = false
 	}
 	retAddr := c.PC()
 	c.Halt = false
 	c.IFF1 = false
 	c.IFF2 = false
+	if c.IM == 0 {
+		// Execute the instruction on the data bus without advancing the
+		// program counter. Two wait states are added to the opcode fetch.
+		if c.WatchIRQ {
+			log.Printf("%v: irq(0:%v), return %v", c.Name, rcs.X8(c.IRQData),
+				rcs.X(retAddr))
+		}
+		c.bus = c.IRQBus
+		if c.bus == nil {
+			c.bus = func() uint8 { return c.IRQData }
+		}
+		c.cycles += 2
+		c.execute()
+		c.bus = nil
+		return
+	}
 	c.SP -= 2
 	c.mem.WriteLE(int(c.SP), retAddr)
 	if c.IM == 2 {
@@ -199,30 +243,68 @@ func (c *CPU) irqAck() {
 			log.Printf("%v: irq(2:%v), vector %v, return %v", c.Name,
 				rcs.X8(c.IRQData), rcs.X(vector), rcs.X(retAddr))
 		}
+		c.cycles += 19
 		c.SetPC(c.mem.ReadLE(vector))
 	} else {
 		if c.WatchIRQ {
 			log.Printf("%v: irq(1), return %v", c.Name, rcs.X(retAddr))
 		}
 		c.pc = 0x0038
+		c.cycles += 13
 	}
+	c.trackCall(retAddr, true)
 }
 
 func (c *CPU) nmiAck() {
+	retAddr := c.PC()
+	c.Halt = false
+	// IFF2 keeps the state of IFF1 so it can be restored with retn
+	c.IFF1 = false
 	c.SP -= 2
-	c.mem.WriteLE(int(c.SP), c.PC())
+	c.mem.WriteLE(int(c.SP), retAddr)
 	c.pc = 0x0066
+	c.cycles += 11
+	c.trackCall(retAddr, true)
+}
+
+// trackCall records a frame in the call stack. It is called after the
+// return address has been pushed and the program counter is at the entry
+// point.
+func (c *CPU) trackCall(retAddr int, interrupt bool) {
+	if c.calls.Enabled {
+		c.calls.Call(rcs.Frame{
+			Entry:     c.PC(),
+			Return:    retAddr,
+			SP:        int(c.SP),
+			Interrupt: interrupt || c.bus != nil,
+		})
+	}
+}
+
+// trackReturn is called after the return address has been popped.
+func (c *CPU) trackReturn() {
+	if c.calls.Enabled {
+		c.calls.Return(int(c.SP))
+	}
 }
 
 func (c *CPU) resetAck() {
 	c.IFF1 = false
 	c.IFF2 = false
+	c.Halt = false
+	c.eiWait = false
 	c.pc = 0
 	c.I = 0
 	c.R = 0
 	c.IM = 0
 }
 
+// Cycles returns the number of T-states used by the instructions and
+// interrupts executed so far.
+func (c *CPU) Cycles() uint64 {
+	return c.cycles
+}
+
 // PC returns the value of the program counter.
 func (c *CPU) PC() int {
 	return int(c.pc)
@@ -245,6 +327,65 @@ func (c *CPU) Memory() *rcs.Memory {
 	return c.mem
 }
 
+// Flow classifies the instruction at addr by its effect on the call stack.
+// Conditional calls and returns are included.
+func (c *CPU) Flow(addr int) rcs.Flow {
+	op := c.mem.Peek(addr)
+	switch {
+	case op == 0xcd, op&0xc7 == 0xc4: // call nn, call cc,nn
+		return rcs.FlowCall
+	case op&0xc7 == 0xc7: // rst p
+		return rcs.FlowCall
+	case op == 0xc9, op&0xc7 == 0xc0: // ret, ret cc
+		return rcs.FlowReturn
+	case op == 0xed:
+		// retn and reti, including the undocumented mirrors
+		if op2 := c.mem.Peek((addr + 1) & 0xffff); op2&0xc7 == 0x45 {
+			return rcs.FlowReturn
+		}
+	}
+	return rcs.FlowNext
+}
+
+// StackAddr is the value of the stack pointer.
+func (c *CPU) StackAddr() int {
+	return int(c.SP)
+}
+
+// CallStack returns the calls tracked during execution. Tracking is off
+// until enabled.
+func (c *CPU) CallStack() *rcs.CallStack {
+	return &c.calls
+}
+
+// maxScan is the number of bytes above the stack pointer that are searched
+// by ScanStack.
+const maxScan = 0x100
+
+// ScanStack finds return addresses on the stack that follow a CALL or RST
+// instruction.
+func (c *CPU) ScanStack() []rcs.Frame {
+	var frames []rcs.Frame
+	for sp := int(c.SP); sp < int(c.SP)+maxScan && sp < 0xffff; sp += 2 {
+		ret := c.mem.PeekLE(sp)
+		entry := -1
+		if op := c.mem.Peek((ret - 3) & 0xffff); op == 0xcd || op&0xc7 == 0xc4 {
+			entry = c.mem.PeekLE((ret - 2) & 0xffff)
+		} else if op := c.mem.Peek((ret - 1) & 0xffff); op&0xc7 == 0xc7 {
+			entry = int(op & 0x38)
+		}
+		if entry < 0 {
+			continue
+		}
+		frames = append([]rcs.Frame{{
+			Entry:  entry,
+			Return: ret,
+			SP:     sp,
+		}}, frames...)
+	}
+	return frames
+}
+
 // NewDisassembler creates a disassembler that can handle Z80 machine
 // code.
 func (c *CPU) NewDisassembler() *rcs.Disassembler {
@@ -253,8 +394,19 @@ func (c *CPU) NewDisassembler() *rcs.Disassembler {
 }
 
 func (c *CPU) fetch() uint8 {
+	if c.bus != nil {
+		return c.bus()
+	}
+	c.pc++
+	return c.mem.Fetch(int(c.pc-1), false)
+}
+
+func (c *CPU) fetchOpcode() uint8 {
+	if c.bus != nil {
+		return c.bus()
+	}
 	c.pc++
-	return c.mem.Read(int(c.pc - 1))
+	return c.mem.Fetch(int(c.pc-1), true)
 }
 
 func (c *CPU) fetch2() int {
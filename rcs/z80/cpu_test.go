@@ -1,7 +1,11 @@
 package z80
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/blackchip-org/retro-cs/mock"
+	"github.com/blackchip-org/retro-cs/rcs"
 )
 
 func TestString(t *testing.T) {
@@ -43,3 +47,190 @@ func TestString(t *testing.T) {
 		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
 	}
 }
+
+func TestFlow(t *testing.T) {
+	var tests = []struct {
+		ops  []uint8
+		want rcs.Flow
+	}{
+		{[]uint8{0xcd}, rcs.FlowCall},         // call nn
+		{[]uint8{0xc4}, rcs.FlowCall},         // call nz,nn
+		{[]uint8{0xfc}, rcs.FlowCall},         // call m,nn
+		{[]uint8{0xc7}, rcs.FlowCall},         // rst 00h
+		{[]uint8{0xff}, rcs.FlowCall},         // rst 38h
+		{[]uint8{0xc9}, rcs.FlowReturn},       // ret
+		{[]uint8{0xc0}, rcs.FlowReturn},       // ret nz
+		{[]uint8{0xed, 0x4d}, rcs.FlowReturn}, // reti
+		{[]uint8{0xed, 0x45}, rcs.FlowReturn}, // retn
+		{[]uint8{0xed, 0x44}, rcs.FlowNext},   // neg
+		{[]uint8{0xc3}, rcs.FlowNext},         // jp nn
+		{[]uint8{0x00}, rcs.FlowNext},         // nop
+	}
+	for _, test := range tests {
+		mock.ResetMemory()
+		cpu := New(mock.TestMemory)
+		cpu.mem.WriteN(0x1000, test.ops...)
+		have := cpu.Flow(0x1000)
+		if have != test.want {
+			t.Errorf("% x: have %v, want %v", test.ops, have, test.want)
+		}
+	}
+}
+
+func TestCallStack(t *testing.T) {
+	mock.ResetMemory()
+	cpu := New(mock.TestMemory)
+	cpu.SP = 0xff00
+	cpu.SetPC(0x1000)
+	cpu.mem.WriteN(0x1000, 0xcd, 0x00, 0x20) // call $2000
+	cpu.mem.WriteN(0x2000, 0xff)             // rst 38h
+	cpu.mem.WriteN(0x0038, 0xc9)             // ret
+	cpu.CallStack().Enabled = true
+	cpu.Next()
+	cpu.Next()
+	want := []rcs.Frame{
+		{Entry: 0x2000, Return: 0x1003, SP: 0xfefe},
+		{Entry: 0x0038, Return: 0x2001, SP: 0xfefc},
+	}
+	if have := cpu.CallStack().Frames; !reflect.DeepEqual(have, want) {
+		t.Errorf("\n have: %+v \n want: %+v", have, want)
+	}
+	if have := cpu.ScanStack(); !reflect.DeepEqual(have, want) {
+		t.Errorf("scan\n have: %+v \n want: %+v", have, want)
+	}
+	cpu.Next()
+	want = want[:1]
+	if have := cpu.CallStack().Frames; !reflect.DeepEqual(have, want) {
+		t.Errorf("return\n have: %+v \n want: %+v", have, want)
+	}
+}
+
+func TestCycles(t *testing.T) {
+	var tests = []struct {
+		name  string
+		ops   []uint8
+		setup func(*CPU)
+		n     int
+		want  uint64
+	}{
+		{"nop", []uint8{0x00}, func(c *CPU) {}, 1, 4},
+		{"irq im1", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IRQ = 1, true, true
+		}, 1, 17},
+		{"irq im2", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IRQ = 2, true, true
+		}, 1, 23},
+		{"irq disabled", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IRQ = 1, true
+		}, 1, 4},
+		{"nmi", []uint8{0x00}, func(c *CPU) { c.NMI = true }, 1, 15},
+		{"halt", []uint8{0x76}, func(c *CPU) {}, 3, 12},
+		{"ldir", []uint8{0xed, 0xb0}, func(c *CPU) { c.C = 3 }, 1, 58},
+		{"cpir", []uint8{0xed, 0xb1}, func(c *CPU) { c.A, c.C = 0xff, 3 }, 3, 58},
+		{"jr nz taken", []uint8{0x20, 0x00}, func(c *CPU) {}, 1, 12},
+		{"jr nz not taken", []uint8{0x20, 0x00}, func(c *CPU) { c.F = FlagZ }, 1, 7},
+		{"ld (ix+d), n", []uint8{0xdd, 0x36, 0x00, 0x00}, func(c *CPU) {}, 1, 19},
+		{"bit 0, (ix+d)", []uint8{0xdd, 0xcb, 0x00, 0x46}, func(c *CPU) {}, 1, 20},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ResetMemory()
+			cpu := New(mock.TestMemory)
+			cpu.SetPC(0x1000)
+			cpu.mem.WriteN(0x1000, test.ops...)
+			test.setup(cpu)
+			for i := 0; i < test.n; i++ {
+				cpu.Next()
+			}
+			if have := cpu.Cycles(); have != test.want {
+				t.Errorf("have %v, want %v", have, test.want)
+			}
+		})
+	}
+}
+
+func TestInterrupts(t *testing.T) {
+	var tests = []struct {
+		name   string
+		ops    []uint8
+		setup  func(*CPU)
+		n      int
+		pc     int
+		ret    int // return address on the stack, if any
+		iff1   bool
+		iff2   bool
+		irq    bool
+		cycles uint64
+	}{
+		{"im 0 rst", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IFF2, c.IRQ, c.IRQData = 0, true, true, true, 0xd7
+		}, 1, 0x0010, 0x1001, false, false, false, 17},
+		{"im 0 call", []uint8{0x00}, func(c *CPU) {
+			bus := []uint8{0xcd, 0x00, 0x20}
+			c.IM, c.IFF1, c.IFF2, c.IRQ = 0, true, true, true
+			c.IRQBus = func() uint8 {
+				v := bus[0]
+				bus = bus[1:]
+				return v
+			}
+		}, 1, 0x2000, 0x1001, false, false, false, 23},
+		{"im 1", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IFF2, c.IRQ = 1, true, true, true
+		}, 1, 0x0038, 0x1001, false, false, false, 17},
+		{"im 2", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IFF2, c.IRQ = 2, true, true, true
+			c.I, c.IRQData = 0x30, 0x04
+			c.mem.WriteLE(0x3004, 0x2000)
+		}, 1, 0x2000, 0x1001, false, false, false, 23},
+		{"pending while disabled", []uint8{0x00, 0x00}, func(c *CPU) {
+			c.IM, c.IRQ = 1, true
+		}, 2, 0x1002, 0, false, false, true, 8},
+		{"after instruction after ei", []uint8{0xfb, 0x00}, func(c *CPU) {
+			c.IM, c.IRQ = 1, true
+		}, 2, 0x0038, 0x1002, false, false, false, 21},
+		{"held until released", []uint8{0x00}, func(c *CPU) {
+			c.IM, c.IFF1, c.IFF2, c.IRQ = 1, true, true, true
+			c.IRQAck = func() {}
+		}, 1, 0x0038, 0x1001, false, false, true, 17},
+		{"wake from halt", []uint8{0x76}, func(c *CPU) {
+			c.IM, c.IFF1, c.IFF2, c.IRQ = 1, true, true, true
+		}, 1, 0x0038, 0x1001, false, false, false, 17},
+		{"nmi", []uint8{0x00}, func(c *CPU) {
+			c.IFF1, c.IFF2, c.NMI = true, true, true
+		}, 1, 0x0066, 0x1001, false, true, false, 15},
+		{"nmi, retn", []uint8{0x00}, func(c *CPU) {
+			c.IFF1, c.IFF2, c.NMI = true, true, true
+			c.mem.WriteN(0x0066, 0xed, 0x45)
+		}, 2, 0x1001, 0, true, true, false, 29},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mock.ResetMemory()
+			cpu := New(mock.TestMemory)
+			cpu.SP = 0xff00
+			cpu.SetPC(0x1000)
+			cpu.mem.WriteN(0x1000, test.ops...)
+			test.setup(cpu)
+			for i := 0; i < test.n; i++ {
+				cpu.Next()
+			}
+			if cpu.PC() != test.pc {
+				t.Errorf("pc: have %04x, want %04x", cpu.PC(), test.pc)
+			}
+			if test.ret != 0 {
+				if ret := cpu.mem.ReadLE(int(cpu.SP)); ret != test.ret {
+					t.Errorf("return: have %04x, want %04x", ret, test.ret)
+				}
+			}
+			if cpu.IFF1 != test.iff1 || cpu.IFF2 != test.iff2 {
+				t.Errorf("iff: have %v %v, want %v %v", cpu.IFF1, cpu.IFF2, test.iff1, test.iff2)
+			}
+			if cpu.IRQ != test.irq {
+				t.Errorf("irq: have %v, want %v", cpu.IRQ, test.irq)
+			}
+			if cpu.Cycles() != test.cycles {
+				t.Errorf("cycles: have %v, want %v", cpu.Cycles(), test.cycles)
+			}
+		})
+	}
+}
@@ -188,18 +188,23 @@ func biti(cpu *CPU, n int, load rcs.Load8) {
 func call(cpu *CPU, flag uint8, condition bool, load rcs.Load) {
 	addr := load()
 	if (cpu.F&flag != 0) == condition {
+		ret := cpu.PC()
 		cpu.SP -= 2
-		cpu.mem.WriteLE(int(cpu.SP), cpu.PC())
+		cpu.mem.WriteLE(int(cpu.SP), ret)
 		cpu.SetPC(addr)
+		cpu.trackCall(ret, false)
+		cpu.cycles += 7
 	}
 }
 
 // call, always
 func calla(cpu *CPU, load rcs.Load) {
 	addr := load()
+	ret := cpu.PC()
 	cpu.SP -= 2
-	cpu.mem.WriteLE(int(cpu.SP), cpu.PC())
+	cpu.mem.WriteLE(int(cpu.SP), ret)
 	cpu.SetPC(addr)
+	cpu.trackCall(ret, false)
 }
 
 // invert carry flag
@@ -298,6 +303,7 @@ func cpxr(cpu *CPU, increment int) {
 		return
 	}
 	cpu.SetPC(cpu.PC() - 2)
+	cpu.cycles += 5
 }
 
 // decimal adjust in a
@@ -414,6 +420,7 @@ func djnz(cpu *CPU, load rcs.Load8) {
 	cpu.B--
 	if cpu.B != 0 {
 		cpu.SetPC(cpu.PC() + int(int8(delta)))
+		cpu.cycles += 5
 	}
 }
 
@@ -421,6 +428,7 @@ func djnz(cpu *CPU, load rcs.Load8) {
 func ei(cpu *CPU) {
 	cpu.IFF1 = true
 	cpu.IFF2 = true
+	cpu.eiWait = true
 }
 
 // exchange
@@ -555,6 +563,7 @@ func inxr(cpu *CPU, increment int) {
 	for cpu.B != 0 {
 		cpu.refreshR()
 		cpu.refreshR()
+		cpu.cycles += 21
 		inx(cpu, increment)
 	}
 }
@@ -578,6 +587,7 @@ func jr(cpu *CPU, flag uint8, condition bool, load rcs.Load8) {
 	flagSet := cpu.F&flag != 0
 	if flagSet == condition {
 		cpu.SetPC(cpu.PC() + delta)
+		cpu.cycles += 5
 	}
 }
 
@@ -653,6 +663,7 @@ func ldxr(cpu *CPU, increment int) {
 	for cpu.B != 0 || cpu.C != 0 {
 		cpu.refreshR()
 		cpu.refreshR()
+		cpu.cycles += 21
 		ldx(cpu, increment)
 	}
 }
@@ -735,6 +746,7 @@ func outxr(cpu *CPU, increment int) {
 	for cpu.B != 0 {
 		cpu.refreshR()
 		cpu.refreshR()
+		cpu.cycles += 21
 		outx(cpu, increment)
 	}
 }
@@ -761,6 +773,7 @@ func res(cpu *CPU, n int, store rcs.Store8, load rcs.Load8) {
 func ret(cpu *CPU, flag uint8, value bool) {
 	if (cpu.F&flag != 0) == value {
 		reta(cpu)
+		cpu.cycles += 6
 	}
 }
 
@@ -768,12 +781,15 @@ func ret(cpu *CPU, flag uint8, value bool) {
 func reta(cpu *CPU) {
 	cpu.SetPC(cpu.mem.ReadLE(int(cpu.SP)))
 	cpu.SP += 2
+	cpu.trackReturn()
 }
 
 // return from interrupt
 func reti(cpu *CPU) {
+	cpu.IFF1 = cpu.IFF2
 	cpu.SetPC(cpu.mem.ReadLE(int(cpu.SP)))
 	cpu.SP += 2
+	cpu.trackReturn()
 }
 
 // return from non-maskable interrupt
@@ -781,6 +797,7 @@ func retn(cpu *CPU) {
 	cpu.IFF1 = cpu.IFF2
 	cpu.SetPC(cpu.mem.ReadLE(int(cpu.SP)))
 	cpu.SP += 2
+	cpu.trackReturn()
 }
 
 // rotate left
@@ -1052,9 +1069,11 @@ func rrd(cpu *CPU) {
 
 // reset
 func rst(cpu *CPU, y int) {
+	ret := cpu.PC()
 	cpu.SP -= 2
-	cpu.mem.WriteLE(int(cpu.SP), cpu.PC())
+	cpu.mem.WriteLE(int(cpu.SP), ret)
 	cpu.SetPC(y * 8)
+	cpu.trackCall(ret, false)
 }
 
 // set carry flag
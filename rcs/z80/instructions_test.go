@@ -70,6 +70,7 @@ func TestOps(t *testing.T) {
 			testMemory(t, cpu.mem, fuseExpected[test.name].memory)
 			testMemory(t, cpu.Ports, fuseExpected[test.name].portWrites)
 			testHalt(t, cpu, fuseExpected[test.name])
+			testTStates(t, cpu, fuseExpected[test.name])
 		})
 	}
 }
@@ -85,6 +86,12 @@ func testMemory(t *testing.T, mem *rcs.Memory, expected [][]int) {
 	}
 }
 
+func testTStates(t *testing.T, cpu *CPU, expected fuseTest) {
+	if cpu.Cycles() != uint64(expected.tstates) {
+		t.Errorf("\n tstates have: %v \n tstates want: %v", cpu.Cycles(), expected.tstates)
+	}
+}
+
 func testHalt(t *testing.T, cpu *CPU, expected fuseTest) {
 	if cpu.Halt != (expected.halt != 0) {
 		t.Errorf("\n want: halt(%v) \n have: halt(%v)", cpu.Halt, expected.halt)
@@ -0,0 +1,250 @@
+package z80
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/config"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Per-instruction tests in the JSON format of the SingleStepTests z80
+// repository. Each file is named after the opcode, such as "dd cb __ 06",
+// and contains the state before and after executing that single
+// instruction. The internal registers WZ, Q, and P are not checked.
+var jsonDir = filepath.Join(config.ResourceDir(), "ext", "z80", "json")
+
+// Set to an opcode, such as "ed 44", to run only the tests in that file
+var jsonSingle = ""
+
+// Stop reporting the failures in a file after this many tests
+const jsonMaxFailures = 10
+
+type jsonState struct {
+	PC   int     `json:"pc"`
+	SP   int     `json:"sp"`
+	A    uint8   `json:"a"`
+	F    uint8   `json:"f"`
+	B    uint8   `json:"b"`
+	C    uint8   `json:"c"`
+	D    uint8   `json:"d"`
+	E    uint8   `json:"e"`
+	H    uint8   `json:"h"`
+	L    uint8   `json:"l"`
+	I    uint8   `json:"i"`
+	R    uint8   `json:"r"`
+	IX   int     `json:"ix"`
+	IY   int     `json:"iy"`
+	AF1  int     `json:"af_"`
+	BC1  int     `json:"bc_"`
+	DE1  int     `json:"de_"`
+	HL1  int     `json:"hl_"`
+	IM   uint8   `json:"im"`
+	IFF1 int     `json:"iff1"`
+	IFF2 int     `json:"iff2"`
+	RAM  [][]int `json:"ram"`
+}
+
+type jsonTest struct {
+	Name    string          `json:"name"`
+	Initial jsonState       `json:"initial"`
+	Final   jsonState       `json:"final"`
+	Cycles  [][]interface{} `json:"cycles"`
+	Ports   [][]interface{} `json:"ports"`
+}
+
+var jsonFlags = []struct {
+	name string
+	flag uint8
+}{
+	{"c", FlagC},
+	{"n", FlagN},
+	{"p", FlagP},
+	{"3", Flag3},
+	{"h", FlagH},
+	{"5", Flag5},
+	{"z", FlagZ},
+	{"s", FlagS},
+}
+
+func TestJSON(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join(jsonDir, "*.json"))
+	if len(files) == 0 {
+		t.Skipf("no tests found in %v", jsonDir)
+	}
+	cpu := New(nil)
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if jsonSingle != "" && name != jsonSingle {
+			continue
+		}
+		if !jsonImplemented(cpu, name) {
+			continue
+		}
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var tests []jsonTest
+			if err := json.Unmarshal(data, &tests); err != nil {
+				t.Fatalf("unable to decode %v: %v", file, err)
+			}
+			runJSONTests(t, tests)
+		})
+	}
+}
+
+// jsonImplemented returns true if the opcode in the name of the test file
+// is found in the opcode tables.
+func jsonImplemented(cpu *CPU, name string) bool {
+	var code []uint8
+	for _, field := range strings.Fields(name) {
+		if field == "__" {
+			continue
+		}
+		v, err := strconv.ParseUint(field, 16, 8)
+		if err != nil {
+			return false
+		}
+		code = append(code, uint8(v))
+	}
+	var table map[uint8]func(*CPU)
+	switch {
+	case len(code) == 1:
+		table = cpu.opcodes
+	case len(code) == 2 && code[0] == 0xcb:
+		table = cpu.opcodesCB
+	case len(code) == 2 && code[0] == 0xed:
+		table = cpu.opcodesED
+	case len(code) == 2 && code[0] == 0xdd:
+		table = cpu.opcodesDD
+	case len(code) == 2 && code[0] == 0xfd:
+		table = cpu.opcodesFD
+	case len(code) == 3 && code[0] == 0xdd && code[1] == 0xcb:
+		table = cpu.opcodesDDCB
+	case len(code) == 3 && code[0] == 0xfd && code[1] == 0xcb:
+		table = cpu.opcodesFDCB
+	default:
+		return false
+	}
+	_, ok := table[code[len(code)-1]]
+	return ok
+}
+
+func runJSONTests(t *testing.T, tests []jsonTest) {
+	ram := make([]uint8, 0x10000)
+	mem := rcs.NewMemory(1, 0x10000)
+	mem.MapRAM(0, ram)
+	ports := make([]uint8, 0x100)
+	cpu := New(mem)
+	cpu.Ports.MapRAM(0, ports)
+
+	failures := 0
+	for _, test := range tests {
+		in := test.Initial
+		for _, av := range in.RAM {
+			ram[av[0]] = uint8(av[1])
+		}
+		for _, p := range test.Ports {
+			if p[2] == "r" {
+				ports[int(p[0].(float64))&0xff] = uint8(p[1].(float64))
+			}
+		}
+		cpu.SetPC(in.PC)
+		cpu.SP = uint16(in.SP)
+		cpu.A, cpu.F = in.A, in.F
+		cpu.B, cpu.C, cpu.D, cpu.E, cpu.H, cpu.L = in.B, in.C, in.D, in.E, in.H, in.L
+		cpu.A1, cpu.F1 = uint8(in.AF1>>8), uint8(in.AF1)
+		cpu.B1, cpu.C1 = uint8(in.BC1>>8), uint8(in.BC1)
+		cpu.D1, cpu.E1 = uint8(in.DE1>>8), uint8(in.DE1)
+		cpu.H1, cpu.L1 = uint8(in.HL1>>8), uint8(in.HL1)
+		cpu.IXH, cpu.IXL = uint8(in.IX>>8), uint8(in.IX)
+		cpu.IYH, cpu.IYL = uint8(in.IY>>8), uint8(in.IY)
+		cpu.I, cpu.R, cpu.IM = in.I, in.R, in.IM
+		cpu.IFF1, cpu.IFF2 = in.IFF1 != 0, in.IFF2 != 0
+		cpu.Halt = false
+
+		start := cpu.Cycles()
+		cpu.Next()
+
+		diffs := compareJSON(cpu, ram, ports, test)
+		if have, want := int(cpu.Cycles()-start), len(test.Cycles); have != want {
+			diffs = append(diffs, fmt.Sprintf("  %-6v have %v want %v", "cycles", have, want))
+		}
+		if len(diffs) > 0 {
+			failures++
+			if failures <= jsonMaxFailures {
+				t.Errorf("%v:\n%v", test.Name, strings.Join(diffs, "\n"))
+			}
+		}
+		for _, av := range append(in.RAM, test.Final.RAM...) {
+			ram[av[0]] = 0
+		}
+		for i := range ports {
+			ports[i] = 0
+		}
+	}
+	if failures > jsonMaxFailures {
+		t.Errorf("%v of %v tests failed", failures, len(tests))
+	}
+}
+
+// compareJSON returns a line for each register, flag, memory cell, and
+// port written that is not in the expected state.
+func compareJSON(cpu *CPU, ram []uint8, ports []uint8, test jsonTest) []string {
+	var diffs []string
+	diff := func(name string, have int, want int) {
+		if have != want {
+			diffs = append(diffs, fmt.Sprintf("  %-6v have %v want %v", name, rcs.X(have), rcs.X(want)))
+		}
+	}
+	want := test.Final
+	pair := func(hi uint8, lo uint8) int {
+		return int(hi)<<8 | int(lo)
+	}
+	bool2int := func(b bool) int {
+		if b {
+			return 1
+		}
+		return 0
+	}
+	diff("pc", cpu.PC(), want.PC)
+	diff("sp", int(cpu.SP), want.SP)
+	diff("a", int(cpu.A), int(want.A))
+	for _, f := range jsonFlags {
+		diff("f."+f.name, int(cpu.F&f.flag)/int(f.flag), int(want.F&f.flag)/int(f.flag))
+	}
+	diff("b", int(cpu.B), int(want.B))
+	diff("c", int(cpu.C), int(want.C))
+	diff("d", int(cpu.D), int(want.D))
+	diff("e", int(cpu.E), int(want.E))
+	diff("h", int(cpu.H), int(want.H))
+	diff("l", int(cpu.L), int(want.L))
+	diff("i", int(cpu.I), int(want.I))
+	diff("r", int(cpu.R), int(want.R))
+	diff("ix", pair(cpu.IXH, cpu.IXL), want.IX)
+	diff("iy", pair(cpu.IYH, cpu.IYL), want.IY)
+	diff("af'", pair(cpu.A1, cpu.F1), want.AF1)
+	diff("bc'", pair(cpu.B1, cpu.C1), want.BC1)
+	diff("de'", pair(cpu.D1, cpu.E1), want.DE1)
+	diff("hl'", pair(cpu.H1, cpu.L1), want.HL1)
+	diff("im", int(cpu.IM), int(want.IM))
+	diff("iff1", bool2int(cpu.IFF1), want.IFF1)
+	diff("iff2", bool2int(cpu.IFF2), want.IFF2)
+	for _, av := range want.RAM {
+		diff(rcs.X16(uint16(av[0])), int(ram[av[0]]), av[1])
+	}
+	for _, p := range test.Ports {
+		if p[2] == "w" {
+			port := int(p[0].(float64)) & 0xff
+			diff(fmt.Sprintf("port %v", rcs.X8(uint8(port))), int(ports[port]), int(p[1].(float64)))
+		}
+	}
+	return diffs
+}
@@ -1618,3 +1618,1619 @@ var opcodesFDCB = map[uint8]func(c *CPU){
 	0xfe: func(c *CPU) { set(c, 7, c.storeLastInd, c.loadIndIY) },
 	0xff: func(c *CPU) { set(c, 7, c.storeA, c.loadIndIY); ld(c, c.storeLastInd, c.loadA) },
 }
+var tstates = [256]uint8{
+	0x00: 4,
+	0x01: 10,
+	0x02: 7,
+	0x03: 6,
+	0x04: 4,
+	0x05: 4,
+	0x06: 7,
+	0x07: 4,
+	0x08: 4,
+	0x09: 11,
+	0x0a: 7,
+	0x0b: 6,
+	0x0c: 4,
+	0x0d: 4,
+	0x0e: 7,
+	0x0f: 4,
+	0x10: 8,
+	0x11: 10,
+	0x12: 7,
+	0x13: 6,
+	0x14: 4,
+	0x15: 4,
+	0x16: 7,
+	0x17: 4,
+	0x18: 12,
+	0x19: 11,
+	0x1a: 7,
+	0x1b: 6,
+	0x1c: 4,
+	0x1d: 4,
+	0x1e: 7,
+	0x1f: 4,
+	0x20: 7,
+	0x21: 10,
+	0x22: 16,
+	0x23: 6,
+	0x24: 4,
+	0x25: 4,
+	0x26: 7,
+	0x27: 4,
+	0x28: 7,
+	0x29: 11,
+	0x2a: 16,
+	0x2b: 6,
+	0x2c: 4,
+	0x2d: 4,
+	0x2e: 7,
+	0x2f: 4,
+	0x30: 7,
+	0x31: 10,
+	0x32: 13,
+	0x33: 6,
+	0x34: 11,
+	0x35: 11,
+	0x36: 10,
+	0x37: 4,
+	0x38: 7,
+	0x39: 11,
+	0x3a: 13,
+	0x3b: 6,
+	0x3c: 4,
+	0x3d: 4,
+	0x3e: 7,
+	0x3f: 4,
+	0x40: 4,
+	0x41: 4,
+	0x42: 4,
+	0x43: 4,
+	0x44: 4,
+	0x45: 4,
+	0x46: 7,
+	0x47: 4,
+	0x48: 4,
+	0x49: 4,
+	0x4a: 4,
+	0x4b: 4,
+	0x4c: 4,
+	0x4d: 4,
+	0x4e: 7,
+	0x4f: 4,
+	0x50: 4,
+	0x51: 4,
+	0x52: 4,
+	0x53: 4,
+	0x54: 4,
+	0x55: 4,
+	0x56: 7,
+	0x57: 4,
+	0x58: 4,
+	0x59: 4,
+	0x5a: 4,
+	0x5b: 4,
+	0x5c: 4,
+	0x5d: 4,
+	0x5e: 7,
+	0x5f: 4,
+	0x60: 4,
+	0x61: 4,
+	0x62: 4,
+	0x63: 4,
+	0x64: 4,
+	0x65: 4,
+	0x66: 7,
+	0x67: 4,
+	0x68: 4,
+	0x69: 4,
+	0x6a: 4,
+	0x6b: 4,
+	0x6c: 4,
+	0x6d: 4,
+	0x6e: 7,
+	0x6f: 4,
+	0x70: 7,
+	0x71: 7,
+	0x72: 7,
+	0x73: 7,
+	0x74: 7,
+	0x75: 7,
+	0x76: 4,
+	0x77: 7,
+	0x78: 4,
+	0x79: 4,
+	0x7a: 4,
+	0x7b: 4,
+	0x7c: 4,
+	0x7d: 4,
+	0x7e: 7,
+	0x7f: 4,
+	0x80: 4,
+	0x81: 4,
+	0x82: 4,
+	0x83: 4,
+	0x84: 4,
+	0x85: 4,
+	0x86: 7,
+	0x87: 4,
+	0x88: 4,
+	0x89: 4,
+	0x8a: 4,
+	0x8b: 4,
+	0x8c: 4,
+	0x8d: 4,
+	0x8e: 7,
+	0x8f: 4,
+	0x90: 4,
+	0x91: 4,
+	0x92: 4,
+	0x93: 4,
+	0x94: 4,
+	0x95: 4,
+	0x96: 7,
+	0x97: 4,
+	0x98: 4,
+	0x99: 4,
+	0x9a: 4,
+	0x9b: 4,
+	0x9c: 4,
+	0x9d: 4,
+	0x9e: 7,
+	0x9f: 4,
+	0xa0: 4,
+	0xa1: 4,
+	0xa2: 4,
+	0xa3: 4,
+	0xa4: 4,
+	0xa5: 4,
+	0xa6: 7,
+	0xa7: 4,
+	0xa8: 4,
+	0xa9: 4,
+	0xaa: 4,
+	0xab: 4,
+	0xac: 4,
+	0xad: 4,
+	0xae: 7,
+	0xaf: 4,
+	0xb0: 4,
+	0xb1: 4,
+	0xb2: 4,
+	0xb3: 4,
+	0xb4: 4,
+	0xb5: 4,
+	0xb6: 7,
+	0xb7: 4,
+	0xb8: 4,
+	0xb9: 4,
+	0xba: 4,
+	0xbb: 4,
+	0xbc: 4,
+	0xbd: 4,
+	0xbe: 7,
+	0xbf: 4,
+	0xc0: 5,
+	0xc1: 10,
+	0xc2: 10,
+	0xc3: 10,
+	0xc4: 10,
+	0xc5: 11,
+	0xc6: 7,
+	0xc7: 11,
+	0xc8: 5,
+	0xc9: 10,
+	0xca: 10,
+	0xcc: 10,
+	0xcd: 17,
+	0xce: 7,
+	0xcf: 11,
+	0xd0: 5,
+	0xd1: 10,
+	0xd2: 10,
+	0xd3: 11,
+	0xd4: 10,
+	0xd5: 11,
+	0xd6: 7,
+	0xd7: 11,
+	0xd8: 5,
+	0xd9: 4,
+	0xda: 10,
+	0xdb: 11,
+	0xdc: 10,
+	0xde: 7,
+	0xdf: 11,
+	0xe0: 5,
+	0xe1: 10,
+	0xe2: 10,
+	0xe3: 19,
+	0xe4: 10,
+	0xe5: 11,
+	0xe6: 7,
+	0xe7: 11,
+	0xe8: 5,
+	0xe9: 4,
+	0xea: 10,
+	0xeb: 4,
+	0xec: 10,
+	0xee: 7,
+	0xef: 11,
+	0xf0: 5,
+	0xf1: 10,
+	0xf2: 10,
+	0xf3: 4,
+	0xf4: 10,
+	0xf5: 11,
+	0xf6: 7,
+	0xf7: 11,
+	0xf8: 5,
+	0xf9: 6,
+	0xfa: 10,
+	0xfb: 4,
+	0xfc: 10,
+	0xfe: 7,
+	0xff: 11,
+}
+var tstatesCB = [256]uint8{
+	0x00: 8,
+	0x01: 8,
+	0x02: 8,
+	0x03: 8,
+	0x04: 8,
+	0x05: 8,
+	0x06: 15,
+	0x07: 8,
+	0x08: 8,
+	0x09: 8,
+	0x0a: 8,
+	0x0b: 8,
+	0x0c: 8,
+	0x0d: 8,
+	0x0e: 15,
+	0x0f: 8,
+	0x10: 8,
+	0x11: 8,
+	0x12: 8,
+	0x13: 8,
+	0x14: 8,
+	0x15: 8,
+	0x16: 15,
+	0x17: 8,
+	0x18: 8,
+	0x19: 8,
+	0x1a: 8,
+	0x1b: 8,
+	0x1c: 8,
+	0x1d: 8,
+	0x1e: 15,
+	0x1f: 8,
+	0x20: 8,
+	0x21: 8,
+	0x22: 8,
+	0x23: 8,
+	0x24: 8,
+	0x25: 8,
+	0x26: 15,
+	0x27: 8,
+	0x28: 8,
+	0x29: 8,
+	0x2a: 8,
+	0x2b: 8,
+	0x2c: 8,
+	0x2d: 8,
+	0x2e: 15,
+	0x2f: 8,
+	0x30: 8,
+	0x31: 8,
+	0x32: 8,
+	0x33: 8,
+	0x34: 8,
+	0x35: 8,
+	0x36: 15,
+	0x37: 8,
+	0x38: 8,
+	0x39: 8,
+	0x3a: 8,
+	0x3b: 8,
+	0x3c: 8,
+	0x3d: 8,
+	0x3e: 15,
+	0x3f: 8,
+	0x40: 8,
+	0x41: 8,
+	0x42: 8,
+	0x43: 8,
+	0x44: 8,
+	0x45: 8,
+	0x46: 12,
+	0x47: 8,
+	0x48: 8,
+	0x49: 8,
+	0x4a: 8,
+	0x4b: 8,
+	0x4c: 8,
+	0x4d: 8,
+	0x4e: 12,
+	0x4f: 8,
+	0x50: 8,
+	0x51: 8,
+	0x52: 8,
+	0x53: 8,
+	0x54: 8,
+	0x55: 8,
+	0x56: 12,
+	0x57: 8,
+	0x58: 8,
+	0x59: 8,
+	0x5a: 8,
+	0x5b: 8,
+	0x5c: 8,
+	0x5d: 8,
+	0x5e: 12,
+	0x5f: 8,
+	0x60: 8,
+	0x61: 8,
+	0x62: 8,
+	0x63: 8,
+	0x64: 8,
+	0x65: 8,
+	0x66: 12,
+	0x67: 8,
+	0x68: 8,
+	0x69: 8,
+	0x6a: 8,
+	0x6b: 8,
+	0x6c: 8,
+	0x6d: 8,
+	0x6e: 12,
+	0x6f: 8,
+	0x70: 8,
+	0x71: 8,
+	0x72: 8,
+	0x73: 8,
+	0x74: 8,
+	0x75: 8,
+	0x76: 12,
+	0x77: 8,
+	0x78: 8,
+	0x79: 8,
+	0x7a: 8,
+	0x7b: 8,
+	0x7c: 8,
+	0x7d: 8,
+	0x7e: 12,
+	0x7f: 8,
+	0x80: 8,
+	0x81: 8,
+	0x82: 8,
+	0x83: 8,
+	0x84: 8,
+	0x85: 8,
+	0x86: 15,
+	0x87: 8,
+	0x88: 8,
+	0x89: 8,
+	0x8a: 8,
+	0x8b: 8,
+	0x8c: 8,
+	0x8d: 8,
+	0x8e: 15,
+	0x8f: 8,
+	0x90: 8,
+	0x91: 8,
+	0x92: 8,
+	0x93: 8,
+	0x94: 8,
+	0x95: 8,
+	0x96: 15,
+	0x97: 8,
+	0x98: 8,
+	0x99: 8,
+	0x9a: 8,
+	0x9b: 8,
+	0x9c: 8,
+	0x9d: 8,
+	0x9e: 15,
+	0x9f: 8,
+	0xa0: 8,
+	0xa1: 8,
+	0xa2: 8,
+	0xa3: 8,
+	0xa4: 8,
+	0xa5: 8,
+	0xa6: 15,
+	0xa7: 8,
+	0xa8: 8,
+	0xa9: 8,
+	0xaa: 8,
+	0xab: 8,
+	0xac: 8,
+	0xad: 8,
+	0xae: 15,
+	0xaf: 8,
+	0xb0: 8,
+	0xb1: 8,
+	0xb2: 8,
+	0xb3: 8,
+	0xb4: 8,
+	0xb5: 8,
+	0xb6: 15,
+	0xb7: 8,
+	0xb8: 8,
+	0xb9: 8,
+	0xba: 8,
+	0xbb: 8,
+	0xbc: 8,
+	0xbd: 8,
+	0xbe: 15,
+	0xbf: 8,
+	0xc0: 8,
+	0xc1: 8,
+	0xc2: 8,
+	0xc3: 8,
+	0xc4: 8,
+	0xc5: 8,
+	0xc6: 15,
+	0xc7: 8,
+	0xc8: 8,
+	0xc9: 8,
+	0xca: 8,
+	0xcb: 8,
+	0xcc: 8,
+	0xcd: 8,
+	0xce: 15,
+	0xcf: 8,
+	0xd0: 8,
+	0xd1: 8,
+	0xd2: 8,
+	0xd3: 8,
+	0xd4: 8,
+	0xd5: 8,
+	0xd6: 15,
+	0xd7: 8,
+	0xd8: 8,
+	0xd9: 8,
+	0xda: 8,
+	0xdb: 8,
+	0xdc: 8,
+	0xdd: 8,
+	0xde: 15,
+	0xdf: 8,
+	0xe0: 8,
+	0xe1: 8,
+	0xe2: 8,
+	0xe3: 8,
+	0xe4: 8,
+	0xe5: 8,
+	0xe6: 15,
+	0xe7: 8,
+	0xe8: 8,
+	0xe9: 8,
+	0xea: 8,
+	0xeb: 8,
+	0xec: 8,
+	0xed: 8,
+	0xee: 15,
+	0xef: 8,
+	0xf0: 8,
+	0xf1: 8,
+	0xf2: 8,
+	0xf3: 8,
+	0xf4: 8,
+	0xf5: 8,
+	0xf6: 15,
+	0xf7: 8,
+	0xf8: 8,
+	0xf9: 8,
+	0xfa: 8,
+	0xfb: 8,
+	0xfc: 8,
+	0xfd: 8,
+	0xfe: 15,
+	0xff: 8,
+}
+var tstatesED = [256]uint8{
+	0x40: 12,
+	0x41: 12,
+	0x42: 15,
+	0x43: 20,
+	0x44: 8,
+	0x45: 14,
+	0x46: 8,
+	0x47: 9,
+	0x48: 12,
+	0x49: 12,
+	0x4a: 15,
+	0x4b: 20,
+	0x4c: 8,
+	0x4d: 14,
+	0x4e: 8,
+	0x4f: 9,
+	0x50: 12,
+	0x51: 12,
+	0x52: 15,
+	0x53: 20,
+	0x54: 8,
+	0x55: 14,
+	0x56: 8,
+	0x57: 9,
+	0x58: 12,
+	0x59: 12,
+	0x5a: 15,
+	0x5b: 20,
+	0x5c: 8,
+	0x5d: 14,
+	0x5e: 8,
+	0x5f: 9,
+	0x60: 12,
+	0x61: 12,
+	0x62: 15,
+	0x63: 20,
+	0x64: 8,
+	0x65: 14,
+	0x66: 8,
+	0x67: 18,
+	0x68: 12,
+	0x69: 12,
+	0x6a: 15,
+	0x6b: 20,
+	0x6c: 8,
+	0x6d: 14,
+	0x6e: 8,
+	0x6f: 18,
+	0x70: 12,
+	0x71: 12,
+	0x72: 15,
+	0x73: 20,
+	0x74: 8,
+	0x75: 14,
+	0x76: 8,
+	0x78: 12,
+	0x79: 12,
+	0x7a: 15,
+	0x7b: 20,
+	0x7c: 8,
+	0x7d: 14,
+	0x7e: 8,
+	0xa0: 16,
+	0xa1: 16,
+	0xa2: 16,
+	0xa3: 16,
+	0xa8: 16,
+	0xa9: 16,
+	0xaa: 16,
+	0xab: 16,
+	0xb0: 16,
+	0xb1: 16,
+	0xb2: 16,
+	0xb3: 16,
+	0xb8: 16,
+	0xb9: 16,
+	0xba: 16,
+	0xbb: 16,
+}
+var tstatesDD = [256]uint8{
+	0x00: 8,
+	0x01: 14,
+	0x02: 11,
+	0x03: 10,
+	0x04: 8,
+	0x05: 8,
+	0x06: 11,
+	0x07: 8,
+	0x08: 8,
+	0x09: 15,
+	0x0a: 11,
+	0x0b: 10,
+	0x0c: 8,
+	0x0d: 8,
+	0x0e: 11,
+	0x0f: 8,
+	0x10: 12,
+	0x11: 14,
+	0x12: 11,
+	0x13: 10,
+	0x14: 8,
+	0x15: 8,
+	0x16: 11,
+	0x17: 8,
+	0x18: 16,
+	0x19: 15,
+	0x1a: 11,
+	0x1b: 10,
+	0x1c: 8,
+	0x1d: 8,
+	0x1e: 11,
+	0x1f: 8,
+	0x20: 11,
+	0x21: 14,
+	0x22: 20,
+	0x23: 10,
+	0x24: 8,
+	0x25: 8,
+	0x26: 11,
+	0x27: 8,
+	0x28: 11,
+	0x29: 15,
+	0x2a: 20,
+	0x2b: 10,
+	0x2c: 8,
+	0x2d: 8,
+	0x2e: 11,
+	0x2f: 8,
+	0x30: 11,
+	0x31: 14,
+	0x32: 17,
+	0x33: 10,
+	0x34: 23,
+	0x35: 23,
+	0x36: 19,
+	0x37: 8,
+	0x38: 11,
+	0x39: 15,
+	0x3a: 17,
+	0x3b: 10,
+	0x3c: 8,
+	0x3d: 8,
+	0x3e: 11,
+	0x3f: 8,
+	0x40: 8,
+	0x41: 8,
+	0x42: 8,
+	0x43: 8,
+	0x44: 8,
+	0x45: 8,
+	0x46: 19,
+	0x47: 8,
+	0x48: 8,
+	0x49: 8,
+	0x4a: 8,
+	0x4b: 8,
+	0x4c: 8,
+	0x4d: 8,
+	0x4e: 19,
+	0x4f: 8,
+	0x50: 8,
+	0x51: 8,
+	0x52: 8,
+	0x53: 8,
+	0x54: 8,
+	0x55: 8,
+	0x56: 19,
+	0x57: 8,
+	0x58: 8,
+	0x59: 8,
+	0x5a: 8,
+	0x5b: 8,
+	0x5c: 8,
+	0x5d: 8,
+	0x5e: 19,
+	0x5f: 8,
+	0x60: 8,
+	0x61: 8,
+	0x62: 8,
+	0x63: 8,
+	0x64: 8,
+	0x65: 8,
+	0x66: 19,
+	0x67: 8,
+	0x68: 8,
+	0x69: 8,
+	0x6a: 8,
+	0x6b: 8,
+	0x6c: 8,
+	0x6d: 8,
+	0x6e: 19,
+	0x6f: 8,
+	0x70: 19,
+	0x71: 19,
+	0x72: 19,
+	0x73: 19,
+	0x74: 19,
+	0x75: 19,
+	0x76: 8,
+	0x77: 19,
+	0x78: 8,
+	0x79: 8,
+	0x7a: 8,
+	0x7b: 8,
+	0x7c: 8,
+	0x7d: 8,
+	0x7e: 19,
+	0x7f: 8,
+	0x80: 8,
+	0x81: 8,
+	0x82: 8,
+	0x83: 8,
+	0x84: 8,
+	0x85: 8,
+	0x86: 19,
+	0x87: 8,
+	0x88: 8,
+	0x89: 8,
+	0x8a: 8,
+	0x8b: 8,
+	0x8c: 8,
+	0x8d: 8,
+	0x8e: 19,
+	0x8f: 8,
+	0x90: 8,
+	0x91: 8,
+	0x92: 8,
+	0x93: 8,
+	0x94: 8,
+	0x95: 8,
+	0x96: 19,
+	0x97: 8,
+	0x98: 8,
+	0x99: 8,
+	0x9a: 8,
+	0x9b: 8,
+	0x9c: 8,
+	0x9d: 8,
+	0x9e: 19,
+	0x9f: 8,
+	0xa0: 8,
+	0xa1: 8,
+	0xa2: 8,
+	0xa3: 8,
+	0xa4: 8,
+	0xa5: 8,
+	0xa6: 19,
+	0xa7: 8,
+	0xa8: 8,
+	0xa9: 8,
+	0xaa: 8,
+	0xab: 8,
+	0xac: 8,
+	0xad: 8,
+	0xae: 19,
+	0xaf: 8,
+	0xb0: 8,
+	0xb1: 8,
+	0xb2: 8,
+	0xb3: 8,
+	0xb4: 8,
+	0xb5: 8,
+	0xb6: 19,
+	0xb7: 8,
+	0xb8: 8,
+	0xb9: 8,
+	0xba: 8,
+	0xbb: 8,
+	0xbc: 8,
+	0xbd: 8,
+	0xbe: 19,
+	0xbf: 8,
+	0xc0: 9,
+	0xc1: 14,
+	0xc2: 14,
+	0xc3: 14,
+	0xc4: 14,
+	0xc5: 15,
+	0xc6: 11,
+	0xc7: 15,
+	0xc8: 9,
+	0xc9: 14,
+	0xca: 14,
+	0xcc: 14,
+	0xcd: 21,
+	0xce: 11,
+	0xcf: 15,
+	0xd0: 9,
+	0xd1: 14,
+	0xd2: 14,
+	0xd3: 15,
+	0xd4: 14,
+	0xd5: 15,
+	0xd6: 11,
+	0xd7: 15,
+	0xd8: 9,
+	0xd9: 8,
+	0xda: 14,
+	0xdb: 15,
+	0xdc: 14,
+	0xde: 11,
+	0xdf: 15,
+	0xe0: 9,
+	0xe1: 14,
+	0xe2: 14,
+	0xe3: 23,
+	0xe4: 14,
+	0xe5: 15,
+	0xe6: 11,
+	0xe7: 15,
+	0xe8: 9,
+	0xe9: 8,
+	0xea: 14,
+	0xeb: 8,
+	0xec: 14,
+	0xee: 11,
+	0xef: 15,
+	0xf0: 9,
+	0xf1: 14,
+	0xf2: 14,
+	0xf3: 8,
+	0xf4: 14,
+	0xf5: 15,
+	0xf6: 11,
+	0xf7: 15,
+	0xf8: 9,
+	0xf9: 10,
+	0xfa: 14,
+	0xfb: 8,
+	0xfc: 14,
+	0xfe: 11,
+	0xff: 15,
+}
+var tstatesFD = [256]uint8{
+	0x00: 8,
+	0x01: 14,
+	0x02: 11,
+	0x03: 10,
+	0x04: 8,
+	0x05: 8,
+	0x06: 11,
+	0x07: 8,
+	0x08: 8,
+	0x09: 15,
+	0x0a: 11,
+	0x0b: 10,
+	0x0c: 8,
+	0x0d: 8,
+	0x0e: 11,
+	0x0f: 8,
+	0x10: 12,
+	0x11: 14,
+	0x12: 11,
+	0x13: 10,
+	0x14: 8,
+	0x15: 8,
+	0x16: 11,
+	0x17: 8,
+	0x18: 16,
+	0x19: 15,
+	0x1a: 11,
+	0x1b: 10,
+	0x1c: 8,
+	0x1d: 8,
+	0x1e: 11,
+	0x1f: 8,
+	0x20: 11,
+	0x21: 14,
+	0x22: 20,
+	0x23: 10,
+	0x24: 8,
+	0x25: 8,
+	0x26: 11,
+	0x27: 8,
+	0x28: 11,
+	0x29: 15,
+	0x2a: 20,
+	0x2b: 10,
+	0x2c: 8,
+	0x2d: 8,
+	0x2e: 11,
+	0x2f: 8,
+	0x30: 11,
+	0x31: 14,
+	0x32: 17,
+	0x33: 10,
+	0x34: 23,
+	0x35: 23,
+	0x36: 19,
+	0x37: 8,
+	0x38: 11,
+	0x39: 15,
+	0x3a: 17,
+	0x3b: 10,
+	0x3c: 8,
+	0x3d: 8,
+	0x3e: 11,
+	0x3f: 8,
+	0x40: 8,
+	0x41: 8,
+	0x42: 8,
+	0x43: 8,
+	0x44: 8,
+	0x45: 8,
+	0x46: 19,
+	0x47: 8,
+	0x48: 8,
+	0x49: 8,
+	0x4a: 8,
+	0x4b: 8,
+	0x4c: 8,
+	0x4d: 8,
+	0x4e: 19,
+	0x4f: 8,
+	0x50: 8,
+	0x51: 8,
+	0x52: 8,
+	0x53: 8,
+	0x54: 8,
+	0x55: 8,
+	0x56: 19,
+	0x57: 8,
+	0x58: 8,
+	0x59: 8,
+	0x5a: 8,
+	0x5b: 8,
+	0x5c: 8,
+	0x5d: 8,
+	0x5e: 19,
+	0x5f: 8,
+	0x60: 8,
+	0x61: 8,
+	0x62: 8,
+	0x63: 8,
+	0x64: 8,
+	0x65: 8,
+	0x66: 19,
+	0x67: 8,
+	0x68: 8,
+	0x69: 8,
+	0x6a: 8,
+	0x6b: 8,
+	0x6c: 8,
+	0x6d: 8,
+	0x6e: 19,
+	0x6f: 8,
+	0x70: 19,
+	0x71: 19,
+	0x72: 19,
+	0x73: 19,
+	0x74: 19,
+	0x75: 19,
+	0x76: 8,
+	0x77: 19,
+	0x78: 8,
+	0x79: 8,
+	0x7a: 8,
+	0x7b: 8,
+	0x7c: 8,
+	0x7d: 8,
+	0x7e: 19,
+	0x7f: 8,
+	0x80: 8,
+	0x81: 8,
+	0x82: 8,
+	0x83: 8,
+	0x84: 8,
+	0x85: 8,
+	0x86: 19,
+	0x87: 8,
+	0x88: 8,
+	0x89: 8,
+	0x8a: 8,
+	0x8b: 8,
+	0x8c: 8,
+	0x8d: 8,
+	0x8e: 19,
+	0x8f: 8,
+	0x90: 8,
+	0x91: 8,
+	0x92: 8,
+	0x93: 8,
+	0x94: 8,
+	0x95: 8,
+	0x96: 19,
+	0x97: 8,
+	0x98: 8,
+	0x99: 8,
+	0x9a: 8,
+	0x9b: 8,
+	0x9c: 8,
+	0x9d: 8,
+	0x9e: 19,
+	0x9f: 8,
+	0xa0: 8,
+	0xa1: 8,
+	0xa2: 8,
+	0xa3: 8,
+	0xa4: 8,
+	0xa5: 8,
+	0xa6: 19,
+	0xa7: 8,
+	0xa8: 8,
+	0xa9: 8,
+	0xaa: 8,
+	0xab: 8,
+	0xac: 8,
+	0xad: 8,
+	0xae: 19,
+	0xaf: 8,
+	0xb0: 8,
+	0xb1: 8,
+	0xb2: 8,
+	0xb3: 8,
+	0xb4: 8,
+	0xb5: 8,
+	0xb6: 19,
+	0xb7: 8,
+	0xb8: 8,
+	0xb9: 8,
+	0xba: 8,
+	0xbb: 8,
+	0xbc: 8,
+	0xbd: 8,
+	0xbe: 19,
+	0xbf: 8,
+	0xc0: 9,
+	0xc1: 14,
+	0xc2: 14,
+	0xc3: 14,
+	0xc4: 14,
+	0xc5: 15,
+	0xc6: 11,
+	0xc7: 15,
+	0xc8: 9,
+	0xc9: 14,
+	0xca: 14,
+	0xcc: 14,
+	0xcd: 21,
+	0xce: 11,
+	0xcf: 15,
+	0xd0: 9,
+	0xd1: 14,
+	0xd2: 14,
+	0xd3: 15,
+	0xd4: 14,
+	0xd5: 15,
+	0xd6: 11,
+	0xd7: 15,
+	0xd8: 9,
+	0xd9: 8,
+	0xda: 14,
+	0xdb: 15,
+	0xdc: 14,
+	0xde: 11,
+	0xdf: 15,
+	0xe0: 9,
+	0xe1: 14,
+	0xe2: 14,
+	0xe3: 23,
+	0xe4: 14,
+	0xe5: 15,
+	0xe6: 11,
+	0xe7: 15,
+	0xe8: 9,
+	0xe9: 8,
+	0xea: 14,
+	0xeb: 8,
+	0xec: 14,
+	0xee: 11,
+	0xef: 15,
+	0xf0: 9,
+	0xf1: 14,
+	0xf2: 14,
+	0xf3: 8,
+	0xf4: 14,
+	0xf5: 15,
+	0xf6: 11,
+	0xf7: 15,
+	0xf8: 9,
+	0xf9: 10,
+	0xfa: 14,
+	0xfb: 8,
+	0xfc: 14,
+	0xfe: 11,
+	0xff: 15,
+}
+var tstatesDDCB = [256]uint8{
+	0x00: 23,
+	0x01: 23,
+	0x02: 23,
+	0x03: 23,
+	0x04: 23,
+	0x05: 23,
+	0x06: 23,
+	0x07: 23,
+	0x08: 23,
+	0x09: 23,
+	0x0a: 23,
+	0x0b: 23,
+	0x0c: 23,
+	0x0d: 23,
+	0x0e: 23,
+	0x0f: 23,
+	0x10: 23,
+	0x11: 23,
+	0x12: 23,
+	0x13: 23,
+	0x14: 23,
+	0x15: 23,
+	0x16: 23,
+	0x17: 23,
+	0x18: 23,
+	0x19: 23,
+	0x1a: 23,
+	0x1b: 23,
+	0x1c: 23,
+	0x1d: 23,
+	0x1e: 23,
+	0x1f: 23,
+	0x20: 23,
+	0x21: 23,
+	0x22: 23,
+	0x23: 23,
+	0x24: 23,
+	0x25: 23,
+	0x26: 23,
+	0x27: 23,
+	0x28: 23,
+	0x29: 23,
+	0x2a: 23,
+	0x2b: 23,
+	0x2c: 23,
+	0x2d: 23,
+	0x2e: 23,
+	0x2f: 23,
+	0x30: 23,
+	0x31: 23,
+	0x32: 23,
+	0x33: 23,
+	0x34: 23,
+	0x35: 23,
+	0x36: 23,
+	0x37: 23,
+	0x38: 23,
+	0x39: 23,
+	0x3a: 23,
+	0x3b: 23,
+	0x3c: 23,
+	0x3d: 23,
+	0x3e: 23,
+	0x3f: 23,
+	0x40: 20,
+	0x41: 20,
+	0x42: 20,
+	0x43: 20,
+	0x44: 20,
+	0x45: 20,
+	0x46: 20,
+	0x47: 20,
+	0x48: 20,
+	0x49: 20,
+	0x4a: 20,
+	0x4b: 20,
+	0x4c: 20,
+	0x4d: 20,
+	0x4e: 20,
+	0x4f: 20,
+	0x50: 20,
+	0x51: 20,
+	0x52: 20,
+	0x53: 20,
+	0x54: 20,
+	0x55: 20,
+	0x56: 20,
+	0x57: 20,
+	0x58: 20,
+	0x59: 20,
+	0x5a: 20,
+	0x5b: 20,
+	0x5c: 20,
+	0x5d: 20,
+	0x5e: 20,
+	0x5f: 20,
+	0x60: 20,
+	0x61: 20,
+	0x62: 20,
+	0x63: 20,
+	0x64: 20,
+	0x65: 20,
+	0x66: 20,
+	0x67: 20,
+	0x68: 20,
+	0x69: 20,
+	0x6a: 20,
+	0x6b: 20,
+	0x6c: 20,
+	0x6d: 20,
+	0x6e: 20,
+	0x6f: 20,
+	0x70: 20,
+	0x71: 20,
+	0x72: 20,
+	0x73: 20,
+	0x74: 20,
+	0x75: 20,
+	0x76: 20,
+	0x77: 20,
+	0x78: 20,
+	0x79: 20,
+	0x7a: 20,
+	0x7b: 20,
+	0x7c: 20,
+	0x7d: 20,
+	0x7e: 20,
+	0x7f: 20,
+	0x80: 23,
+	0x81: 23,
+	0x82: 23,
+	0x83: 23,
+	0x84: 23,
+	0x85: 23,
+	0x86: 23,
+	0x87: 23,
+	0x88: 23,
+	0x89: 23,
+	0x8a: 23,
+	0x8b: 23,
+	0x8c: 23,
+	0x8d: 23,
+	0x8e: 23,
+	0x8f: 23,
+	0x90: 23,
+	0x91: 23,
+	0x92: 23,
+	0x93: 23,
+	0x94: 23,
+	0x95: 23,
+	0x96: 23,
+	0x97: 23,
+	0x98: 23,
+	0x99: 23,
+	0x9a: 23,
+	0x9b: 23,
+	0x9c: 23,
+	0x9d: 23,
+	0x9e: 23,
+	0x9f: 23,
+	0xa0: 23,
+	0xa1: 23,
+	0xa2: 23,
+	0xa3: 23,
+	0xa4: 23,
+	0xa5: 23,
+	0xa6: 23,
+	0xa7: 23,
+	0xa8: 23,
+	0xa9: 23,
+	0xaa: 23,
+	0xab: 23,
+	0xac: 23,
+	0xad: 23,
+	0xae: 23,
+	0xaf: 23,
+	0xb0: 23,
+	0xb1: 23,
+	0xb2: 23,
+	0xb3: 23,
+	0xb4: 23,
+	0xb5: 23,
+	0xb6: 23,
+	0xb7: 23,
+	0xb8: 23,
+	0xb9: 23,
+	0xba: 23,
+	0xbb: 23,
+	0xbc: 23,
+	0xbd: 23,
+	0xbe: 23,
+	0xbf: 23,
+	0xc0: 23,
+	0xc1: 23,
+	0xc2: 23,
+	0xc3: 23,
+	0xc4: 23,
+	0xc5: 23,
+	0xc6: 23,
+	0xc7: 23,
+	0xc8: 23,
+	0xc9: 23,
+	0xca: 23,
+	0xcb: 23,
+	0xcc: 23,
+	0xcd: 23,
+	0xce: 23,
+	0xcf: 23,
+	0xd0: 23,
+	0xd1: 23,
+	0xd2: 23,
+	0xd3: 23,
+	0xd4: 23,
+	0xd5: 23,
+	0xd6: 23,
+	0xd7: 23,
+	0xd8: 23,
+	0xd9: 23,
+	0xda: 23,
+	0xdb: 23,
+	0xdc: 23,
+	0xdd: 23,
+	0xde: 23,
+	0xdf: 23,
+	0xe0: 23,
+	0xe1: 23,
+	0xe2: 23,
+	0xe3: 23,
+	0xe4: 23,
+	0xe5: 23,
+	0xe6: 23,
+	0xe7: 23,
+	0xe8: 23,
+	0xe9: 23,
+	0xea: 23,
+	0xeb: 23,
+	0xec: 23,
+	0xed: 23,
+	0xee: 23,
+	0xef: 23,
+	0xf0: 23,
+	0xf1: 23,
+	0xf2: 23,
+	0xf3: 23,
+	0xf4: 23,
+	0xf5: 23,
+	0xf6: 23,
+	0xf7: 23,
+	0xf8: 23,
+	0xf9: 23,
+	0xfa: 23,
+	0xfb: 23,
+	0xfc: 23,
+	0xfd: 23,
+	0xfe: 23,
+	0xff: 23,
+}
+var tstatesFDCB = [256]uint8{
+	0x00: 23,
+	0x01: 23,
+	0x02: 23,
+	0x03: 23,
+	0x04: 23,
+	0x05: 23,
+	0x06: 23,
+	0x07: 23,
+	0x08: 23,
+	0x09: 23,
+	0x0a: 23,
+	0x0b: 23,
+	0x0c: 23,
+	0x0d: 23,
+	0x0e: 23,
+	0x0f: 23,
+	0x10: 23,
+	0x11: 23,
+	0x12: 23,
+	0x13: 23,
+	0x14: 23,
+	0x15: 23,
+	0x16: 23,
+	0x17: 23,
+	0x18: 23,
+	0x19: 23,
+	0x1a: 23,
+	0x1b: 23,
+	0x1c: 23,
+	0x1d: 23,
+	0x1e: 23,
+	0x1f: 23,
+	0x20: 23,
+	0x21: 23,
+	0x22: 23,
+	0x23: 23,
+	0x24: 23,
+	0x25: 23,
+	0x26: 23,
+	0x27: 23,
+	0x28: 23,
+	0x29: 23,
+	0x2a: 23,
+	0x2b: 23,
+	0x2c: 23,
+	0x2d: 23,
+	0x2e: 23,
+	0x2f: 23,
+	0x30: 23,
+	0x31: 23,
+	0x32: 23,
+	0x33: 23,
+	0x34: 23,
+	0x35: 23,
+	0x36: 23,
+	0x37: 23,
+	0x38: 23,
+	0x39: 23,
+	0x3a: 23,
+	0x3b: 23,
+	0x3c: 23,
+	0x3d: 23,
+	0x3e: 23,
+	0x3f: 23,
+	0x40: 20,
+	0x41: 20,
+	0x42: 20,
+	0x43: 20,
+	0x44: 20,
+	0x45: 20,
+	0x46: 20,
+	0x47: 20,
+	0x48: 20,
+	0x49: 20,
+	0x4a: 20,
+	0x4b: 20,
+	0x4c: 20,
+	0x4d: 20,
+	0x4e: 20,
+	0x4f: 20,
+	0x50: 20,
+	0x51: 20,
+	0x52: 20,
+	0x53: 20,
+	0x54: 20,
+	0x55: 20,
+	0x56: 20,
+	0x57: 20,
+	0x58: 20,
+	0x59: 20,
+	0x5a: 20,
+	0x5b: 20,
+	0x5c: 20,
+	0x5d: 20,
+	0x5e: 20,
+	0x5f: 20,
+	0x60: 20,
+	0x61: 20,
+	0x62: 20,
+	0x63: 20,
+	0x64: 20,
+	0x65: 20,
+	0x66: 20,
+	0x67: 20,
+	0x68: 20,
+	0x69: 20,
+	0x6a: 20,
+	0x6b: 20,
+	0x6c: 20,
+	0x6d: 20,
+	0x6e: 20,
+	0x6f: 20,
+	0x70: 20,
+	0x71: 20,
+	0x72: 20,
+	0x73: 20,
+	0x74: 20,
+	0x75: 20,
+	0x76: 20,
+	0x77: 20,
+	0x78: 20,
+	0x79: 20,
+	0x7a: 20,
+	0x7b: 20,
+	0x7c: 20,
+	0x7d: 20,
+	0x7e: 20,
+	0x7f: 20,
+	0x80: 23,
+	0x81: 23,
+	0x82: 23,
+	0x83: 23,
+	0x84: 23,
+	0x85: 23,
+	0x86: 23,
+	0x87: 23,
+	0x88: 23,
+	0x89: 23,
+	0x8a: 23,
+	0x8b: 23,
+	0x8c: 23,
+	0x8d: 23,
+	0x8e: 23,
+	0x8f: 23,
+	0x90: 23,
+	0x91: 23,
+	0x92: 23,
+	0x93: 23,
+	0x94: 23,
+	0x95: 23,
+	0x96: 23,
+	0x97: 23,
+	0x98: 23,
+	0x99: 23,
+	0x9a: 23,
+	0x9b: 23,
+	0x9c: 23,
+	0x9d: 23,
+	0x9e: 23,
+	0x9f: 23,
+	0xa0: 23,
+	0xa1: 23,
+	0xa2: 23,
+	0xa3: 23,
+	0xa4: 23,
+	0xa5: 23,
+	0xa6: 23,
+	0xa7: 23,
+	0xa8: 23,
+	0xa9: 23,
+	0xaa: 23,
+	0xab: 23,
+	0xac: 23,
+	0xad: 23,
+	0xae: 23,
+	0xaf: 23,
+	0xb0: 23,
+	0xb1: 23,
+	0xb2: 23,
+	0xb3: 23,
+	0xb4: 23,
+	0xb5: 23,
+	0xb6: 23,
+	0xb7: 23,
+	0xb8: 23,
+	0xb9: 23,
+	0xba: 23,
+	0xbb: 23,
+	0xbc: 23,
+	0xbd: 23,
+	0xbe: 23,
+	0xbf: 23,
+	0xc0: 23,
+	0xc1: 23,
+	0xc2: 23,
+	0xc3: 23,
+	0xc4: 23,
+	0xc5: 23,
+	0xc6: 23,
+	0xc7: 23,
+	0xc8: 23,
+	0xc9: 23,
+	0xca: 23,
+	0xcb: 23,
+	0xcc: 23,
+	0xcd: 23,
+	0xce: 23,
+	0xcf: 23,
+	0xd0: 23,
+	0xd1: 23,
+	0xd2: 23,
+	0xd3: 23,
+	0xd4: 23,
+	0xd5: 23,
+	0xd6: 23,
+	0xd7: 23,
+	0xd8: 23,
+	0xd9: 23,
+	0xda: 23,
+	0xdb: 23,
+	0xdc: 23,
+	0xdd: 23,
+	0xde: 23,
+	0xdf: 23,
+	0xe0: 23,
+	0xe1: 23,
+	0xe2: 23,
+	0xe3: 23,
+	0xe4: 23,
+	0xe5: 23,
+	0xe6: 23,
+	0xe7: 23,
+	0xe8: 23,
+	0xe9: 23,
+	0xea: 23,
+	0xeb: 23,
+	0xec: 23,
+	0xed: 23,
+	0xee: 23,
+	0xef: 23,
+	0xf0: 23,
+	0xf1: 23,
+	0xf2: 23,
+	0xf3: 23,
+	0xf4: 23,
+	0xf5: 23,
+	0xf6: 23,
+	0xf7: 23,
+	0xf8: 23,
+	0xf9: 23,
+	0xfa: 23,
+	0xfb: 23,
+	0xfc: 23,
+	0xfd: 23,
+	0xfe: 23,
+	0xff: 23,
+}
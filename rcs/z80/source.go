@@ -0,0 +1,101 @@
+package z80
+
+import (
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+// Syntax is the source code format accepted by both the z80asm and sjasm
+// assemblers. Entry points are the reset address, the interrupt handler for
+// mode 1, and the non-maskable interrupt handler.
+var Syntax = &rcs.Syntax{
+	Name:    "z80asm",
+	Decode:  decode,
+	Format:  format,
+	Org:     "org $%04x",
+	Equate:  "%v: equ $%04x",
+	Label:   "%v:",
+	Byte:    "defb",
+	Word:    "defw",
+	Entries: []int{0x0000, 0x0038, 0x0066},
+}
+
+// Syntaxes returns the assemblers that source code can be generated for.
+func (c *CPU) Syntaxes() []*rcs.Syntax {
+	return []*rcs.Syntax{Syntax}
+}
+
+var addrRegex = regexp.MustCompile(`\$[0-9a-f]{4}`)
+
+func decode(mem *rcs.Memory, addr int) (rcs.Instr, bool) {
+	dasm := NewDisassembler(mem)
+	dasm.SetPC(addr)
+	s := dasm.NextStmt()
+	if strings.HasPrefix(s.Op, "?") {
+		return rcs.Instr{}, false
+	}
+	in := rcs.Instr{
+		Addr:  addr,
+		Op:    s.Op,
+		Bytes: s.Bytes,
+		Next:  true,
+	}
+	var values []int
+	for _, v := range addrRegex.FindAllString(s.Op, -1) {
+		n, _ := strconv.ParseUint(v[1:], 16, 16)
+		values = append(values, int(n))
+	}
+
+	op := s.Bytes[0]
+	switch {
+	case op == 0xc3, op == 0x18: // jp nn, jr e
+		in.Jumps = values
+		in.Next = false
+	case op&0xc7 == 0xc2, op&0xe7 == 0x20, op == 0x10: // jp cc, jr cc, djnz
+		in.Jumps = values
+	case op == 0xcd, op&0xc7 == 0xc4: // call nn, call cc,nn
+		in.Calls = values
+	case op&0xc7 == 0xc7: // rst p
+		in.Calls = []int{int(op & 0x38)}
+	case op == 0xc9, op == 0xe9: // ret, jp (hl)
+		in.Next = false
+	case op == 0xed && s.Bytes[1]&0xc7 == 0x45: // retn, reti
+		in.Next = false
+	case (op == 0xdd || op == 0xfd) && s.Bytes[1] == 0xe9: // jp (ix), jp (iy)
+		in.Next = false
+	default:
+		in.Refs = values
+	}
+	return in, true
+}
+
+// format returns the instruction as shown by the disassembler with labels
+// for addresses. Instructions that are assembled to different bytes, such
+// as the alternate encodings of "ld hl,($1234)", or that use names not
+// known to all assemblers, such as "sls", cannot be formatted.
+func format(in rcs.Instr, label func(int) (string, bool)) (string, bool) {
+	inst, operands := splitInst(in.Op)
+	if inst == "sls" {
+		return "", false
+	}
+	code, err := Assemble(in.Addr, in.Op)
+	if err != nil || !reflect.DeepEqual(code, in.Bytes) {
+		return "", false
+	}
+	text := strings.Join(operands, ",")
+	text = addrRegex.ReplaceAllStringFunc(text, func(v string) string {
+		n, _ := strconv.ParseUint(v[1:], 16, 16)
+		if name, ok := label(int(n)); ok {
+			return name
+		}
+		return v
+	})
+	if text == "" {
+		return inst, true
+	}
+	return inst + " " + text, true
+}
@@ -0,0 +1,119 @@
+package z80
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/blackchip-org/retro-cs/mock"
+	"github.com/blackchip-org/retro-cs/rcs"
+)
+
+var sourceProgram = []uint8{
+	0x31, 0x00, 0x50, // $0000: ld sp,$5000
+	0xcd, 0x10, 0x00, // $0003: call $0010
+	0x18, 0xfe, // $0006: jr $0006
+	0x48, 0x49, 0x00, // $0008: "HI", 0
+	0x00, 0x00, 0x00, 0x00, 0x00, // $000b
+	0x21, 0x08, 0x00, // $0010: ld hl,$0008
+	0xed, 0x6b, 0x00, 0x4c, // $0013: ld hl,($4c00)
+	0xcb, 0x30, // $0017: sls b
+	0xdd, 0x7e, 0x05, // $0019: ld a,(ix+$05)
+	0xc0,             // $001c: ret nz
+	0xc3, 0x00, 0x80, // $001d: jp $8000
+}
+
+func TestSource(t *testing.T) {
+	mock.ResetMemory()
+	mem := mock.TestMemory
+	mem.WriteN(0, sourceProgram...)
+	names := map[int]string{0x8000: "start", 0x4c00: "score"}
+	an := rcs.Analyze(mem, Syntax, 0x0000, 0x001f, nil, names, nil)
+	var buf bytes.Buffer
+	if err := an.WriteSource(&buf); err != nil {
+		t.Fatal(err)
+	}
+	have := strings.TrimSpace(buf.String())
+	want := strings.TrimSpace(`
+; $0000-$001f, z80asm
+
+score: equ $4c00
+start: equ $8000
+
+	org $0000
+	ld sp,$5000
+	call L0010
+L0006:
+	jr L0006
+L0008:
+	defb $48,$49,$00,$00,$00,$00,$00,$00
+L0010:
+	ld hl,L0008
+	defb $ed,$6b,$00,$4c            ; ld hl,($4c00)
+	defb $cb,$30                    ; sls b
+	ld a,(ix+$05)
+	ret nz
+	jp start
+`)
+	if have != want {
+		t.Errorf("\n have: \n%v \n want: \n%v", have, want)
+	}
+	code, err := reassemble(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(code, sourceProgram) {
+		t.Errorf("\n have: % x \n want: % x", code, sourceProgram)
+	}
+}
+
+// reassemble assembles the source code produced for the test program.
+func reassemble(src string) ([]uint8, error) {
+	values := make(map[string]string)
+	var lines []string
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(strings.SplitN(line, ";", 2)[0])
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 3 && fields[1] == "equ":
+			values[strings.TrimSuffix(fields[0], ":")] = fields[2]
+		case len(fields) > 0:
+			lines = append(lines, line)
+		}
+	}
+	var code []uint8
+	for pass := 0; pass < 2; pass++ {
+		code = code[:0]
+		for _, line := range lines {
+			fields := strings.Fields(line)
+			switch {
+			case fields[0] == "org":
+			case strings.HasSuffix(fields[0], ":"):
+				values[strings.TrimSuffix(fields[0], ":")] = fmt.Sprintf("$%04x", len(code))
+			case fields[0] == "defb":
+				for _, v := range strings.Split(fields[1], ",") {
+					n, _ := strconv.ParseUint(v[1:], 16, 8)
+					code = append(code, uint8(n))
+				}
+			default:
+				for name, v := range values {
+					line = regexp.MustCompile(`\b`+name+`\b`).ReplaceAllLiteralString(line, v)
+				}
+				b, err := Assemble(len(code), line)
+				if err != nil && pass == 1 {
+					return nil, fmt.Errorf("%v: %v", line, err)
+				}
+				if err != nil {
+					// labels are not known until the second pass
+					b = make([]uint8, 3)
+				}
+				code = append(code, b...)
+			}
+		}
+	}
+	return code, nil
+}
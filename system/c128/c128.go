@@ -64,11 +64,13 @@ func New(ctx rcs.SDLContext) (*rcs.Mach, error) {
 	s.IO.MapRW(0x020, &s.vic.BorderColor)
 	s.IO.MapRW(0x021, &s.vic.BgColor)
 	s.IO.MapLoad(0x500, s.mmu.ReadCR)
+	s.IO.MapPeek(0x500, s.mmu.PeekCR)
 	s.IO.MapStore(0x500, s.mmu.WriteCR)
 	// PCR
 	for i := 0; i < 4; i++ {
 		i := i
 		s.IO.MapLoad(0x501+i, func() uint8 { return s.mmu.ReadPCR(i) })
+		s.IO.MapPeek(0x501+i, func() uint8 { return s.mmu.PeekPCR(i) })
 		s.IO.MapStore(0x501+i, func(v uint8) { s.mmu.WritePCR(i, v) })
 	}
 	// HACK
@@ -81,8 +83,10 @@ func New(ctx rcs.SDLContext) (*rcs.Mach, error) {
 	})
 
 	s.IO.MapLoad(0x600, s.vdc.ReadStatus)
+	s.IO.MapPeek(0x600, s.vdc.PeekStatus)
 	s.IO.MapStore(0x600, s.vdc.WriteAddr)
 	s.IO.MapLoad(0x601, s.vdc.ReadData)
+	s.IO.MapPeek(0x601, s.vdc.PeekData)
 	s.IO.MapStore(0x601, s.vdc.WriteData)
 
 	// map banks
@@ -147,10 +151,12 @@ func New(ctx rcs.SDLContext) (*rcs.Mach, error) {
 		}
 
 		s.mem.MapLoad(0xff00, s.mmu.ReadCR)
+		s.mem.MapPeek(0xff00, s.mmu.PeekCR)
 		s.mem.MapStore(0xff00, s.mmu.WriteCR)
 		for i := 0; i < 4; i++ {
 			i := i
 			s.mem.MapLoad(0xff01+i, func() uint8 { return s.mmu.ReadLCR(i) })
+			s.mem.MapPeek(0xff01+i, func() uint8 { return s.mmu.PeekLCR(i) })
 			s.mem.MapStore(0xff01+i, func(v uint8) { s.mmu.WriteLCR(i, v) })
 		}
 	}
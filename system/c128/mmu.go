@@ -35,6 +35,11 @@ func (m *MMU) ReadCR() uint8 {
 	return v
 }
 
+// PeekCR returns the configuration register without any side effects.
+func (m *MMU) PeekCR() uint8 {
+	return uint8(m.Mem.Bank())
+}
+
 func (m *MMU) WriteCR(v uint8) {
 	if m.WatchCR.W {
 		log.Printf("mmu:cr <= 0x%02x", v)
@@ -50,6 +55,11 @@ func (m *MMU) ReadLCR(i int) uint8 {
 	return v
 }
 
+// PeekLCR returns a load configuration register without any side effects.
+func (m *MMU) PeekLCR(i int) uint8 {
+	return m.LCR[i]
+}
+
 func (m *MMU) WriteLCR(i int, v uint8) {
 	if m.WatchLCR.W {
 		log.Printf("mmu:lcr-%v <= 0x%02x", mmuRegs[i], v)
@@ -67,6 +77,11 @@ func (m *MMU) ReadPCR(i int) uint8 {
 	return v
 }
 
+// PeekPCR returns a pre-configuration register without any side effects.
+func (m *MMU) PeekPCR(i int) uint8 {
+	return m.PCR[i]
+}
+
 func (m *MMU) WritePCR(i int, v uint8) {
 	if m.WatchPCR.W {
 		log.Printf("mmu:pcr-%v <= 0x%02x", mmuRegs[i], v)
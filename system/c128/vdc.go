@@ -44,6 +44,12 @@ func (v *VDC) ReadStatus() uint8 {
 	return v.Status
 }
 
+// PeekStatus returns the value of the status register without any side
+// effects.
+func (v *VDC) PeekStatus() uint8 {
+	return v.Status
+}
+
 func (v *VDC) ReadData() uint8 {
 	val := uint8(0)
 	switch v.Addr {
@@ -64,6 +70,20 @@ func (v *VDC) ReadData() uint8 {
 	return val
 }
 
+// PeekData returns the value that would be returned by ReadData without
+// advancing the memory position.
+func (v *VDC) PeekData() uint8 {
+	switch v.Addr {
+	case 0x12: // current memory address (high byte)
+		return uint8(v.MemPos >> 8)
+	case 0x13: // current memory address (low byte)
+		return uint8(v.MemPos)
+	case 0x18: // vertical smooth scrolling and control register
+		return v.VSS
+	}
+	return 0
+}
+
 func (v *VDC) WriteData(val uint8) {
 	switch v.Addr {
 	case 0x12: // current memory address (high byte)
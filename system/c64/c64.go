@@ -44,20 +44,26 @@ func New(ctx rcs.SDLContext) (*rcs.Mach, error) {
 		Draw:      v.Draw,
 	}
 
+	devices := []rcs.Region{
+		// setup IO port on the 6510, map address 1 to "PLA"s
+		{
+			Name:  "io port",
+			Start: 0x01,
+			End:   0x01,
+			Load:  s.ioPortLoad,
+			Peek:  s.ioPortLoad,
+			Store: s.ioPortStore,
+		},
+		{Name: "border color", Start: 0xd020, End: 0xd020, RW: &s.vic.BorderColor},
+		{Name: "background color", Start: 0xd021, End: 0xd021, RW: &s.vic.BgColor},
+		{Name: "stop key", Start: 0x0091, End: 0x0091, RW: &kb.stkey},
+		{Name: "buffer index", Start: 0x00c6, End: 0x00c6, RW: &kb.ndx},
+		{Name: "keyboard buffer", Start: 0x0277, End: 0x0280, RAM: kb.buf},
+		{Name: "joystick 2", Start: 0xdc00, End: 0xdc00, RW: &kb.joy2},
+	}
 	for b := 0; b < 32; b++ {
 		s.mem.SetBank(b)
-		// setup IO port on the 6510, map address 1 to "PLA"s
-		s.mem.MapLoad(0x01, s.ioPortLoad)
-		s.mem.MapStore(0x01, s.ioPortStore)
-
-		s.mem.MapRW(0xd020, &s.vic.BorderColor)
-		s.mem.MapRW(0xd021, &s.vic.BgColor)
-
-		s.mem.MapRW(0x0091, &kb.stkey) // stop key
-		s.mem.MapRW(0x00c6, &kb.ndx)   // buffer index
-		s.mem.MapRAM(0x0277, kb.buf)
-
-		s.mem.MapRW(0xdc00, &kb.joy2)
+		s.mem.MapRegions(devices)
 	}
 	// Initialize to bank 31
 	s.mem.SetBank(31)
@@ -95,14 +101,14 @@ func New(ctx rcs.SDLContext) (*rcs.Mach, error) {
 	return mach, nil
 }
 
-func (s *system) ioPortStore(v uint8) {
+func (s *system) ioPortStore(_ int, v uint8) {
 	// PLA information is in the bottom 3 bits
 	s.bank &^= 0x7
 	s.bank |= v & 0x7
 	s.mem.SetBank(int(s.bank))
 }
 
-func (s *system) ioPortLoad() uint8 {
+func (s *system) ioPortLoad(_ int) uint8 {
 	// Only return the bottom 3 bits for now
 	return s.bank & 0x7
 }
@@ -5,10 +5,6 @@ import (
 )
 
 func newMemory(ram []uint8, io []uint8, roms map[string][]byte) *rcs.Memory {
-	basic := roms["basic"]
-	kernal := roms["kernal"]
-	chargen := roms["chargen"]
-
 	iomem := rcs.NewMemory(1, 0x1000)
 	iomem.MapRAM(0, io)
 
@@ -19,117 +15,59 @@ func newMemory(ram []uint8, io []uint8, roms map[string][]byte) *rcs.Memory {
 		carthi = cart[0x2000:0x4000]
 	}
 
-	mem := rcs.NewMemory(32, 0x10000)
+	var (
+		ramR     = rcs.Region{Name: "ram", Start: 0x0000, End: 0xffff, RAM: ram}
+		basicR   = rcs.Region{Name: "basic", Start: 0xa000, End: 0xbfff, ROM: roms["basic"]}
+		ioR      = rcs.Region{Name: "io", Start: 0xd000, End: 0xdfff, Mem: iomem}
+		charR    = rcs.Region{Name: "chargen", Start: 0xd000, End: 0xdfff, ROM: roms["chargen"]}
+		kernalR  = rcs.Region{Name: "kernal", Start: 0xe000, End: 0xffff, ROM: roms["kernal"]}
+		cartloR  = rcs.Region{Name: "cartlo", Start: 0x8000, End: 0x9fff, ROM: cartlo}
+		carthiR  = rcs.Region{Name: "carthi", Start: 0xa000, End: 0xbfff, ROM: carthi}
+		ultimaxR = rcs.Region{Name: "carthi", Start: 0xe000, End: 0xffff, ROM: carthi}
+		open1R   = rcs.Region{Name: "open", Start: 0x1000, End: 0x7fff, Unmap: true}
+		open2R   = rcs.Region{Name: "open", Start: 0xa000, End: 0xcfff, Unmap: true}
+	)
 
 	// https://www.c64-wiki.com/wiki/Bank_Switching
-	mem.SetBank(31)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0xa000, basic)
-	mem.Map(0xd000, iomem)
-	mem.MapROM(0xe000, kernal)
-
-	for _, bank := range []int{30, 14} {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-		mem.Map(0xd000, iomem)
-		mem.MapROM(0xe000, kernal)
-	}
-
-	for _, bank := range []int{29, 13} {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-		mem.Map(0xd000, iomem)
-	}
-
-	for _, bank := range []int{28, 24} {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-	}
-
-	mem.SetBank(27)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0xa000, basic)
-	mem.MapROM(0xd000, chargen)
-	mem.MapROM(0xe000, kernal)
-
-	for _, bank := range []int{26, 10} {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-		mem.MapROM(0xd000, chargen)
-		mem.MapROM(0xe000, kernal)
-	}
-
-	for _, bank := range []int{25, 9} {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-		mem.MapROM(0xd000, chargen)
-	}
-
-	for bank := 23; bank >= 16; bank-- {
-		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
-		for addr := 0x1000; addr <= 0x7fff; addr++ {
-			mem.Unmap(addr)
-		}
-		mem.MapROM(0x8000, cartlo)
-		for addr := 0xa000; addr <= 0xcfff; addr++ {
-			mem.Unmap(addr)
-		}
-		mem.Map(0xd000, iomem)
-		mem.MapROM(0xe000, carthi)
+	banks := map[int][]rcs.Region{
+		31: {ramR, basicR, ioR, kernalR},
+		30: {ramR, ioR, kernalR},
+		29: {ramR, ioR},
+		28: {ramR},
+		27: {ramR, basicR, charR, kernalR},
+		26: {ramR, charR, kernalR},
+		25: {ramR, charR},
+		24: {ramR},
+		23: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		22: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		21: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		20: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		19: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		18: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		17: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		16: {ramR, open1R, cartloR, open2R, ioR, ultimaxR},
+		15: {ramR, cartloR, basicR, ioR, kernalR},
+		14: {ramR, ioR, kernalR},
+		13: {ramR, ioR},
+		12: {ramR},
+		11: {ramR, cartloR, basicR, charR, kernalR},
+		10: {ramR, charR, kernalR},
+		9:  {ramR, charR},
+		8:  {ramR},
+		7:  {ramR, cartloR, carthiR, ioR, kernalR},
+		6:  {ramR, carthiR, ioR, kernalR},
+		5:  {ramR, ioR},
+		4:  {ramR},
+		3:  {ramR, cartloR, carthiR, charR, kernalR},
+		2:  {ramR, carthiR, charR, kernalR},
+		1:  {ramR},
+		0:  {ramR},
 	}
 
-	mem.SetBank(15)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0x8000, cartlo)
-	mem.MapROM(0xa000, basic)
-	mem.Map(0xd000, iomem)
-	mem.MapROM(0xe000, kernal)
-
-	for _, bank := range []int{12, 8, 4, 0} {
+	mem := rcs.NewMemory(32, 0x10000)
+	for bank, regions := range banks {
 		mem.SetBank(bank)
-		mem.MapRAM(0x0000, ram)
+		mem.MapRegions(regions)
 	}
-
-	mem.SetBank(11)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0x8000, cartlo)
-	mem.MapROM(0xa000, basic)
-	mem.MapROM(0xd000, chargen)
-	mem.MapROM(0xe000, kernal)
-
-	mem.SetBank(7)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0x8000, cartlo)
-	mem.MapROM(0xa000, carthi)
-	mem.Map(0xd000, iomem)
-	mem.MapROM(0xe000, kernal)
-
-	mem.SetBank(6)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0xa000, carthi)
-	mem.Map(0xd000, iomem)
-	mem.MapROM(0xe000, kernal)
-
-	mem.SetBank(5)
-	mem.MapRAM(0x0000, ram)
-	mem.Map(0xd000, iomem)
-
-	mem.SetBank(3)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0x8000, cartlo)
-	mem.MapROM(0xa000, carthi)
-	mem.MapROM(0xd000, chargen)
-	mem.MapROM(0xe000, kernal)
-
-	mem.SetBank(2)
-	mem.MapRAM(0x0000, ram)
-	mem.MapROM(0xa000, carthi)
-	mem.MapROM(0xd000, chargen)
-	mem.MapROM(0xe000, kernal)
-
-	mem.SetBank(1)
-	mem.MapRAM(0x0000, ram)
-
 	return mem
 }
@@ -36,37 +36,44 @@ func new(ctx rcs.SDLContext, set []rcs.ROM) (*rcs.Mach, error) {
 	mem := rcs.NewMemory(1, 0x10000)
 	ram := make([]uint8, 0x2000, 0x2000)
 
-	mem.MapRAM(0x6800, make([]uint8, 0x100, 0x100)) // temporary
-	for i := 0; i < 8; i++ {
-		mem.MapRW(0x6800+i, &s.dipSwitches[i])
-	}
-	mem.MapRW(0x6820, &s.InterruptEnable0)
-	mem.MapRW(0x6821, &s.InterruptEnable1)
-	mem.MapRW(0x6822, &s.InterruptEnable2)
-	mem.MapRW(0x6823, &s.reset)
-
-	mem.MapRAM(0x7000, make([]uint8, 0x1000, 0x1000))
-	mem.MapRAM(0x8000, ram)
-	mem.MapRAM(0xa000, make([]uint8, 0x1000, 0x1000))
-
 	s.n51xx = namco.NewN51XX()
 	s.n54xx = namco.NewN54XX()
 
 	s.n06xx = namco.NewN06XX()
 	s.n06xx.DeviceW[0] = s.n51xx.Write
 	s.n06xx.DeviceR[0] = s.n51xx.Read
+	s.n06xx.DeviceP[0] = s.n51xx.Peek
 	s.n06xx.DeviceW[3] = s.n54xx.Write
 	s.n06xx.DeviceR[3] = s.n54xx.Read
-	for i, addr := 0, 0x7000; addr < 0x7100; addr, i = addr+1, i+1 {
-		j := i
-		mem.MapLoad(addr, s.n06xx.ReadData(j))
-		mem.MapStore(addr, s.n06xx.WriteData(j))
-	}
-	for i, addr := 0, 0x7100; addr < 0x7200; addr, i = addr+1, i+1 {
-		j := i
-		mem.MapLoad(addr, s.n06xx.ReadCtrl(j))
-		mem.MapStore(addr, s.n06xx.WriteCtrl(j))
-	}
+	s.n06xx.DeviceP[3] = s.n54xx.Peek
+
+	mem.MapRegions([]rcs.Region{
+		{Name: "temporary", Start: 0x6800, End: 0x68ff, RAM: make([]uint8, 0x100, 0x100)},
+		{Name: "dip switches", Start: 0x6800, End: 0x6807, RAM: s.dipSwitches[:]},
+		{Name: "interrupt enable 1", Start: 0x6820, End: 0x6820, RW: &s.InterruptEnable0},
+		{Name: "interrupt enable 2", Start: 0x6821, End: 0x6821, RW: &s.InterruptEnable1},
+		{Name: "interrupt enable 3", Start: 0x6822, End: 0x6822, RW: &s.InterruptEnable2},
+		{Name: "reset", Start: 0x6823, End: 0x6823, RW: &s.reset},
+		{Name: "temporary", Start: 0x7000, End: 0x7fff, RAM: make([]uint8, 0x1000, 0x1000)},
+		{Name: "ram", Start: 0x8000, End: 0x9fff, RAM: ram},
+		{Name: "temporary", Start: 0xa000, End: 0xafff, RAM: make([]uint8, 0x1000, 0x1000)},
+		{
+			Name:  "n06xx data",
+			Start: 0x7000,
+			End:   0x70ff,
+			Load:  s.n06xx.ReadData,
+			Peek:  s.n06xx.PeekData,
+			Store: s.n06xx.WriteData,
+		},
+		{
+			Name:  "n06xx control",
+			Start: 0x7100,
+			End:   0x71ff,
+			Load:  s.n06xx.ReadCtrl,
+			Peek:  s.n06xx.PeekCtrl,
+			Store: s.n06xx.WriteCtrl,
+		},
+	})
 
 	var screen rcs.Screen
 	var video *namco.Video
@@ -81,8 +88,10 @@ func new(ctx rcs.SDLContext, set []rcs.ROM) (*rcs.Mach, error) {
 		if err != nil {
 			return nil, err
 		}
-		mem.MapRAM(0x8000, video.TileMemory)
-		mem.MapRAM(0x8400, video.ColorMemory)
+		mem.MapRegions([]rcs.Region{
+			{Name: "tiles", Start: 0x8000, End: 0x83ff, RAM: video.TileMemory},
+			{Name: "colors", Start: 0x8400, End: 0x87ff, RAM: video.ColorMemory},
+		})
 
 		screen = rcs.Screen{
 			W:         namco.W,
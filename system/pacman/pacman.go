@@ -39,46 +39,34 @@ func new(ctx rcs.SDLContext, set []rcs.ROM) (*rcs.Mach, error) {
 	s.mem = rcs.NewMemory(1, 0x10000)
 	ram := make([]uint8, 0x1000, 0x1000)
 
-	s.mem.MapROM(0x0000, roms["code"])
-	s.mem.MapRAM(0x4000, ram)
+	s.mem.MapRegions([]rcs.Region{
+		{Name: "code", Start: 0x0000, End: 0x3fff, ROM: roms["code"]},
+		{Name: "ram", Start: 0x4000, End: 0x4fff, RAM: ram},
 
-	// Register range. Nil mappings first then add real mappings
-	for i := 0x5000; i < 0x6000; i++ {
-		s.mem.MapNil(i)
-	}
-	s.mem.MapWO(0x5000, &s.interruptEnable)
-	for i := 0x5000; i < 0x503f; i++ {
-		s.mem.MapRO(i, &s.in0)
-	}
-	s.mem.MapWO(0x5001, &s.soundEnable)
-	s.mem.MapWO(0x5002, &s.unknown0)
-	s.mem.MapRW(0x5003, &s.flipScreen)
-	s.mem.MapRW(0x5004, &s.lampPlayer1)
-	s.mem.MapRW(0x5005, &s.lampPlayer2)
-	s.mem.MapRW(0x5006, &s.coinLockout)
-	s.mem.MapRW(0x5007, &s.coinCounter)
-	for i := 0x5040; i <= 0x507f; i++ {
-		s.mem.MapRO(i, &s.in1)
-	}
-	for i := 0x5080; i <= 0x50bf; i++ {
-		s.mem.MapRO(i, &s.dipSwitches)
-	}
-	for i := 0x50c0; i <= 0x50ff; i++ {
-		s.mem.MapWO(i, &s.watchdogReset)
-	}
+		// Register range. Nil mappings first then add real mappings
+		{Name: "registers", Start: 0x5000, End: 0x5fff, Nil: true},
+		{Name: "interrupt enable", Start: 0x5000, End: 0x5000, WO: &s.interruptEnable},
+		{Name: "in0", Start: 0x5000, End: 0x5000, Mirror: 0x003f, RO: &s.in0},
+		{Name: "sound enable", Start: 0x5001, End: 0x5001, WO: &s.soundEnable},
+		{Name: "unknown", Start: 0x5002, End: 0x5002, WO: &s.unknown0},
+		{Name: "flip screen", Start: 0x5003, End: 0x5003, RW: &s.flipScreen},
+		{Name: "lamp player 1", Start: 0x5004, End: 0x5004, RW: &s.lampPlayer1},
+		{Name: "lamp player 2", Start: 0x5005, End: 0x5005, RW: &s.lampPlayer2},
+		{Name: "coin lockout", Start: 0x5006, End: 0x5006, RW: &s.coinLockout},
+		{Name: "coin counter", Start: 0x5007, End: 0x5007, RW: &s.coinCounter},
+		{Name: "in1", Start: 0x5040, End: 0x5040, Mirror: 0x003f, RO: &s.in1},
+		{Name: "dip switches", Start: 0x5080, End: 0x5080, Mirror: 0x003f, RO: &s.dipSwitches},
+		{Name: "watchdog reset", Start: 0x50c0, End: 0x50c0, Mirror: 0x003f, WO: &s.watchdogReset},
 
-	if code2, ok := roms["code2"]; ok {
-		s.mem.MapROM(0x8000, code2)
-	}
+		// Ms. Pac-Man only
+		{Name: "code2", Start: 0x8000, End: 0x9fff, ROM: roms["code2"]},
 
-	// The first interrupt is executed without the stack pointer being set.
-	// The machine attempts to write the return address to 0xffff and 0xfffe
-	// but no memory is mapped there. Ms. Pac-Man also writes to 0xfffd
-	// and 0xfffc. Remove this warning.
-	s.mem.MapNil(0xfffc)
-	s.mem.MapNil(0xfffd)
-	s.mem.MapNil(0xfffe)
-	s.mem.MapNil(0xffff)
+		// The first interrupt is executed without the stack pointer being
+		// set. The machine attempts to write the return address to 0xffff
+		// and 0xfffe but no memory is mapped there. Ms. Pac-Man also writes
+		// to 0xfffd and 0xfffc. Remove this warning.
+		{Name: "stack", Start: 0xfffc, End: 0xffff, Nil: true},
+	})
 
 	cpu := z80.New(s.mem)
 	cpu.Ports.MapRW(0x00, &s.intSelect)
@@ -96,16 +84,15 @@ func new(ctx rcs.SDLContext, set []rcs.ROM) (*rcs.Mach, error) {
 		if err != nil {
 			return nil, err
 		}
-		s.mem.MapRAM(0x4000, video.TileMemory)
-		s.mem.MapRAM(0x4400, video.ColorMemory)
-
 		// Pacman is missing address line A15 so an access to $c000 is the
 		// same as accessing $4000. Ms. Pacman has additional ROMs in high
 		// memory so it has an A15 line but it appears to have the RAM mapped at
 		// $c000 as well. Text for HIGH SCORE and CREDIT accesses this high
 		// memory when writing to video memory. Copy protection?
-		s.mem.MapRAM(0xc000, video.TileMemory)
-		s.mem.MapRAM(0xc400, video.ColorMemory)
+		s.mem.MapRegions([]rcs.Region{
+			{Name: "tiles", Start: 0x4000, End: 0x43ff, Mirror: 0x8000, RAM: video.TileMemory},
+			{Name: "colors", Start: 0x4400, End: 0x47ff, Mirror: 0x8000, RAM: video.ColorMemory},
+		})
 
 		for i := 0; i < 8; i++ {
 			s.mem.MapRW(0x5060+(i*2), &video.SpriteCoords[i].X)